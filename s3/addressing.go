@@ -0,0 +1,63 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"strings"
+)
+
+// AddressingStyle controls how the bucket name is conveyed in requests.
+type AddressingStyle int
+
+const (
+	// Path-style requests name the bucket in the first path component, as in
+	// https://s3.amazonaws.com/bucket/key. This is the default.
+	PathStyle AddressingStyle = iota
+
+	// Virtual-hosted-style requests name the bucket in the host, as in
+	// https://bucket.s3.amazonaws.com/key. Buckets whose names are not valid
+	// DNS labels fall back to path style.
+	VirtualHostedStyle
+)
+
+// Return true if the supplied bucket name may be used as a label in a
+// virtual-hosted-style host name. Names containing dots are rejected because
+// they would not match the wildcard in Amazon's TLS certificates.
+//
+// Reference:
+//     http://docs.aws.amazon.com/AmazonS3/latest/dev/BucketRestrictions.html
+func isDnsCompatible(name string) bool {
+	if len(name) < 3 || len(name) > 63 {
+		return false
+	}
+
+	// This also rules out names formatted as IP addresses.
+	if strings.Contains(name, ".") {
+		return false
+	}
+
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9':
+		case r == '-' && i != 0 && i != len(name)-1:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
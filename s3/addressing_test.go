@@ -0,0 +1,133 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestAddressing(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// isDnsCompatible
+////////////////////////////////////////////////////////////////////////
+
+type IsDnsCompatibleTest struct {
+}
+
+func init() { RegisterTestSuite(&IsDnsCompatibleTest{}) }
+
+func (t *IsDnsCompatibleTest) CompatibleNames() {
+	ExpectTrue(isDnsCompatible("abc"))
+	ExpectTrue(isDnsCompatible("some-bucket"))
+	ExpectTrue(isDnsCompatible("bucket-123"))
+	ExpectTrue(isDnsCompatible("0123456789012345678901234567890123456789012345678901234567890ab"))
+}
+
+func (t *IsDnsCompatibleTest) IncompatibleNames() {
+	ExpectFalse(isDnsCompatible(""))
+	ExpectFalse(isDnsCompatible("ab"))
+	ExpectFalse(isDnsCompatible("0123456789012345678901234567890123456789012345678901234567890abc"))
+	ExpectFalse(isDnsCompatible("Some-Bucket"))
+	ExpectFalse(isDnsCompatible("some_bucket"))
+	ExpectFalse(isDnsCompatible("-bucket"))
+	ExpectFalse(isDnsCompatible("bucket-"))
+	ExpectFalse(isDnsCompatible("some.bucket"))
+	ExpectFalse(isDnsCompatible("192.168.5.4"))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Virtual-hosted-style requests
+////////////////////////////////////////////////////////////////////////
+
+type VirtualHostedTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&VirtualHostedTest{}) }
+
+func (t *VirtualHostedTest) SetUp(i *TestInfo) {
+	var err error
+
+	t.bucketTest.SetUp(i)
+	t.bucket, err = openBucket(
+		"some-bucket",
+		&url.URL{Scheme: "https", Host: "s3.example.com"},
+		true,
+		t.httpConn,
+		t.signer,
+		t.presigner,
+		t.v4Presigner,
+		t.clock)
+
+	AssertEq(nil, err)
+}
+
+// Set up the signer to capture the request it is asked to sign, returning a
+// pointer that will be filled in.
+func (t *VirtualHostedTest) captureRequest() **http.Request {
+	httpReq := new(*http.Request)
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			*httpReq = r
+			return errors.New("")
+		}))
+
+	return httpReq
+}
+
+func (t *VirtualHostedTest) ObjectRequest() {
+	httpReq := t.captureRequest()
+
+	// Call
+	t.bucket.GetObject("foo/bar")
+
+	AssertNe(nil, *httpReq)
+	ExpectEq("some-bucket", (*httpReq).Bucket)
+	ExpectEq("/foo/bar", (*httpReq).Path)
+}
+
+func (t *VirtualHostedTest) BucketRequest() {
+	httpReq := t.captureRequest()
+
+	// Call
+	t.bucket.ListObjects(ListRequest{})
+
+	AssertNe(nil, *httpReq)
+	ExpectEq("some-bucket", (*httpReq).Bucket)
+	ExpectEq("/", (*httpReq).Path)
+}
+
+func (t *VirtualHostedTest) PresignedUrl() {
+	// Presigner
+	ExpectCall(t.presigner, "Presign")(Any(), Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request, e time.Time) error {
+			r.Parameters["Signature"] = "foo"
+			return nil
+		}))
+
+	// Call
+	u, err := t.bucket.PresignUrl("a", &PresignOptions{Expiry: time.Hour})
+	AssertEq(nil, err)
+
+	ExpectEq("https://some-bucket.s3.example.com/a?Signature=foo", u)
+}
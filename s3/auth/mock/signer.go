@@ -12,6 +12,7 @@ import (
 	http "github.com/jacobsa/aws/s3/http"
 	oglemock "github.com/jacobsa/oglemock"
 	runtime "runtime"
+	time "time"
 	unsafe "unsafe"
 )
 
@@ -65,3 +66,54 @@ func (m *mockSigner) Sign(p0 *http.Request) (o0 error) {
 
 	return
 }
+
+type MockPresigner interface {
+	auth.Presigner
+	oglemock.MockObject
+}
+
+type mockPresigner struct {
+	controller  oglemock.Controller
+	description string
+}
+
+func NewMockPresigner(
+	c oglemock.Controller,
+	desc string) MockPresigner {
+	return &mockPresigner{
+		controller:  c,
+		description: desc,
+	}
+}
+
+func (m *mockPresigner) Oglemock_Id() uintptr {
+	return uintptr(unsafe.Pointer(m))
+}
+
+func (m *mockPresigner) Oglemock_Description() string {
+	return m.description
+}
+
+func (m *mockPresigner) Presign(p0 *http.Request, p1 time.Time) (o0 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"Presign",
+		file,
+		line,
+		[]interface{}{p0, p1})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockPresigner.Presign: invalid return values: %v", retVals))
+	}
+
+	// o0 error
+	if retVals[0] != nil {
+		o0 = retVals[0].(error)
+	}
+
+	return
+}
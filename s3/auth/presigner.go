@@ -0,0 +1,162 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"github.com/jacobsa/aws"
+	"github.com/jacobsa/aws/s3/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Presigner knows how to authenticate a request using query parameters
+// rather than headers, so that its URL may be handed to a third party who
+// doesn't hold the access key.
+//
+// Reference:
+//     http://goo.gl/bOVNo
+type Presigner interface {
+	// Add query parameters to the supplied request that authenticate it until
+	// the supplied time. The request must include a Date header, which is
+	// taken to be the time of signing.
+	Presign(r *http.Request, expires time.Time) error
+}
+
+// NewPresigner creates a Presigner that uses legacy query string
+// authentication (AWSAccessKeyId, Expires, and Signature parameters) with the
+// supplied access key.
+func NewPresigner(key *aws.AccessKey) (Presigner, error) {
+	return newPresigner(stringToSign, key)
+}
+
+// newPresigner is a helper used by NewPresigner, split out for testability in
+// the same manner as newSigner.
+func newPresigner(
+	sts func(*http.Request) (string, error),
+	key *aws.AccessKey) (Presigner, error) {
+	return &presigner{sts, key}, nil
+}
+
+type presigner struct {
+	sts func(*http.Request) (string, error)
+	key *aws.AccessKey
+}
+
+func (p *presigner) Presign(r *http.Request, expires time.Time) error {
+	// Query string authentication signs the expiry time, in seconds since the
+	// epoch, in place of the date.
+	expiresStr := strconv.FormatInt(expires.Unix(), 10)
+
+	sigReq := *r
+	sigReq.Headers = make(map[string]string, len(r.Headers))
+	for name, val := range r.Headers {
+		sigReq.Headers[name] = val
+	}
+
+	sigReq.Headers["Date"] = expiresStr
+
+	// Canonicalize the request.
+	toSign, err := p.sts(&sigReq)
+	if err != nil {
+		return fmt.Errorf("stringToSign: %v", err)
+	}
+
+	// Sign the request.
+	signature, err := computeSignature(p.key.Secret, toSign)
+	if err != nil {
+		return err
+	}
+
+	// Add the appropriate parameters.
+	if r.Parameters == nil {
+		r.Parameters = make(map[string]string)
+	}
+
+	r.Parameters["AWSAccessKeyId"] = p.key.Id
+	r.Parameters["Expires"] = expiresStr
+	r.Parameters["Signature"] = signature
+
+	return nil
+}
+
+// The longest validity period allowed for a SigV4 presigned URL.
+const maxV4Expiry = 7 * 24 * time.Hour
+
+// NewV4Presigner creates a Presigner that uses AWS Signature Version 4 query
+// parameters (X-Amz-Credential, X-Amz-Expires, X-Amz-Signature, etc.) with the
+// supplied access key. region is the name of the region the request is bound
+// for (e.g. "us-east-1") and host is the host of the endpoint to which it will
+// be sent.
+//
+// SigV4 limits the validity period to seven days.
+func NewV4Presigner(
+	key *aws.AccessKey,
+	region string,
+	host string) (Presigner, error) {
+	return &v4Presigner{key, region, host}, nil
+}
+
+type v4Presigner struct {
+	key    *aws.AccessKey
+	region string
+	host   string
+}
+
+func (p *v4Presigner) Presign(r *http.Request, expires time.Time) error {
+	// Find the signing time and validity period.
+	t, err := v4SigningTime(r)
+	if err != nil {
+		return err
+	}
+
+	expiry := expires.Sub(t) / time.Second
+	if expiry < 1 || expiry > maxV4Expiry/time.Second {
+		return fmt.Errorf(
+			"Expiry time must be between one second and %v after the request date.",
+			maxV4Expiry)
+	}
+
+	// Sign every header present except for the date, which is instead conveyed
+	// by the X-Amz-Date parameter.
+	headers := map[string]string{"Host": v4Host(r, p.host)}
+	for name, val := range r.Headers {
+		if strings.ToLower(name) != "date" {
+			headers[name] = val
+		}
+	}
+
+	_, signedHeaders := v4CanonicalHeaders(headers)
+
+	// Add the parameters that are themselves covered by the signature.
+	if r.Parameters == nil {
+		r.Parameters = make(map[string]string)
+	}
+
+	r.Parameters["X-Amz-Algorithm"] = v4Algorithm
+	r.Parameters["X-Amz-Credential"] = p.key.Id + "/" + v4Scope(t, p.region)
+	r.Parameters["X-Amz-Date"] = t.UTC().Format(v4TimeFormat)
+	r.Parameters["X-Amz-Expires"] = strconv.FormatInt(int64(expiry), 10)
+	r.Parameters["X-Amz-SignedHeaders"] = signedHeaders
+
+	// Sign the request. The payload of a presigned request isn't known in
+	// advance.
+	canonical, _ := v4CanonicalRequest(r, headers, unsignedPayload)
+	r.Parameters["X-Amz-Signature"] = v4Signature(p.key.Secret, t, p.region, canonical)
+
+	return nil
+}
@@ -62,9 +62,23 @@ func (s *signer) Sign(r *http.Request) error {
 	}
 
 	// Sign the request.
-	h := hmac.New(sha1.New, []byte(s.key.Secret))
+	signature, err := computeSignature(s.key.Secret, toSign)
+	if err != nil {
+		return err
+	}
+
+	// Add the appropriate header.
+	r.Headers["Authorization"] = fmt.Sprintf("AWS %s:%s", s.key.Id, signature)
+
+	return nil
+}
+
+// Compute the base64-encoded HMAC-SHA1 signature of the supplied string, as
+// used by both header and query string authentication.
+func computeSignature(secret string, toSign string) (string, error) {
+	h := hmac.New(sha1.New, []byte(secret))
 	if _, err := h.Write([]byte(toSign)); err != nil {
-		return fmt.Errorf("hmac.Write: %v", err)
+		return "", fmt.Errorf("hmac.Write: %v", err)
 	}
 
 	signature := h.Sum(nil)
@@ -73,13 +87,79 @@ func (s *signer) Sign(r *http.Request) error {
 	buf := new(bytes.Buffer)
 	encoder := base64.NewEncoder(base64.StdEncoding, buf)
 	if _, err := encoder.Write(signature); err != nil {
-		return fmt.Errorf("encoder.Write: %v", err)
+		return "", fmt.Errorf("encoder.Write: %v", err)
 	}
 
 	encoder.Close()
 
+	return buf.String(), nil
+}
+
+// NewV4Signer creates a Signer that uses AWS Signature Version 4, which is
+// required by regions launched since 2014 and supported by all others.
+// region is the name of the region the requests are bound for (e.g.
+// "us-east-1") and host is the host of the endpoint to which they will be
+// sent.
+//
+// Requests must contain a Date or x-amz-date header. The payload of a request
+// with an in-memory body is hashed and the hash included in the signature.
+// The payload of a streaming request can't be hashed without reading it, so
+// it is sent unsigned (its Content-MD5 header, if any, is still signed). If
+// unsignedPayload is true, all payloads are sent unsigned.
+//
+// Reference:
+//     http://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func NewV4Signer(
+	key *aws.AccessKey,
+	region string,
+	host string,
+	unsignedPayload bool) (Signer, error) {
+	return &v4Signer{key, region, host, unsignedPayload}, nil
+}
+
+type v4Signer struct {
+	key             *aws.AccessKey
+	region          string
+	host            string
+	unsignedPayload bool
+}
+
+func (s *v4Signer) Sign(r *http.Request) error {
+	// Find the signing time, and make sure the server sees the same one.
+	t, err := v4SigningTime(r)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := lookUpHeader(r.Headers, "x-amz-date"); !ok {
+		r.Headers["x-amz-date"] = t.UTC().Format(v4TimeFormat)
+	}
+
+	// Hash the payload, if possible.
+	payloadHash := unsignedPayload
+	if !s.unsignedPayload && r.BodyReader == nil {
+		payloadHash = sha256Hex(r.Body)
+	}
+
+	r.Headers["x-amz-content-sha256"] = payloadHash
+
+	// Sign every header present, plus the host.
+	headers := map[string]string{"Host": v4Host(r, s.host)}
+	for name, val := range r.Headers {
+		headers[name] = val
+	}
+
+	canonical, signedHeaders := v4CanonicalRequest(r, headers, payloadHash)
+	signature := v4Signature(s.key.Secret, t, s.region, canonical)
+
 	// Add the appropriate header.
-	r.Headers["Authorization"] = fmt.Sprintf("AWS %s:%s", s.key.Id, buf.String())
+	r.Headers["Authorization"] = fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		v4Algorithm,
+		s.key.Id,
+		v4Scope(t, s.region),
+		signedHeaders,
+		signature)
 
 	return nil
 }
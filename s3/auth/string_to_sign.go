@@ -16,12 +16,109 @@
 package auth
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/jacobsa/aws/s3/http"
 	"net/url"
+	"sort"
+	"strings"
 )
 
+// Request parameters that identify a sub-resource of the resource named by a
+// request's path, and so must be included in CanonicalizedResource.
+//
+// Reference:
+//     http://goo.gl/Z8DiC
+var subResources = map[string]bool{
+	"acl":                          true,
+	"delete":                       true,
+	"lifecycle":                    true,
+	"location":                     true,
+	"logging":                      true,
+	"notification":                 true,
+	"partNumber":                   true,
+	"policy":                       true,
+	"requestPayment":               true,
+	"response-cache-control":       true,
+	"response-content-disposition": true,
+	"response-content-encoding":    true,
+	"response-content-language":    true,
+	"response-content-type":        true,
+	"response-expires":             true,
+	"torrent":                      true,
+	"uploadId":                     true,
+	"uploads":                      true,
+	"versionId":                    true,
+	"versioning":                   true,
+	"versions":                     true,
+	"website":                      true,
+}
+
+// Return the sub-resource portion of CanonicalizedResource for the supplied
+// request parameters, e.g. "?partNumber=3&uploadId=taco". Parameters with
+// empty values (e.g. "uploads") appear without an equals sign. Unlike the
+// path, values are not URL-encoded.
+func canonicalizeSubResources(params map[string]string) string {
+	var names []string
+	for name := range params {
+		if subResources[name] {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return ""
+	}
+
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name
+		if val := params[name]; val != "" {
+			parts[i] += "=" + val
+		}
+	}
+
+	return "?" + strings.Join(parts, "&")
+}
+
+// Return the CanonicalizedAmzHeaders portion of the string to sign for the
+// supplied request headers: each x-amz-* header, with its name lower-cased and
+// surrounding whitespace removed from its value, as "name:value\n", sorted by
+// name. Headers whose names differ only in case are combined into a single
+// comma-separated value.
+func canonicalizeAmzHeaders(headers map[string]string) string {
+	values := make(map[string][]string)
+	for name, val := range headers {
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, "x-amz-") {
+			continue
+		}
+
+		// Unfold values that span multiple lines.
+		val = strings.Join(strings.Fields(val), " ")
+		values[name] = append(values[name], val)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		vals := values[name]
+		sort.Strings(vals)
+		fmt.Fprintf(&buf, "%s:%s\n", name, strings.Join(vals, ","))
+	}
+
+	return buf.String()
+}
+
 // Given an HTTP request, return the string that should be signed for that
 // request. The request must include a `Date` header.
 //
@@ -39,13 +136,23 @@ func stringToSign(r *http.Request) (string, error) {
 	contentMd5 := r.Headers["Content-MD5"]
 	contentType := r.Headers["Content-Type"]
 
-	// We don't yet support CanonicalizedAmzHeaders.
-	canonicalizedAmzHeaders := ""
+	canonicalizedAmzHeaders := canonicalizeAmzHeaders(r.Headers)
 
 	// Amazon's signing algorithm is weird -- it requires URL encoding for paths,
-	// but not query parameters. Luckily we currently only support simple
-	// path-style requests.
-	canonicalizedResource := (&url.URL{Path: r.Path}).RequestURI()
+	// but not query parameters. The resource always begins with the bucket
+	// name, even for virtual-hosted-style requests.
+	path := r.Path
+	if r.Bucket != "" {
+		if path == "" {
+			path = "/"
+		}
+
+		path = "/" + r.Bucket + path
+	}
+
+	canonicalizedResource :=
+		(&url.URL{Path: path}).RequestURI() +
+			canonicalizeSubResources(r.Parameters)
 
 	// Put everything together.
 	return fmt.Sprintf(
@@ -99,6 +99,56 @@ func (t *StringToSignTest) EmptyPath() {
 				"/"))
 }
 
+func (t *StringToSignTest) VirtualHostedBucket() {
+	// Request
+	req := &http.Request{
+		Verb:   "GET",
+		Bucket: "johnsmith",
+		Path:   "/photos/puppy.jpg",
+		Headers: map[string]string{
+			"Date": "Tue, 27 Mar 2007 19:36:42 +0000",
+		},
+	}
+
+	// Call
+	s, err := stringToSign(req)
+	AssertEq(nil, err)
+
+	ExpectThat(
+		s,
+		Equals(
+			"GET\n"+
+				"\n"+ // Content-MD5
+				"\n"+ // Content-Type
+				"Tue, 27 Mar 2007 19:36:42 +0000\n"+
+				"/johnsmith/photos/puppy.jpg"))
+}
+
+func (t *StringToSignTest) VirtualHostedBucketWithEmptyPath() {
+	// Request
+	req := &http.Request{
+		Verb:   "GET",
+		Bucket: "johnsmith",
+		Path:   "",
+		Headers: map[string]string{
+			"Date": "some_date",
+		},
+	}
+
+	// Call
+	s, err := stringToSign(req)
+	AssertEq(nil, err)
+
+	ExpectThat(
+		s,
+		Equals(
+			"GET\n"+
+				"\n"+ // Content-MD5
+				"\n"+ // Content-Type
+				"some_date\n"+
+				"/johnsmith/"))
+}
+
 func (t *StringToSignTest) PathContainsKorean() {
 	// Request
 	req := &http.Request{
@@ -246,3 +296,206 @@ func (t *StringToSignTest) ComplicatedRequest() {
 				"some_date\n"+
 				"/foo/bar/baz"))
 }
+
+func (t *StringToSignTest) IgnoresOrdinaryParameters() {
+	// Request
+	req := &http.Request{
+		Verb: "GET",
+		Path: "/foo",
+		Headers: map[string]string{
+			"Date": "some_date",
+		},
+		Parameters: map[string]string{
+			"marker":   "taco",
+			"max-keys": "17",
+		},
+	}
+
+	// Call
+	s, err := stringToSign(req)
+	AssertEq(nil, err)
+
+	ExpectThat(
+		s,
+		Equals(
+			"GET\n"+
+				"\n"+ // Content-MD5
+				"\n"+ // Content-Type
+				"some_date\n"+
+				"/foo"))
+}
+
+func (t *StringToSignTest) SubResourceWithoutValue() {
+	// Request
+	req := &http.Request{
+		Verb: "POST",
+		Path: "/foo/bar",
+		Headers: map[string]string{
+			"Date": "some_date",
+		},
+		Parameters: map[string]string{
+			"uploads": "",
+		},
+	}
+
+	// Call
+	s, err := stringToSign(req)
+	AssertEq(nil, err)
+
+	ExpectThat(
+		s,
+		Equals(
+			"POST\n"+
+				"\n"+ // Content-MD5
+				"\n"+ // Content-Type
+				"some_date\n"+
+				"/foo/bar?uploads"))
+}
+
+func (t *StringToSignTest) MultipleSubResources() {
+	// Request
+	req := &http.Request{
+		Verb: "PUT",
+		Path: "/foo/bar",
+		Headers: map[string]string{
+			"Date": "some_date",
+		},
+		Parameters: map[string]string{
+			"uploadId":   "a/b+c",
+			"partNumber": "3",
+			"marker":     "taco",
+		},
+	}
+
+	// Call
+	s, err := stringToSign(req)
+	AssertEq(nil, err)
+
+	ExpectThat(
+		s,
+		Equals(
+			"PUT\n"+
+				"\n"+ // Content-MD5
+				"\n"+ // Content-Type
+				"some_date\n"+
+				"/foo/bar?partNumber=3&uploadId=a/b+c"))
+}
+
+func (t *StringToSignTest) IncludesAmzHeaders() {
+	// Request
+	req := &http.Request{
+		Verb: "PUT",
+		Path: "/foo/bar",
+		Headers: map[string]string{
+			"Date":                "some_date",
+			"Content-Type":        "blah/foo",
+			"Cache-Control":       "no-cache",
+			"x-amz-acl":           "public-read",
+			"X-Amz-Meta-Flavor":   "  taco ",
+			"X-Amz-Meta-Long":     "burrito\n  enchilada",
+			"X-Amz-Storage-Class": "REDUCED_REDUNDANCY",
+		},
+	}
+
+	// Call
+	s, err := stringToSign(req)
+	AssertEq(nil, err)
+
+	ExpectThat(
+		s,
+		Equals(
+			"PUT\n"+
+				"\n"+ // Content-MD5
+				"blah/foo\n"+
+				"some_date\n"+
+				"x-amz-acl:public-read\n"+
+				"x-amz-meta-flavor:taco\n"+
+				"x-amz-meta-long:burrito enchilada\n"+
+				"x-amz-storage-class:REDUCED_REDUNDANCY\n"+
+				"/foo/bar"))
+}
+
+func (t *StringToSignTest) CombinesAmzHeadersDifferingInCase() {
+	// Request
+	req := &http.Request{
+		Verb: "GET",
+		Path: "/foo",
+		Headers: map[string]string{
+			"Date":         "some_date",
+			"X-Amz-Meta-A": "taco",
+			"x-amz-meta-a": "burrito",
+		},
+	}
+
+	// Call
+	s, err := stringToSign(req)
+	AssertEq(nil, err)
+
+	ExpectThat(
+		s,
+		Equals(
+			"GET\n"+
+				"\n"+ // Content-MD5
+				"\n"+ // Content-Type
+				"some_date\n"+
+				"x-amz-meta-a:burrito,taco\n"+
+				"/foo"))
+}
+
+func (t *StringToSignTest) IncludesEncryptionHeaders() {
+	// Request
+	req := &http.Request{
+		Verb: "PUT",
+		Path: "/foo/bar",
+		Headers: map[string]string{
+			"Date": "some_date",
+			"x-amz-server-side-encryption-customer-algorithm": "AES256",
+			"x-amz-server-side-encryption-customer-key":       "a2V5",
+			"x-amz-server-side-encryption-customer-key-MD5":   "bWQ1",
+		},
+	}
+
+	// Call
+	s, err := stringToSign(req)
+	AssertEq(nil, err)
+
+	ExpectThat(
+		s,
+		Equals(
+			"PUT\n"+
+				"\n"+ // Content-MD5
+				"\n"+ // Content-Type
+				"some_date\n"+
+				"x-amz-server-side-encryption-customer-algorithm:AES256\n"+
+				"x-amz-server-side-encryption-customer-key:a2V5\n"+
+				"x-amz-server-side-encryption-customer-key-md5:bWQ1\n"+
+				"/foo/bar"))
+}
+
+func (t *StringToSignTest) CopyRequest() {
+	// Request
+	req := &http.Request{
+		Verb: "PUT",
+		Path: "/dst.bucket/foo",
+		Headers: map[string]string{
+			"Date":                     "some_date",
+			"x-amz-copy-source":        "/src.bucket/%ED%83%80%EC%BD%94",
+			"x-amz-metadata-directive": "REPLACE",
+		},
+	}
+
+	// Call
+	s, err := stringToSign(req)
+	AssertEq(nil, err)
+
+	ExpectThat(
+		s,
+		Equals(
+			"PUT\n"+
+				"\n"+ // Content-MD5
+				"\n"+ // Content-Type
+				"some_date\n"+
+				"x-amz-copy-source:/src.bucket/%ED%83%80%EC%BD%94\n"+
+				"x-amz-metadata-directive:REPLACE\n"+
+				"/dst.bucket/foo"))
+}
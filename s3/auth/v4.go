@@ -0,0 +1,224 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/jacobsa/aws/s3/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Helpers for AWS Signature Version 4, shared by the header signer and the
+// presigner.
+//
+// Reference:
+//     http://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html
+
+const (
+	v4Algorithm     = "AWS4-HMAC-SHA256"
+	v4Service       = "s3"
+	v4TimeFormat    = "20060102T150405Z"
+	v4DateFormat    = "20060102"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+func isV4Unreserved(c byte) bool {
+	return ('a' <= c && c <= 'z') ||
+		('A' <= c && c <= 'Z') ||
+		('0' <= c && c <= '9') ||
+		c == '-' ||
+		c == '.' ||
+		c == '_' ||
+		c == '~'
+}
+
+// URI-encode the supplied string in the manner required by SigV4: every byte
+// except the unreserved characters is percent-encoded, with upper case hex
+// digits. Slashes are left alone if encodeSlash is false.
+func v4UriEncode(s string, encodeSlash bool) string {
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isV4Unreserved(c) || (c == '/' && !encodeSlash) {
+			buf = append(buf, c)
+		} else {
+			buf = append(buf, fmt.Sprintf("%%%02X", c)...)
+		}
+	}
+
+	return string(buf)
+}
+
+// Return the CanonicalQueryString for the supplied parameters: encoded
+// name=value pairs sorted by name and joined with ampersands. Parameters
+// without values (e.g. "uploads") appear with an empty value.
+func v4CanonicalQuery(params map[string]string) string {
+	pairs := make([]string, 0, len(params))
+	for name, val := range params {
+		pairs = append(pairs, v4UriEncode(name, true)+"="+v4UriEncode(val, true))
+	}
+
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// Return the CanonicalHeaders and SignedHeaders portions of the canonical
+// request for the supplied headers, which must include Host.
+func v4CanonicalHeaders(
+	headers map[string]string) (canonical string, signed string) {
+	values := make(map[string][]string)
+	for name, val := range headers {
+		name = strings.ToLower(name)
+		val = strings.Join(strings.Fields(val), " ")
+		values[name] = append(values[name], val)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		vals := values[name]
+		sort.Strings(vals)
+		canonical += name + ":" + strings.Join(vals, ",") + "\n"
+	}
+
+	signed = strings.Join(names, ";")
+	return
+}
+
+// Return the canonical request for the supplied request, signing the supplied
+// headers, along with the list of signed headers.
+func v4CanonicalRequest(
+	r *http.Request,
+	headers map[string]string,
+	payloadHash string) (canonical string, signedHeaders string) {
+	// S3 wants the path encoded exactly once, with slashes intact.
+	path := r.Path
+	if path == "" {
+		path = "/"
+	}
+
+	canonicalHeaders, signedHeaders := v4CanonicalHeaders(headers)
+	canonical = strings.Join(
+		[]string{
+			r.Verb,
+			v4UriEncode(path, false),
+			v4CanonicalQuery(r.Parameters),
+			canonicalHeaders,
+			signedHeaders,
+			payloadHash,
+		},
+		"\n")
+
+	return
+}
+
+// Return the credential scope for a request signed at the supplied time.
+func v4Scope(t time.Time, region string) string {
+	return strings.Join(
+		[]string{t.UTC().Format(v4DateFormat), region, v4Service, "aws4_request"},
+		"/")
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Compute the hex-encoded signature of the supplied canonical request, made at
+// the supplied time within the supplied region.
+func v4Signature(
+	secret string,
+	t time.Time,
+	region string,
+	canonicalRequest string) string {
+	toSign := strings.Join(
+		[]string{
+			v4Algorithm,
+			t.UTC().Format(v4TimeFormat),
+			v4Scope(t, region),
+			sha256Hex([]byte(canonicalRequest)),
+		},
+		"\n")
+
+	key := hmacSha256([]byte("AWS4"+secret), t.UTC().Format(v4DateFormat))
+	key = hmacSha256(key, region)
+	key = hmacSha256(key, v4Service)
+	key = hmacSha256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSha256(key, toSign))
+}
+
+// Find the value of the named header, ignoring case.
+func lookUpHeader(headers map[string]string, name string) (string, bool) {
+	for n, val := range headers {
+		if strings.EqualFold(n, name) {
+			return val, true
+		}
+	}
+
+	return "", false
+}
+
+// Return the host to which the supplied request will be sent, given the
+// connection's endpoint host.
+func v4Host(r *http.Request, endpointHost string) string {
+	if r.Bucket != "" {
+		return r.Bucket + "." + endpointHost
+	}
+
+	return endpointHost
+}
+
+// Return the time at which the supplied request is being signed, taken from
+// its x-amz-date header if present and its Date header otherwise.
+func v4SigningTime(r *http.Request) (time.Time, error) {
+	if val, ok := lookUpHeader(r.Headers, "x-amz-date"); ok {
+		t, err := time.Parse(v4TimeFormat, val)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid x-amz-date header: %s", val)
+		}
+
+		return t, nil
+	}
+
+	date, ok := r.Headers["Date"]
+	if !ok {
+		return time.Time{}, fmt.Errorf("A Date header is required.")
+	}
+
+	t, err := time.Parse(time.RFC1123, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid Date header: %s", date)
+	}
+
+	return t, nil
+}
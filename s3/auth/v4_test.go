@@ -0,0 +1,76 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	. "github.com/jacobsa/ogletest"
+	"testing"
+)
+
+func TestV4(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type V4Test struct{}
+
+func init() { RegisterTestSuite(&V4Test{}) }
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *V4Test) UriEncodeUnreserved() {
+	s := "azAZ09-._~"
+	ExpectEq(s, v4UriEncode(s, true))
+}
+
+func (t *V4Test) UriEncodeReserved() {
+	ExpectEq("%20%2B%2A%3D%26%3F", v4UriEncode(" +*=&?", true))
+	ExpectEq("%ED%83%80", v4UriEncode("타", true))
+}
+
+func (t *V4Test) UriEncodeSlash() {
+	ExpectEq("a%2Fb", v4UriEncode("a/b", true))
+	ExpectEq("a/b", v4UriEncode("a/b", false))
+}
+
+func (t *V4Test) CanonicalQuery() {
+	params := map[string]string{
+		"prefix":    "a/b c",
+		"max-keys":  "2",
+		"uploads":   "",
+		"X-Amz-Foo": "bar",
+	}
+
+	ExpectEq(
+		"X-Amz-Foo=bar&max-keys=2&prefix=a%2Fb%20c&uploads=",
+		v4CanonicalQuery(params))
+}
+
+func (t *V4Test) CanonicalHeaders() {
+	headers := map[string]string{
+		"Host":         "foo",
+		"X-Amz-Meta-A": "  b   c ",
+		"Content-Type": "text/plain",
+	}
+
+	canonical, signed := v4CanonicalHeaders(headers)
+
+	ExpectEq("content-type:text/plain\nhost:foo\nx-amz-meta-a:b c\n", canonical)
+	ExpectEq("content-type;host;x-amz-meta-a", signed)
+}
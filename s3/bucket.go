@@ -17,15 +17,19 @@ package s3
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/base64"
-	"encoding/xml"
 	"fmt"
 	"github.com/jacobsa/aws"
 	"github.com/jacobsa/aws/s3/auth"
 	"github.com/jacobsa/aws/s3/http"
 	"github.com/jacobsa/aws/time"
+	"io"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"strings"
 	sys_time "time"
 	"unicode/utf8"
 )
@@ -47,13 +51,134 @@ type Bucket interface {
 	// Retrieve data for the object with the given key.
 	GetObject(key string) (data []byte, err error)
 
+	// Like GetObject, but return a stream from which the object's data may be
+	// read rather than reading it all into memory. size is the length of the
+	// object in bytes, or -1 if the server didn't say. If the error is nil, the
+	// caller must close the reader.
+	GetObjectReader(key string) (r io.ReadCloser, size int64, err error)
+
+	// Like GetObjectReader, but make the read conditional on the supplied
+	// options, and return the object's metadata along with its data. opts may
+	// be nil, in which case the read is unconditional. If the conditions are
+	// not met, the error is of type *NotModifiedError or
+	// *PreconditionFailedError.
+	GetObjectWithOptions(
+		key string,
+		opts *GetOptions) (r io.ReadCloser, info *ObjectInfo, err error)
+
+	// Retrieve a contiguous range of the data for the object with the given
+	// key, along with the total size of the object.
+	//
+	// If offset is non-negative, the range starts at that offset and contains
+	// up to length bytes, or everything to the end of the object if length is
+	// negative. If offset is negative, the range is the final -offset bytes of
+	// the object (or the entire object, if it is shorter) and length is
+	// ignored.
+	//
+	// A range that starts beyond the end of the object results in an error of
+	// type *RangeNotSatisfiableError.
+	GetObjectRange(
+		key string,
+		offset int64,
+		length int64) (data []byte, objectSize int64, err error)
+
+	// Like GetObjectRange, but allow supplying the key needed to read an object
+	// stored with a customer-provided encryption key. opts may be nil, in which
+	// case this is the same as GetObjectRange.
+	GetObjectRangeWithOptions(
+		key string,
+		offset int64,
+		length int64,
+		opts *RangeOptions) (data []byte, objectSize int64, err error)
+
+	// Retrieve metadata about the object with the given key, without
+	// retrieving its contents. If the object doesn't exist, IsNotFound returns
+	// true for the error.
+	StatObject(key string) (info *ObjectInfo, err error)
+
+	// Like StatObject, but allow supplying the key needed to describe an object
+	// stored with a customer-provided encryption key. opts may be nil, in which
+	// case this is the same as StatObject.
+	StatObjectWithOptions(key string, opts *StatOptions) (info *ObjectInfo, err error)
+
 	// Store the supplied data with the given key, overwriting any previous
 	// version. The object is created with the default ACL of "private".
 	StoreObject(key string, data []byte) error
 
-	// Delete the object with the supplied key.
+	// Like StoreObject, but read the object's data from the supplied stream
+	// rather than from memory. size is the number of bytes the stream will
+	// yield, or -1 if that isn't known in advance.
+	//
+	// S3 needs to know an object's length and MD5 hash before receiving its
+	// data. If r is an io.ReadSeeker it is read twice, once to compute these and
+	// once to upload. Otherwise its contents are first buffered in a temporary
+	// file on disk.
+	StoreObjectFromReader(key string, r io.Reader, size int64) error
+
+	// Like StoreObjectFromReader, but allow control over the object's headers,
+	// ACL, storage class, and user-defined metadata. opts may be nil, in which
+	// case defaults are used.
+	StoreObjectWithOptions(
+		key string,
+		r io.Reader,
+		size int64,
+		opts *StoreOptions) (result *StoreResult, err error)
+
+	// Copy the object with key srcKey to dstKey on the server side, without the
+	// data passing through the client. The source object may be in another
+	// bucket; see CopyOptions. opts may be nil, in which case defaults are used.
+	CopyObject(
+		srcKey string,
+		dstKey string,
+		opts *CopyOptions) (result *CopyObjectResult, err error)
+
+	// Delete the object with the supplied key. If versioning is enabled for the
+	// bucket, this places a delete marker in front of the object's versions
+	// rather than removing any of them.
 	DeleteObject(key string) error
 
+	// Permanently delete the version of the object with the supplied key that
+	// has the given version ID, which may be a delete marker. If it is the
+	// latest version, the previous one (if any) becomes the object's contents.
+	DeleteObjectVersion(key string, versionId string) error
+
+	// Delete the objects with the supplied keys, of which there must be between
+	// one and MaxDeleteObjectsKeys, in a single request. A nil error means only
+	// that the request was processed; the result says which keys could not be
+	// deleted and why. In quiet mode the result doesn't list the keys that were
+	// successfully deleted.
+	//
+	// Deleting a key that doesn't exist counts as a success.
+	DeleteObjects(keys []string, quiet bool) (result *DeleteObjectsResult, err error)
+
+	// Begin a multipart upload for the object with the given key, returning an
+	// ID for the upload to be passed to the other multipart methods. The upload
+	// must eventually be completed or aborted; until then, S3 charges for the
+	// storage used by any parts uploaded.
+	//
+	// Most users will want s3util.Uploader instead of using this directly.
+	InitiateMultipartUpload(key string) (uploadId string, err error)
+
+	// Upload one part of a multipart upload, reading its data from r in the
+	// same manner as StoreObjectFromReader. Part numbers must be in [1, 10000];
+	// every part except the last must be at least 5 MiB. Uploading a part with
+	// the same number as a previous one replaces it. The returned ETag must be
+	// passed to CompleteMultipartUpload.
+	UploadPart(
+		key string,
+		uploadId string,
+		partNumber int,
+		r io.Reader,
+		size int64) (etag string, err error)
+
+	// Assemble the supplied parts, which must be in increasing order of part
+	// number, into an object with the given key, overwriting any previous
+	// version.
+	CompleteMultipartUpload(key string, uploadId string, parts []CompletedPart) error
+
+	// Abort a multipart upload, freeing the storage used by its parts.
+	AbortMultipartUpload(key string, uploadId string) error
+
 	// Return an ordered set of contiguous object keys in the bucket that are
 	// strictly greater than prevKey (or at the beginning of the range if prevKey
 	// is empty). It is guaranteed that as some time during the request there
@@ -71,48 +196,232 @@ type Bucket interface {
 	// prevKey must be a valid key, with the sole exception that it is allowed to
 	// be the empty string.
 	ListKeys(prevKey string) (keys []string, err error)
+
+	// Return a batch of keys matching the supplied request, in order, along
+	// with any common prefixes that keys were rolled up into. This allows
+	// treating a bucket as a hierarchy, e.g. listing the "directory" foo/ by
+	// using a prefix of "foo/" and a delimiter of "/".
+	//
+	// If the result is truncated, further results may be obtained by repeating
+	// the request with Marker set to the result's NextMarker.
+	ListObjects(req ListRequest) (result *ListResult, err error)
+
+	// Enable or suspend versioning for the bucket. Once enabled, versioning
+	// can't be turned off entirely; suspending it means that new objects
+	// replace the version with ID "null" rather than adding new versions.
+	SetVersioning(status VersioningStatus) error
+
+	// Return the versioning state of the bucket, which is empty if versioning
+	// has never been enabled.
+	GetVersioning() (status VersioningStatus, err error)
+
+	// Return a batch of the versions of objects in the bucket and delete
+	// markers matching the supplied request, in order of key and then from
+	// newest to oldest. Common prefixes work as with ListObjects.
+	//
+	// If the result is truncated, further results may be obtained by repeating
+	// the request with KeyMarker and VersionIdMarker set to the result's
+	// NextKeyMarker and NextVersionIdMarker.
+	ListObjectVersions(req ListVersionsRequest) (result *ListVersionsResult, err error)
+
+	// Return a URL with which anyone may read or write the object with the
+	// given key, without holding an access key, until the URL expires. The
+	// object need not yet exist. See PresignOptions.
+	PresignUrl(key string, opts *PresignOptions) (url string, err error)
+
+	// Return a view of the bucket whose operations are bound to the supplied
+	// context: cancelling the context or passing its deadline aborts any request
+	// in progress, including the reading of a stream returned by
+	// GetObjectReader. The receiver is unaffected.
+	WithContext(ctx context.Context) Bucket
 }
 
 // OpenBucket returns a Bucket tied to a given name in a given region. You must
 // have previously created the bucket in the region, and the supplied access
-// key must have access to it.
+// key must have access to it. Options may be supplied to customize the
+// bucket's behavior.
 //
 // To easily create a bucket, use the AWS Console:
 //
 //     https://console.aws.amazon.com/s3/
 //
-func OpenBucket(name string, region Region, key aws.AccessKey) (Bucket, error) {
-	// Create a connection to the given region's endpoint.
+func OpenBucket(
+	name string,
+	region Region,
+	key aws.AccessKey,
+	opts ...Option) (Bucket, error) {
 	endpoint := &url.URL{Scheme: "https", Host: string(region)}
-	httpConn, err := http.NewConn(endpoint)
+	return openBucketAtEndpoint(
+		name,
+		endpoint,
+		regionName(region),
+		key,
+		makeBucketOptions(opts))
+}
+
+// OpenBucketAtEndpoint is like OpenBucket, but sends requests to an arbitrary
+// endpoint rather than to one of Amazon's regions, for use with S3-compatible
+// services such as MinIO or Ceph. The endpoint's scheme must be http or https.
+// It may include a port and a path prefix, as in:
+//
+//     http://localhost:9000/s3
+//
+// The bucket is addressed using the supplied style, which overrides any
+// supplied with WithAddressingStyle. Signature Version 4 credentials are
+// scoped to the region set with WithSigningRegion, "us-east-1" by default.
+func OpenBucketAtEndpoint(
+	name string,
+	endpoint *url.URL,
+	style AddressingStyle,
+	key aws.AccessKey,
+	opts ...Option) (Bucket, error) {
+	if endpoint.Host == "" {
+		return nil, fmt.Errorf("Endpoint has no host: %s", endpoint)
+	}
+
+	if endpoint.RawQuery != "" || endpoint.Fragment != "" {
+		return nil, fmt.Errorf("Endpoint may not have a query or fragment: %s", endpoint)
+	}
+
+	o := makeBucketOptions(opts)
+	o.addressingStyle = style
+
+	return openBucketAtEndpoint(name, endpoint, "us-east-1", key, o)
+}
+
+// Open a bucket at the given endpoint, using the given name for the region
+// in Signature Version 4 credential scopes unless the options say otherwise.
+func openBucketAtEndpoint(
+	name string,
+	endpoint *url.URL,
+	region string,
+	key aws.AccessKey,
+	o *bucketOptions) (Bucket, error) {
+	if o.signingRegion != "" {
+		region = o.signingRegion
+	}
+
+	clock := time.RealClock()
+	httpConn, signer, err := makeConnAndSigner(endpoint, region, key, o, clock)
 	if err != nil {
-		return nil, fmt.Errorf("http.NewConn: %v", err)
+		return nil, err
 	}
 
-	// Create an appropriate request signer.
-	signer, err := auth.NewSigner(&key)
+	// Create presigners, for creating URLs.
+	presigner, err := auth.NewPresigner(&key)
 	if err != nil {
-		return nil, fmt.Errorf("auth.NewSigner: %v", err)
+		return nil, fmt.Errorf("auth.NewPresigner: %v", err)
+	}
+
+	v4Presigner, err := auth.NewV4Presigner(&key, region, endpoint.Host)
+	if err != nil {
+		return nil, fmt.Errorf("auth.NewV4Presigner: %v", err)
+	}
+
+	if o.signatureV4 {
+		presigner = v4Presigner
 	}
 
-	return openBucket(name, httpConn, signer, time.RealClock())
+	virtualHosted :=
+		o.addressingStyle == VirtualHostedStyle &&
+			isDnsCompatible(name)
+
+	return openBucket(
+		name,
+		endpoint,
+		virtualHosted,
+		httpConn,
+		signer,
+		presigner,
+		v4Presigner,
+		clock)
+}
+
+// Create a connection to the given endpoint along with a signer for the
+// requests sent over it, as called for by the supplied options. region is the
+// name used in Signature Version 4 credential scopes.
+func makeConnAndSigner(
+	endpoint *url.URL,
+	region string,
+	key aws.AccessKey,
+	o *bucketOptions,
+	clock time.Clock) (httpConn http.Conn, signer auth.Signer, err error) {
+	// Create a connection to the endpoint.
+	if httpConn, err = http.NewConnWithClient(endpoint, o.httpClient); err != nil {
+		err = fmt.Errorf("http.NewConnWithClient: %v", err)
+		return
+	}
+
+	// Create an appropriate request signer.
+	if o.signatureV4 {
+		signer, err = auth.NewV4Signer(
+			&key,
+			region,
+			endpoint.Host,
+			o.unsignedPayload)
+
+		if err != nil {
+			err = fmt.Errorf("auth.NewV4Signer: %v", err)
+			return
+		}
+	} else {
+		if signer, err = auth.NewSigner(&key); err != nil {
+			err = fmt.Errorf("auth.NewSigner: %v", err)
+			return
+		}
+	}
+
+	// Retry failed requests if asked to.
+	if p := o.retryPolicy; p != nil {
+		httpConn = http.NewRetryingConn(
+			httpConn,
+			http.RetryPolicy{
+				MaxAttempts:    p.MaxAttempts,
+				InitialBackoff: p.InitialBackoff,
+				MaxBackoff:     p.MaxBackoff,
+			},
+			resignRequest(signer, clock))
+	}
+
+	return
 }
 
 // A version of OpenBucket with the ability to inject dependencies, for
 // testability.
 func openBucket(
 	name string,
+	endpoint *url.URL,
+	virtualHosted bool,
 	httpConn http.Conn,
 	signer auth.Signer,
+	presigner auth.Presigner,
+	v4Presigner auth.Presigner,
 	clock time.Clock) (Bucket, error) {
-	return &bucket{name, httpConn, signer, clock}, nil
+	b := &bucket{
+		name:          name,
+		endpoint:      endpoint,
+		pathPrefix:    strings.TrimSuffix(endpoint.Path, "/"),
+		virtualHosted: virtualHosted,
+		httpConn:      httpConn,
+		signer:        signer,
+		presigner:     presigner,
+		v4Presigner:   v4Presigner,
+		clock:         clock,
+	}
+
+	return b, nil
 }
 
 type bucket struct {
-	name     string
-	httpConn http.Conn
-	signer   auth.Signer
-	clock    time.Clock
+	name          string
+	endpoint      *url.URL
+	pathPrefix    string
+	virtualHosted bool
+	httpConn      http.Conn
+	signer        auth.Signer
+	presigner     auth.Presigner
+	v4Presigner   auth.Presigner
+	clock         time.Clock
 }
 
 ////////////////////////////////////////////////////////////////////////
@@ -128,7 +437,10 @@ func isLegalXmlCharacter(r rune) (inrange bool) {
 		(r >= 0x10000 && r <= 0x10FFFF)
 }
 
-func validateKey(key string) error {
+// ValidateKey returns an error if the supplied string is not a legal key, as
+// described in the documentation for Bucket. Implementations of Bucket other
+// than the one returned by OpenBucket should use it to reject the same keys.
+func ValidateKey(key string) error {
 	// Keys must be valid UTF-8 and no more than 1024 bytes long.
 	if len(key) > 1024 {
 		return fmt.Errorf("Keys may be no longer than 1024 bytes.")
@@ -156,15 +468,47 @@ func validateKey(key string) error {
 	return nil
 }
 
+// Return the value to use for http.Request.Bucket in requests for this
+// bucket.
+func (b *bucket) hostBucket() string {
+	if b.virtualHosted {
+		return b.name
+	}
+
+	return ""
+}
+
+// Return the request path addressing the bucket itself.
+func (b *bucket) bucketPath() string {
+	if b.virtualHosted {
+		return b.pathPrefix + "/"
+	}
+
+	return fmt.Sprintf("%s/%s", b.pathPrefix, b.name)
+}
+
+// Return the request path addressing the object with the given key.
+func (b *bucket) objectPath(key string) string {
+	if b.virtualHosted {
+		return fmt.Sprintf("%s/%s", b.pathPrefix, key)
+	}
+
+	return fmt.Sprintf("%s/%s/%s", b.pathPrefix, b.name, key)
+}
+
 func addMd5Header(r *http.Request, body []byte) error {
 	md5Hash := md5.New()
 	if _, err := md5Hash.Write(body); err != nil {
 		return fmt.Errorf("md5Hash.Write: %v", err)
 	}
 
+	return addMd5HeaderForSum(r, md5Hash.Sum(nil))
+}
+
+func addMd5HeaderForSum(r *http.Request, sum []byte) error {
 	base64Md5Buf := new(bytes.Buffer)
 	base64Encoder := base64.NewEncoder(base64.StdEncoding, base64Md5Buf)
-	if _, err := base64Encoder.Write(md5Hash.Sum(nil)); err != nil {
+	if _, err := base64Encoder.Write(sum); err != nil {
 		return fmt.Errorf("base64Encoder.Write: %v", err)
 	}
 
@@ -179,8 +523,32 @@ func addMd5Header(r *http.Request, body []byte) error {
 ////////////////////////////////////////////////////////////////////////
 
 func (b *bucket) GetObject(key string) (data []byte, err error) {
+	// Open a stream for the object's data.
+	r, _, err := b.GetObjectReader(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the stream is closed no matter how we exit.
+	defer r.Close()
+
+	// Read everything.
+	if data, err = ioutil.ReadAll(r); err != nil {
+		return nil, fmt.Errorf("ReadAll: %v", err)
+	}
+
+	return data, nil
+}
+
+// Validate the supplied key, then send a signed GET request for it with the
+// supplied additional headers and parameters, returning the response without
+// looking at it.
+func (b *bucket) sendGetRequest(
+	key string,
+	headers map[string]string,
+	params map[string]string) (*http.StreamingResponse, error) {
 	// Validate the key.
-	if err := validateKey(key); err != nil {
+	if err := ValidateKey(key); err != nil {
 		return nil, err
 	}
 
@@ -189,13 +557,42 @@ func (b *bucket) GetObject(key string) (data []byte, err error) {
 	// Reference:
 	//     http://docs.amazonwebservices.com/AmazonS3/latest/API/RESTObjectGET.html
 	httpReq := &http.Request{
-		Verb: "GET",
-		Path: fmt.Sprintf("/%s/%s", b.name, key),
+		Verb:   "GET",
+		Path:   b.objectPath(key),
+		Bucket: b.hostBucket(),
 		Headers: map[string]string{
 			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
 		},
+		Parameters: map[string]string{},
+	}
+
+	for key, val := range headers {
+		httpReq.Headers[key] = val
+	}
+
+	for name, val := range params {
+		httpReq.Parameters[name] = val
+	}
+
+	// Sign the request.
+	if err := b.signer.Sign(httpReq); err != nil {
+		return nil, fmt.Errorf("Sign: %v", err)
+	}
+
+	// Send the request.
+	httpResp, err := b.httpConn.StreamRequest(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("StreamRequest: %v", err)
 	}
 
+	return httpResp, nil
+}
+
+// Sign and send the supplied request, returning an error if the response
+// doesn't have the expected status code.
+func (b *bucket) sendRequest(
+	httpReq *http.Request,
+	expectedStatus int) (*http.Response, error) {
 	// Sign the request.
 	if err := b.signer.Sign(httpReq); err != nil {
 		return nil, fmt.Errorf("Sign: %v", err)
@@ -207,12 +604,36 @@ func (b *bucket) GetObject(key string) (data []byte, err error) {
 		return nil, fmt.Errorf("SendRequest: %v", err)
 	}
 
+	// Check the response.
+	if httpResp.StatusCode != expectedStatus {
+		return nil, newError(httpResp.StatusCode, httpResp.Body)
+	}
+
+	return httpResp, nil
+}
+
+// Read and close the body of a response with an unexpected status code,
+// returning an error describing it. On error the body is hopefully small, so
+// it's included in the message.
+func streamingResponseError(httpResp *http.StreamingResponse) error {
+	defer httpResp.Body.Close()
+	body, _ := ioutil.ReadAll(httpResp.Body)
+	return newError(httpResp.StatusCode, body)
+}
+
+func (b *bucket) GetObjectReader(key string) (r io.ReadCloser, size int64, err error) {
+	// Send the request.
+	httpResp, err := b.sendGetRequest(key, nil, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// Check the response.
 	if httpResp.StatusCode != 200 {
-		return nil, fmt.Errorf("Error from server: %d %s", httpResp.StatusCode, httpResp.Body)
+		return nil, 0, streamingResponseError(httpResp)
 	}
 
-	return httpResp.Body, nil
+	return httpResp.Body, httpResp.ContentLength, nil
 }
 
 ////////////////////////////////////////////////////////////////////////
@@ -221,7 +642,7 @@ func (b *bucket) GetObject(key string) (data []byte, err error) {
 
 func (b *bucket) StoreObject(key string, data []byte) error {
 	// Validate the key.
-	if err := validateKey(key); err != nil {
+	if err := ValidateKey(key); err != nil {
 		return err
 	}
 
@@ -230,9 +651,10 @@ func (b *bucket) StoreObject(key string, data []byte) error {
 	// Reference:
 	//     http://docs.amazonwebservices.com/AmazonS3/latest/API/RESTObjectPUT.html
 	httpReq := &http.Request{
-		Verb: "PUT",
-		Path: fmt.Sprintf("/%s/%s", b.name, key),
-		Body: data,
+		Verb:   "PUT",
+		Path:   b.objectPath(key),
+		Bucket: b.hostBucket(),
+		Body:   data,
 		Headers: map[string]string{
 			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
 		},
@@ -256,129 +678,196 @@ func (b *bucket) StoreObject(key string, data []byte) error {
 
 	// Check the response.
 	if httpResp.StatusCode != 200 {
-		return fmt.Errorf("Error from server: %d %s", httpResp.StatusCode, httpResp.Body)
+		return newError(httpResp.StatusCode, httpResp.Body)
 	}
 
 	return nil
 }
 
 ////////////////////////////////////////////////////////////////////////
-// DeleteObject
+// StoreObjectFromReader
 ////////////////////////////////////////////////////////////////////////
 
-func (b *bucket) DeleteObject(key string) error {
-	// Validate the key.
-	if err := validateKey(key); err != nil {
-		return err
+// Read the remainder of r, returning the number of bytes read and their MD5
+// sum.
+func hashStream(r io.Reader) (n int64, sum []byte, err error) {
+	md5Hash := md5.New()
+	if n, err = io.Copy(md5Hash, r); err != nil {
+		return
 	}
 
-	// Build an appropriate HTTP request.
-	//
-	// Reference:
-	//     http://docs.amazonwebservices.com/AmazonS3/latest/API/RESTObjectDELETE.html
-	httpReq := &http.Request{
-		Verb: "DELETE",
-		Path: fmt.Sprintf("/%s/%s", b.name, key),
-		Headers: map[string]string{
-			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
-		},
+	sum = md5Hash.Sum(nil)
+	return
+}
+
+// Compute the length and MD5 sum of the remainder of r, then rewind it so that
+// it may be read again.
+func hashSeeker(r io.ReadSeeker) (n int64, sum []byte, err error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		err = fmt.Errorf("Seek: %v", err)
+		return
 	}
 
-	// Add a Content-MD5 header, as advised in the Amazon docs.
-	if err := addMd5Header(httpReq, httpReq.Body); err != nil {
-		return err
+	if n, sum, err = hashStream(r); err != nil {
+		err = fmt.Errorf("Reading: %v", err)
+		return
 	}
 
-	// Sign the request.
-	if err := b.signer.Sign(httpReq); err != nil {
-		return fmt.Errorf("Sign: %v", err)
+	if _, err = r.Seek(start, io.SeekStart); err != nil {
+		err = fmt.Errorf("Seek: %v", err)
+		return
 	}
 
-	// Send the request.
-	httpResp, err := b.httpConn.SendRequest(httpReq)
+	return
+}
+
+// Copy the remainder of r into a new temporary file, returning the file
+// rewound to its start along with the length and MD5 sum of its contents. The
+// caller must close and remove the file.
+func spoolToTempFile(r io.Reader) (f *os.File, n int64, sum []byte, err error) {
+	f, err = ioutil.TempFile("", "s3_upload")
 	if err != nil {
-		return fmt.Errorf("SendRequest: %v", err)
+		err = fmt.Errorf("TempFile: %v", err)
+		return
 	}
 
-	// Check the response.
-	if httpResp.StatusCode != 204 {
-		return fmt.Errorf("Error from server: %d %s", httpResp.StatusCode, httpResp.Body)
+	// Clean up if we don't succeed.
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			f = nil
+		}
+	}()
+
+	if n, sum, err = hashStream(io.TeeReader(r, f)); err != nil {
+		err = fmt.Errorf("Copying to temporary file: %v", err)
+		return
 	}
 
-	return nil
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		err = fmt.Errorf("Seek: %v", err)
+		return
+	}
+
+	return
 }
 
-////////////////////////////////////////////////////////////////////////
-// ListKeys
-////////////////////////////////////////////////////////////////////////
+// Return a stream that yields the remainder of r, along with its length and
+// MD5 sum, as described in the documentation for StoreObjectFromReader. If
+// size is non-negative, it must match the length of the data. The caller must
+// call cleanup when done with the stream.
+func prepareBody(
+	r io.Reader,
+	size int64) (body io.Reader, n int64, sum []byte, cleanup func(), err error) {
+	cleanup = func() {}
+
+	if rs, ok := r.(io.ReadSeeker); ok {
+		if n, sum, err = hashSeeker(rs); err != nil {
+			return
+		}
+
+		body = rs
+	} else {
+		var f *os.File
+		if f, n, sum, err = spoolToTempFile(r); err != nil {
+			return
+		}
+
+		cleanup = func() {
+			f.Close()
+			os.Remove(f.Name())
+		}
+
+		body = f
+	}
+
+	if size >= 0 && n != size {
+		cleanup()
+		err = fmt.Errorf("Expected %d bytes of data, but read %d.", size, n)
+		return
+	}
+
+	// Where possible, return a stream that can be rewound so that the request
+	// may be retried.
+	if ra, ok := body.(io.ReaderAt); ok {
+		var start int64
+		if start, err = body.(io.Seeker).Seek(0, io.SeekCurrent); err != nil {
+			cleanup()
+			err = fmt.Errorf("Seek: %v", err)
+			return
+		}
+
+		body = io.NewSectionReader(ra, start, n)
+		return
+	}
 
-type bucketContents struct {
-	Key string
+	body = io.LimitReader(body, n)
+	return
 }
 
-type listBucketResult struct {
-	XMLName  xml.Name
-	Contents []bucketContents
+func (b *bucket) StoreObjectFromReader(key string, r io.Reader, size int64) error {
+	_, err := b.StoreObjectWithOptions(key, r, size, nil)
+	return err
 }
 
-func (b *bucket) ListKeys(prevKey string) (keys []string, err error) {
-	// Make sure the previous key is empty or valid.
-	if err := validateKey(prevKey); err != nil && prevKey != "" {
-		return nil, err
+////////////////////////////////////////////////////////////////////////
+// DeleteObject
+////////////////////////////////////////////////////////////////////////
+
+func (b *bucket) DeleteObject(key string) error {
+	// Validate the key.
+	if err := ValidateKey(key); err != nil {
+		return err
 	}
 
 	// Build an appropriate HTTP request.
 	//
 	// Reference:
-	//     http://docs.amazonwebservices.com/AmazonS3/latest/API/RESTBucketGET.html
+	//     http://docs.amazonwebservices.com/AmazonS3/latest/API/RESTObjectDELETE.html
 	httpReq := &http.Request{
-		Verb: "GET",
-		Path: fmt.Sprintf("/%s", b.name),
+		Verb:   "DELETE",
+		Path:   b.objectPath(key),
+		Bucket: b.hostBucket(),
 		Headers: map[string]string{
 			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
 		},
-		Parameters: map[string]string{},
 	}
 
-	if prevKey != "" {
-		httpReq.Parameters["marker"] = prevKey
+	// Add a Content-MD5 header, as advised in the Amazon docs.
+	if err := addMd5Header(httpReq, httpReq.Body); err != nil {
+		return err
 	}
 
 	// Sign the request.
 	if err := b.signer.Sign(httpReq); err != nil {
-		return nil, fmt.Errorf("Sign: %v", err)
+		return fmt.Errorf("Sign: %v", err)
 	}
 
 	// Send the request.
 	httpResp, err := b.httpConn.SendRequest(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("SendRequest: %v", err)
+		return fmt.Errorf("SendRequest: %v", err)
 	}
 
 	// Check the response.
-	if httpResp.StatusCode != 200 {
-		return nil, fmt.Errorf("Error from server: %d %s", httpResp.StatusCode, httpResp.Body)
+	if httpResp.StatusCode != 204 {
+		return newError(httpResp.StatusCode, httpResp.Body)
 	}
 
-	// Attempt to parse the body.
-	result := listBucketResult{}
-	if err := xml.Unmarshal(httpResp.Body, &result); err != nil {
-		return nil, fmt.Errorf(
-			"Invalid data from server (%s): %s",
-			err.Error(),
-			httpResp.Body)
-	}
+	return nil
+}
 
-	// Make sure the server agress with us about the interpretation of the
-	// request.
-	if result.XMLName.Local != "ListBucketResult" {
-		return nil, fmt.Errorf("Invalid data from server: %s", httpResp.Body)
-	}
+////////////////////////////////////////////////////////////////////////
+// ListKeys
+////////////////////////////////////////////////////////////////////////
 
-	keys = make([]string, len(result.Contents))
-	for i, elem := range result.Contents {
-		keys[i] = elem.Key
+func (b *bucket) ListKeys(prevKey string) (keys []string, err error) {
+	result, err := b.ListObjects(ListRequest{Marker: prevKey})
+	if err != nil {
+		return nil, err
 	}
 
-	return keys, nil
+	return result.Keys, nil
 }
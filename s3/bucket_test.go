@@ -23,9 +23,11 @@ import (
 	"github.com/jacobsa/aws/s3/auth/mock"
 	"github.com/jacobsa/aws/s3/http"
 	"github.com/jacobsa/aws/s3/http/mock"
-	. "github.com/jacobsa/oglematchers"
 	"github.com/jacobsa/oglemock"
 	. "github.com/jacobsa/ogletest"
+	"io"
+	"io/ioutil"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -53,6 +55,32 @@ func computeBase64Md5(d []byte) string {
 	return buf.String()
 }
 
+// A response body that records whether it has been closed, and optionally
+// returns an error after its contents are exhausted.
+type fakeBody struct {
+	r      io.Reader
+	err    error
+	closed bool
+}
+
+func newFakeBody(s string) *fakeBody {
+	return &fakeBody{r: strings.NewReader(s)}
+}
+
+func (b *fakeBody) Read(p []byte) (n int, err error) {
+	n, err = b.r.Read(p)
+	if err == io.EOF && b.err != nil {
+		err = b.err
+	}
+
+	return
+}
+
+func (b *fakeBody) Close() error {
+	b.closed = true
+	return nil
+}
+
 type fakeClock struct {
 	now time.Time
 }
@@ -62,10 +90,12 @@ func (c *fakeClock) Now() time.Time {
 }
 
 type bucketTest struct {
-	httpConn mock_http.MockConn
-	signer   mock_auth.MockSigner
-	bucket   Bucket
-	clock    *fakeClock
+	httpConn    mock_http.MockConn
+	signer      mock_auth.MockSigner
+	presigner   mock_auth.MockPresigner
+	v4Presigner mock_auth.MockPresigner
+	bucket      Bucket
+	clock       *fakeClock
 }
 
 func (t *bucketTest) SetUp(i *TestInfo) {
@@ -73,9 +103,20 @@ func (t *bucketTest) SetUp(i *TestInfo) {
 
 	t.httpConn = mock_http.NewMockConn(i.MockController, "httpConn")
 	t.signer = mock_auth.NewMockSigner(i.MockController, "signer")
+	t.presigner = mock_auth.NewMockPresigner(i.MockController, "presigner")
+	t.v4Presigner = mock_auth.NewMockPresigner(i.MockController, "v4Presigner")
 	t.clock = &fakeClock{}
 
-	t.bucket, err = openBucket("some.bucket", t.httpConn, t.signer, t.clock)
+	t.bucket, err = openBucket(
+		"some.bucket",
+		&url.URL{Scheme: "https", Host: "s3.example.com"},
+		false,
+		t.httpConn,
+		t.signer,
+		t.presigner,
+		t.v4Presigner,
+		t.clock)
+
 	AssertEq(nil, err)
 }
 
@@ -95,8 +136,8 @@ func (t *GetObjectTest) KeyNotValidUtf8() {
 	// Call
 	_, err := t.bucket.GetObject(key)
 
-	ExpectThat(err, Error(HasSubstr("valid")))
-	ExpectThat(err, Error(HasSubstr("UTF-8")))
+	ExpectThat(err, ErrorThat(HasSubstr("valid")))
+	ExpectThat(err, ErrorThat(HasSubstr("UTF-8")))
 }
 
 func (t *GetObjectTest) KeyTooLong() {
@@ -105,8 +146,8 @@ func (t *GetObjectTest) KeyTooLong() {
 	// Call
 	_, err := t.bucket.GetObject(key)
 
-	ExpectThat(err, Error(HasSubstr("1024")))
-	ExpectThat(err, Error(HasSubstr("bytes")))
+	ExpectThat(err, ErrorThat(HasSubstr("1024")))
+	ExpectThat(err, ErrorThat(HasSubstr("bytes")))
 }
 
 func (t *GetObjectTest) KeyContainsNullByte() {
@@ -115,7 +156,7 @@ func (t *GetObjectTest) KeyContainsNullByte() {
 	// Call
 	_, err := t.bucket.GetObject(key)
 
-	ExpectThat(err, Error(HasSubstr("U+0000")))
+	ExpectThat(err, ErrorThat(HasSubstr("U+0000")))
 }
 
 func (t *GetObjectTest) KeyContainsOutOfRangeCodepoint() {
@@ -124,7 +165,7 @@ func (t *GetObjectTest) KeyContainsOutOfRangeCodepoint() {
 	// Call
 	_, err := t.bucket.GetObject(key)
 
-	ExpectThat(err, Error(HasSubstr("U+FFFE")))
+	ExpectThat(err, ErrorThat(HasSubstr("U+FFFE")))
 }
 
 func (t *GetObjectTest) KeyIsEmpty() {
@@ -133,7 +174,7 @@ func (t *GetObjectTest) KeyIsEmpty() {
 	// Call
 	_, err := t.bucket.GetObject(key)
 
-	ExpectThat(err, Error(HasSubstr("empty")))
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
 }
 
 func (t *GetObjectTest) CallsSigner() {
@@ -169,8 +210,8 @@ func (t *GetObjectTest) SignerReturnsError() {
 	// Call
 	_, err := t.bucket.GetObject(key)
 
-	ExpectThat(err, Error(HasSubstr("Sign")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
 }
 
 func (t *GetObjectTest) CallsConn() {
@@ -185,8 +226,8 @@ func (t *GetObjectTest) CallsConn() {
 
 	// Conn
 	var httpReq *http.Request
-	ExpectCall(t.httpConn, "SendRequest")(Any()).
-		WillOnce(oglemock.Invoke(func(r *http.Request) (*http.Response, error) {
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) (*http.StreamingResponse, error) {
 		httpReq = r
 		return nil, errors.New("")
 	}))
@@ -206,14 +247,14 @@ func (t *GetObjectTest) ConnReturnsError() {
 		WillOnce(oglemock.Return(nil))
 
 	// Conn
-	ExpectCall(t.httpConn, "SendRequest")(Any()).
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
 		WillOnce(oglemock.Return(nil, errors.New("taco")))
 
 	// Call
 	_, err := t.bucket.GetObject(key)
 
-	ExpectThat(err, Error(HasSubstr("SendRequest")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("StreamRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
 }
 
 func (t *GetObjectTest) ServerReturnsError() {
@@ -224,20 +265,47 @@ func (t *GetObjectTest) ServerReturnsError() {
 		WillOnce(oglemock.Return(nil))
 
 	// Conn
-	resp := &http.Response{
+	resp := &http.StreamingResponse{
 		StatusCode: 500,
-		Body:       []byte("taco"),
+		Body:       newFakeBody("taco"),
 	}
 
-	ExpectCall(t.httpConn, "SendRequest")(Any()).
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	_, err := t.bucket.GetObject(key)
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("500")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *GetObjectTest) ReadingBodyFails() {
+	key := "a"
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	body := newFakeBody("")
+	body.err = errors.New("taco")
+
+	resp := &http.StreamingResponse{
+		StatusCode: 200,
+		Body:       body,
+	}
+
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
 		WillOnce(oglemock.Return(resp, nil))
 
 	// Call
 	_, err := t.bucket.GetObject(key)
 
-	ExpectThat(err, Error(HasSubstr("server")))
-	ExpectThat(err, Error(HasSubstr("500")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("ReadAll")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+	ExpectTrue(body.closed)
 }
 
 func (t *GetObjectTest) ReturnsResponseBody() {
@@ -248,12 +316,13 @@ func (t *GetObjectTest) ReturnsResponseBody() {
 		WillOnce(oglemock.Return(nil))
 
 	// Conn
-	resp := &http.Response{
+	body := newFakeBody("taco")
+	resp := &http.StreamingResponse{
 		StatusCode: 200,
-		Body:       []byte("taco"),
+		Body:       body,
 	}
 
-	ExpectCall(t.httpConn, "SendRequest")(Any()).
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
 		WillOnce(oglemock.Return(resp, nil))
 
 	// Call
@@ -261,6 +330,144 @@ func (t *GetObjectTest) ReturnsResponseBody() {
 	AssertEq(nil, err)
 
 	ExpectThat(data, DeepEquals([]byte("taco")))
+	ExpectTrue(body.closed)
+}
+
+////////////////////////////////////////////////////////////////////////
+// GetObjectReader
+////////////////////////////////////////////////////////////////////////
+
+type GetObjectReaderTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&GetObjectReaderTest{}) }
+
+func (t *GetObjectReaderTest) KeyNotValidUtf8() {
+	key := "\x80\x81\x82"
+
+	// Call
+	_, _, err := t.bucket.GetObjectReader(key)
+
+	ExpectThat(err, ErrorThat(HasSubstr("valid")))
+	ExpectThat(err, ErrorThat(HasSubstr("UTF-8")))
+}
+
+func (t *GetObjectReaderTest) KeyIsEmpty() {
+	key := ""
+
+	// Call
+	_, _, err := t.bucket.GetObjectReader(key)
+
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *GetObjectReaderTest) CallsSigner() {
+	key := "foo/bar/baz"
+
+	// Clock
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+
+	// Signer
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	// Call
+	t.bucket.GetObjectReader(key)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("GET", httpReq.Verb)
+	ExpectEq("/some.bucket/foo/bar/baz", httpReq.Path)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", httpReq.Headers["Date"])
+}
+
+func (t *GetObjectReaderTest) SignerReturnsError() {
+	key := "a"
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(errors.New("taco")))
+
+	// Call
+	_, _, err := t.bucket.GetObjectReader(key)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *GetObjectReaderTest) ConnReturnsError() {
+	key := "a"
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	_, _, err := t.bucket.GetObjectReader(key)
+
+	ExpectThat(err, ErrorThat(HasSubstr("StreamRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *GetObjectReaderTest) ServerReturnsError() {
+	key := "a"
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	body := newFakeBody("taco")
+	resp := &http.StreamingResponse{
+		StatusCode: 500,
+		Body:       body,
+	}
+
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	_, _, err := t.bucket.GetObjectReader(key)
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("500")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+	ExpectTrue(body.closed)
+}
+
+func (t *GetObjectReaderTest) ReturnsBodyAndLength() {
+	key := "a"
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	body := newFakeBody("taco")
+	resp := &http.StreamingResponse{
+		StatusCode:    200,
+		ContentLength: 4,
+		Body:          body,
+	}
+
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	r, size, err := t.bucket.GetObjectReader(key)
+	AssertEq(nil, err)
+
+	ExpectEq(body, r)
+	ExpectEq(4, size)
+	ExpectFalse(body.closed)
 }
 
 ////////////////////////////////////////////////////////////////////////
@@ -280,8 +487,8 @@ func (t *StoreObjectTest) KeyNotValidUtf8() {
 	// Call
 	err := t.bucket.StoreObject(key, data)
 
-	ExpectThat(err, Error(HasSubstr("valid")))
-	ExpectThat(err, Error(HasSubstr("UTF-8")))
+	ExpectThat(err, ErrorThat(HasSubstr("valid")))
+	ExpectThat(err, ErrorThat(HasSubstr("UTF-8")))
 }
 
 func (t *StoreObjectTest) KeyTooLong() {
@@ -291,8 +498,8 @@ func (t *StoreObjectTest) KeyTooLong() {
 	// Call
 	err := t.bucket.StoreObject(key, data)
 
-	ExpectThat(err, Error(HasSubstr("1024")))
-	ExpectThat(err, Error(HasSubstr("bytes")))
+	ExpectThat(err, ErrorThat(HasSubstr("1024")))
+	ExpectThat(err, ErrorThat(HasSubstr("bytes")))
 }
 
 func (t *StoreObjectTest) KeyContainsNullByte() {
@@ -302,7 +509,7 @@ func (t *StoreObjectTest) KeyContainsNullByte() {
 	// Call
 	err := t.bucket.StoreObject(key, data)
 
-	ExpectThat(err, Error(HasSubstr("U+0000")))
+	ExpectThat(err, ErrorThat(HasSubstr("U+0000")))
 }
 
 func (t *StoreObjectTest) KeyContainsOutOfRangeCodepoint() {
@@ -312,7 +519,7 @@ func (t *StoreObjectTest) KeyContainsOutOfRangeCodepoint() {
 	// Call
 	err := t.bucket.StoreObject(key, data)
 
-	ExpectThat(err, Error(HasSubstr("U+FFFE")))
+	ExpectThat(err, ErrorThat(HasSubstr("U+FFFE")))
 }
 
 func (t *StoreObjectTest) KeyIsEmpty() {
@@ -322,7 +529,7 @@ func (t *StoreObjectTest) KeyIsEmpty() {
 	// Call
 	err := t.bucket.StoreObject(key, data)
 
-	ExpectThat(err, Error(HasSubstr("empty")))
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
 }
 
 func (t *StoreObjectTest) CallsSigner() {
@@ -362,8 +569,8 @@ func (t *StoreObjectTest) SignerReturnsError() {
 	// Call
 	err := t.bucket.StoreObject(key, data)
 
-	ExpectThat(err, Error(HasSubstr("Sign")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
 }
 
 func (t *StoreObjectTest) CallsConn() {
@@ -407,8 +614,8 @@ func (t *StoreObjectTest) ConnReturnsError() {
 	// Call
 	err := t.bucket.StoreObject(key, data)
 
-	ExpectThat(err, Error(HasSubstr("SendRequest")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("SendRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
 }
 
 func (t *StoreObjectTest) ServerReturnsError() {
@@ -431,9 +638,9 @@ func (t *StoreObjectTest) ServerReturnsError() {
 	// Call
 	err := t.bucket.StoreObject(key, data)
 
-	ExpectThat(err, Error(HasSubstr("server")))
-	ExpectThat(err, Error(HasSubstr("500")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("500")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
 }
 
 func (t *StoreObjectTest) ServerSaysOkay() {
@@ -459,6 +666,222 @@ func (t *StoreObjectTest) ServerSaysOkay() {
 	ExpectEq(nil, err)
 }
 
+////////////////////////////////////////////////////////////////////////
+// StoreObjectFromReader
+////////////////////////////////////////////////////////////////////////
+
+type StoreObjectFromReaderTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&StoreObjectFromReaderTest{}) }
+
+// A reader that hides any other interfaces implemented by the wrapped reader,
+// in particular io.Seeker.
+type plainReader struct {
+	r io.Reader
+}
+
+func (r *plainReader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+func (t *StoreObjectFromReaderTest) KeyNotValidUtf8() {
+	key := "\x80\x81\x82"
+
+	// Call
+	err := t.bucket.StoreObjectFromReader(key, bytes.NewReader(nil), 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("valid")))
+	ExpectThat(err, ErrorThat(HasSubstr("UTF-8")))
+}
+
+func (t *StoreObjectFromReaderTest) KeyIsEmpty() {
+	key := ""
+
+	// Call
+	err := t.bucket.StoreObjectFromReader(key, bytes.NewReader(nil), 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *StoreObjectFromReaderTest) SeekableReaderIsShorterThanSize() {
+	key := "a"
+	data := []byte("taco")
+
+	// Call
+	err := t.bucket.StoreObjectFromReader(key, bytes.NewReader(data), 5)
+
+	ExpectThat(err, ErrorThat(HasSubstr("5")))
+	ExpectThat(err, ErrorThat(HasSubstr("4")))
+}
+
+func (t *StoreObjectFromReaderTest) StreamIsLongerThanSize() {
+	key := "a"
+	data := []byte("taco")
+
+	// Call
+	err := t.bucket.StoreObjectFromReader(key, &plainReader{bytes.NewReader(data)}, 3)
+
+	ExpectThat(err, ErrorThat(HasSubstr("3")))
+	ExpectThat(err, ErrorThat(HasSubstr("4")))
+}
+
+func (t *StoreObjectFromReaderTest) StreamReturnsError() {
+	key := "a"
+
+	// Reader
+	body := newFakeBody("taco")
+	body.err = errors.New("burrito")
+
+	// Call
+	err := t.bucket.StoreObjectFromReader(key, body, -1)
+
+	ExpectThat(err, ErrorThat(HasSubstr("burrito")))
+}
+
+func (t *StoreObjectFromReaderTest) CallsSignerWithSeekableReader() {
+	key := "foo/bar/baz"
+	data := []byte{0x00, 0xde, 0xad, 0xbe, 0xef}
+
+	// Reader, partially consumed already.
+	r := bytes.NewReader(append([]byte{0x17}, data...))
+	_, err := r.ReadByte()
+	AssertEq(nil, err)
+
+	// Clock
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+
+	// Signer
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	// Call
+	t.bucket.StoreObjectFromReader(key, r, int64(len(data)))
+
+	AssertNe(nil, httpReq)
+	ExpectEq("PUT", httpReq.Verb)
+	ExpectEq("/some.bucket/foo/bar/baz", httpReq.Path)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", httpReq.Headers["Date"])
+	ExpectEq(computeBase64Md5(data), httpReq.Headers["Content-MD5"])
+	ExpectEq(len(data), httpReq.ContentLength)
+
+	AssertNe(nil, httpReq.BodyReader)
+	body, err := ioutil.ReadAll(httpReq.BodyReader)
+	AssertEq(nil, err)
+	ExpectThat(body, DeepEquals(data))
+}
+
+func (t *StoreObjectFromReaderTest) CallsConnWithStreamOfUnknownLength() {
+	key := "a"
+	data := []byte{0x00, 0xde, 0xad, 0xbe, 0xef}
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn. The body must be read during the call, since it may be backed by a
+	// temporary file.
+	var httpReq *http.Request
+	var body []byte
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) (*http.Response, error) {
+			httpReq = r
+			body, _ = ioutil.ReadAll(r.BodyReader)
+			return nil, errors.New("")
+		}))
+
+	// Call
+	t.bucket.StoreObjectFromReader(key, &plainReader{bytes.NewReader(data)}, -1)
+
+	AssertNe(nil, httpReq)
+	ExpectEq(computeBase64Md5(data), httpReq.Headers["Content-MD5"])
+	ExpectEq(len(data), httpReq.ContentLength)
+	ExpectThat(body, DeepEquals(data))
+}
+
+func (t *StoreObjectFromReaderTest) SignerReturnsError() {
+	key := "a"
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(errors.New("taco")))
+
+	// Call
+	err := t.bucket.StoreObjectFromReader(key, bytes.NewReader(nil), 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *StoreObjectFromReaderTest) ConnReturnsError() {
+	key := "a"
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	err := t.bucket.StoreObjectFromReader(key, bytes.NewReader(nil), 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("SendRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *StoreObjectFromReaderTest) ServerReturnsError() {
+	key := "a"
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 500,
+		Body:       []byte("taco"),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	err := t.bucket.StoreObjectFromReader(key, bytes.NewReader(nil), 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("500")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *StoreObjectFromReaderTest) ServerSaysOkay() {
+	key := "a"
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 200,
+		Body:       []byte("taco"),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	err := t.bucket.StoreObjectFromReader(key, &plainReader{bytes.NewReader(nil)}, -1)
+
+	ExpectEq(nil, err)
+}
+
 ////////////////////////////////////////////////////////////////////////
 // DeleteObject
 ////////////////////////////////////////////////////////////////////////
@@ -475,8 +898,8 @@ func (t *DeleteObjectTest) KeyNotValidUtf8() {
 	// Call
 	err := t.bucket.DeleteObject(key)
 
-	ExpectThat(err, Error(HasSubstr("valid")))
-	ExpectThat(err, Error(HasSubstr("UTF-8")))
+	ExpectThat(err, ErrorThat(HasSubstr("valid")))
+	ExpectThat(err, ErrorThat(HasSubstr("UTF-8")))
 }
 
 func (t *DeleteObjectTest) KeyTooLong() {
@@ -485,8 +908,8 @@ func (t *DeleteObjectTest) KeyTooLong() {
 	// Call
 	err := t.bucket.DeleteObject(key)
 
-	ExpectThat(err, Error(HasSubstr("1024")))
-	ExpectThat(err, Error(HasSubstr("bytes")))
+	ExpectThat(err, ErrorThat(HasSubstr("1024")))
+	ExpectThat(err, ErrorThat(HasSubstr("bytes")))
 }
 
 func (t *DeleteObjectTest) KeyContainsNullByte() {
@@ -495,7 +918,7 @@ func (t *DeleteObjectTest) KeyContainsNullByte() {
 	// Call
 	err := t.bucket.DeleteObject(key)
 
-	ExpectThat(err, Error(HasSubstr("U+0000")))
+	ExpectThat(err, ErrorThat(HasSubstr("U+0000")))
 }
 
 func (t *DeleteObjectTest) KeyContainsOutOfRangeCodepoint() {
@@ -504,7 +927,7 @@ func (t *DeleteObjectTest) KeyContainsOutOfRangeCodepoint() {
 	// Call
 	err := t.bucket.DeleteObject(key)
 
-	ExpectThat(err, Error(HasSubstr("U+FFFE")))
+	ExpectThat(err, ErrorThat(HasSubstr("U+FFFE")))
 }
 
 func (t *DeleteObjectTest) KeyIsEmpty() {
@@ -513,7 +936,7 @@ func (t *DeleteObjectTest) KeyIsEmpty() {
 	// Call
 	err := t.bucket.DeleteObject(key)
 
-	ExpectThat(err, Error(HasSubstr("empty")))
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
 }
 
 func (t *DeleteObjectTest) CallsSigner() {
@@ -549,8 +972,8 @@ func (t *DeleteObjectTest) SignerReturnsError() {
 	// Call
 	err := t.bucket.DeleteObject(key)
 
-	ExpectThat(err, Error(HasSubstr("Sign")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
 }
 
 func (t *DeleteObjectTest) CallsConn() {
@@ -592,8 +1015,8 @@ func (t *DeleteObjectTest) ConnReturnsError() {
 	// Call
 	err := t.bucket.DeleteObject(key)
 
-	ExpectThat(err, Error(HasSubstr("SendRequest")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("SendRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
 }
 
 func (t *DeleteObjectTest) ServerReturnsError() {
@@ -615,9 +1038,9 @@ func (t *DeleteObjectTest) ServerReturnsError() {
 	// Call
 	err := t.bucket.DeleteObject(key)
 
-	ExpectThat(err, Error(HasSubstr("server")))
-	ExpectThat(err, Error(HasSubstr("500")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("500")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
 }
 
 func (t *DeleteObjectTest) ServerReturnsNoContent() {
@@ -657,8 +1080,8 @@ func (t *ListKeysTest) PrevKeyNotValidUtf8() {
 	// Call
 	_, err := t.bucket.ListKeys(prevKey)
 
-	ExpectThat(err, Error(HasSubstr("valid")))
-	ExpectThat(err, Error(HasSubstr("UTF-8")))
+	ExpectThat(err, ErrorThat(HasSubstr("valid")))
+	ExpectThat(err, ErrorThat(HasSubstr("UTF-8")))
 }
 
 func (t *ListKeysTest) PrevKeyTooLong() {
@@ -667,8 +1090,8 @@ func (t *ListKeysTest) PrevKeyTooLong() {
 	// Call
 	_, err := t.bucket.ListKeys(prevKey)
 
-	ExpectThat(err, Error(HasSubstr("1024")))
-	ExpectThat(err, Error(HasSubstr("bytes")))
+	ExpectThat(err, ErrorThat(HasSubstr("1024")))
+	ExpectThat(err, ErrorThat(HasSubstr("bytes")))
 }
 
 func (t *ListKeysTest) PrevKeyContainsNullByte() {
@@ -677,7 +1100,7 @@ func (t *ListKeysTest) PrevKeyContainsNullByte() {
 	// Call
 	_, err := t.bucket.ListKeys(prevKey)
 
-	ExpectThat(err, Error(HasSubstr("U+0000")))
+	ExpectThat(err, ErrorThat(HasSubstr("U+0000")))
 }
 
 func (t *ListKeysTest) PrevKeyContainsOutOfRangeCodepoint() {
@@ -686,7 +1109,7 @@ func (t *ListKeysTest) PrevKeyContainsOutOfRangeCodepoint() {
 	// Call
 	_, err := t.bucket.ListKeys(prevKey)
 
-	ExpectThat(err, Error(HasSubstr("U+FFFE")))
+	ExpectThat(err, ErrorThat(HasSubstr("U+FFFE")))
 }
 
 func (t *ListKeysTest) CallsSignerWithEmptyMin() {
@@ -749,8 +1172,8 @@ func (t *ListKeysTest) SignerReturnsError() {
 	// Call
 	_, err := t.bucket.ListKeys(prevKey)
 
-	ExpectThat(err, Error(HasSubstr("Sign")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
 }
 
 func (t *ListKeysTest) CallsConn() {
@@ -792,8 +1215,8 @@ func (t *ListKeysTest) ConnReturnsError() {
 	// Call
 	_, err := t.bucket.ListKeys(prevKey)
 
-	ExpectThat(err, Error(HasSubstr("SendRequest")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("SendRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
 }
 
 func (t *ListKeysTest) ServerReturnsError() {
@@ -815,9 +1238,9 @@ func (t *ListKeysTest) ServerReturnsError() {
 	// Call
 	_, err := t.bucket.ListKeys(prevKey)
 
-	ExpectThat(err, Error(HasSubstr("server")))
-	ExpectThat(err, Error(HasSubstr("500")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("500")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
 }
 
 func (t *ListKeysTest) ResponseBodyIsJunk() {
@@ -839,8 +1262,8 @@ func (t *ListKeysTest) ResponseBodyIsJunk() {
 	// Call
 	_, err := t.bucket.ListKeys(prevKey)
 
-	ExpectThat(err, Error(HasSubstr("Invalid")))
-	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectThat(err, ErrorThat(HasSubstr("Invalid")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
 }
 
 func (t *ListKeysTest) WrongRootTag() {
@@ -868,8 +1291,8 @@ func (t *ListKeysTest) WrongRootTag() {
 	// Call
 	_, err := t.bucket.ListKeys(prevKey)
 
-	ExpectThat(err, Error(HasSubstr("Invalid")))
-	ExpectThat(err, Error(HasSubstr("FooBar")))
+	ExpectThat(err, ErrorThat(HasSubstr("Invalid")))
+	ExpectThat(err, ErrorThat(HasSubstr("FooBar")))
 }
 
 func (t *ListKeysTest) ResponseContainsNoKeys() {
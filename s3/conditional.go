@@ -0,0 +1,138 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"io"
+	sys_time "time"
+)
+
+// The format HTTP uses for dates in headers such as If-Modified-Since.
+const httpTimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// Conditions that must hold for a read to return the object's data, and the
+// version of the object to read. The zero value imposes no conditions and
+// reads the latest version.
+type GetOptions struct {
+	// If non-empty, the object's data is returned only if its entity tag
+	// differs from this one. Otherwise the error is of type *NotModifiedError.
+	IfNoneMatch string
+
+	// If non-zero, the object's data is returned only if it has been modified
+	// since this time. Otherwise the error is of type *NotModifiedError.
+	IfModifiedSince sys_time.Time
+
+	// If non-empty, the object's data is returned only if its entity tag
+	// matches this one. Otherwise the error is of type *PreconditionFailedError.
+	IfMatch string
+
+	// If non-zero, the object's data is returned only if it has not been
+	// modified since this time. Otherwise the error is of type
+	// *PreconditionFailedError.
+	IfUnmodifiedSince sys_time.Time
+
+	// If non-empty, the version of the object with this ID is read rather than
+	// the latest one. See Bucket.ListObjectVersions.
+	VersionId string
+
+	// The key with which the object was encrypted, if it was stored with
+	// StoreOptions.CustomerKey. S3 refuses to return such objects without it.
+	CustomerKey []byte
+}
+
+// Return the request headers corresponding to the supplied options.
+func getOptionHeaders(opts *GetOptions) (map[string]string, error) {
+	headers := map[string]string{}
+
+	if opts.IfNoneMatch != "" {
+		headers["If-None-Match"] = opts.IfNoneMatch
+	}
+
+	if !opts.IfModifiedSince.IsZero() {
+		headers["If-Modified-Since"] = opts.IfModifiedSince.UTC().Format(httpTimeFormat)
+	}
+
+	if opts.IfMatch != "" {
+		headers["If-Match"] = opts.IfMatch
+	}
+
+	if !opts.IfUnmodifiedSince.IsZero() {
+		headers["If-Unmodified-Since"] = opts.IfUnmodifiedSince.UTC().Format(httpTimeFormat)
+	}
+
+	if err := addCustomerKeyHeaders(headers, opts.CustomerKey); err != nil {
+		return nil, err
+	}
+
+	return headers, nil
+}
+
+// Return the request parameters corresponding to the supplied options.
+func getOptionParams(opts *GetOptions) map[string]string {
+	params := map[string]string{}
+
+	if opts.VersionId != "" {
+		params["versionId"] = opts.VersionId
+	}
+
+	return params
+}
+
+func (b *bucket) GetObjectWithOptions(
+	key string,
+	opts *GetOptions) (r io.ReadCloser, info *ObjectInfo, err error) {
+	if opts == nil {
+		opts = &GetOptions{}
+	}
+
+	headers, err := getOptionHeaders(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Send the request.
+	httpResp, err := b.sendGetRequest(key, headers, getOptionParams(opts))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check the response.
+	switch httpResp.StatusCode {
+	case 200:
+	case 304:
+		httpResp.Body.Close()
+		return nil, nil, &NotModifiedError{Key: key}
+
+	case 412:
+		httpResp.Body.Close()
+		return nil, nil, &PreconditionFailedError{Key: key}
+
+	default:
+		return nil, nil, streamingResponseError(httpResp)
+	}
+
+	// Parse the object's metadata.
+	if info, err = parseObjectInfo(key, httpResp.Headers); err != nil {
+		httpResp.Body.Close()
+		return nil, nil, err
+	}
+
+	if info.Size < 0 {
+		info.Size = httpResp.ContentLength
+	}
+
+	return httpResp.Body, info, nil
+}
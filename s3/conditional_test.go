@@ -0,0 +1,216 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestConditional(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type GetObjectWithOptionsTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&GetObjectWithOptionsTest{}) }
+
+// Call GetObjectWithOptions with the supplied options, returning the request
+// passed to the signer.
+func (t *GetObjectWithOptionsTest) captureRequest(
+	opts *GetOptions) *http.Request {
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	t.bucket.GetObjectWithOptions("a", opts)
+	return httpReq
+}
+
+// Set up the signer and conn to return the supplied response.
+func (t *GetObjectWithOptionsTest) respondWith(resp *http.StreamingResponse) {
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *GetObjectWithOptionsTest) KeyIsEmpty() {
+	// Call
+	_, _, err := t.bucket.GetObjectWithOptions("", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *GetObjectWithOptionsTest) NilOptions() {
+	httpReq := t.captureRequest(nil)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("GET", httpReq.Verb)
+	ExpectEq("/some.bucket/a", httpReq.Path)
+	ExpectEq(1, len(httpReq.Headers), "%v", httpReq.Headers)
+	ExpectEq(0, len(httpReq.Parameters), "%v", httpReq.Parameters)
+}
+
+func (t *GetObjectWithOptionsTest) AllOptions() {
+	loc := time.FixedZone("UTC-8", -8*60*60)
+	opts := &GetOptions{
+		IfNoneMatch:       `"taco"`,
+		IfModifiedSince:   time.Date(1985, time.March, 18, 7, 33, 17, 123, loc),
+		IfMatch:           `"burrito"`,
+		IfUnmodifiedSince: time.Date(2012, time.August, 15, 22, 56, 00, 0, time.UTC),
+		VersionId:         "enchilada",
+	}
+
+	httpReq := t.captureRequest(opts)
+
+	AssertNe(nil, httpReq)
+	ExpectEq(`"taco"`, httpReq.Headers["If-None-Match"])
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 GMT", httpReq.Headers["If-Modified-Since"])
+	ExpectEq(`"burrito"`, httpReq.Headers["If-Match"])
+	ExpectEq("Wed, 15 Aug 2012 22:56:00 GMT", httpReq.Headers["If-Unmodified-Since"])
+	ExpectThat(httpReq.Parameters, DeepEquals(map[string]string{"versionId": "enchilada"}))
+}
+
+func (t *GetObjectWithOptionsTest) CustomerKey() {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	httpReq := t.captureRequest(&GetOptions{CustomerKey: key})
+
+	AssertNe(nil, httpReq)
+	ExpectEq("AES256", httpReq.Headers["x-amz-server-side-encryption-customer-algorithm"])
+	ExpectEq(
+		"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+		httpReq.Headers["x-amz-server-side-encryption-customer-key"])
+	ExpectEq(
+		computeBase64Md5(key),
+		httpReq.Headers["x-amz-server-side-encryption-customer-key-MD5"])
+}
+
+func (t *GetObjectWithOptionsTest) CustomerKeyHasWrongLength() {
+	// Call
+	_, _, err := t.bucket.GetObjectWithOptions("a", &GetOptions{CustomerKey: []byte("taco")})
+
+	ExpectThat(err, ErrorThat(HasSubstr("32 bytes long; got 4")))
+}
+
+func (t *GetObjectWithOptionsTest) ServerSaysNotModified() {
+	body := newFakeBody("")
+	t.respondWith(&http.StreamingResponse{
+		StatusCode: 304,
+		Body:       body,
+	})
+
+	// Call
+	_, _, err := t.bucket.GetObjectWithOptions("a", nil)
+
+	AssertNe(nil, err)
+	notModified, ok := err.(*NotModifiedError)
+	AssertTrue(ok, "%v", err)
+	ExpectEq("a", notModified.Key)
+	ExpectTrue(body.closed)
+}
+
+func (t *GetObjectWithOptionsTest) ServerSaysPreconditionFailed() {
+	body := newFakeBody("<Error>...</Error>")
+	t.respondWith(&http.StreamingResponse{
+		StatusCode: 412,
+		Body:       body,
+	})
+
+	// Call
+	_, _, err := t.bucket.GetObjectWithOptions("a", nil)
+
+	AssertNe(nil, err)
+	failed, ok := err.(*PreconditionFailedError)
+	AssertTrue(ok, "%v", err)
+	ExpectEq("a", failed.Key)
+	ExpectTrue(body.closed)
+}
+
+func (t *GetObjectWithOptionsTest) ServerReturnsError() {
+	body := newFakeBody("taco")
+	t.respondWith(&http.StreamingResponse{
+		StatusCode: 500,
+		Body:       body,
+	})
+
+	// Call
+	_, _, err := t.bucket.GetObjectWithOptions("a", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("500")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+	ExpectTrue(body.closed)
+}
+
+func (t *GetObjectWithOptionsTest) InvalidHeaders() {
+	body := newFakeBody("taco")
+	t.respondWith(&http.StreamingResponse{
+		StatusCode: 200,
+		Body:       body,
+		Headers:    map[string]string{"Last-Modified": "burrito"},
+	})
+
+	// Call
+	_, _, err := t.bucket.GetObjectWithOptions("a", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Last-Modified")))
+	ExpectTrue(body.closed)
+}
+
+func (t *GetObjectWithOptionsTest) ReturnsDataAndInfo() {
+	body := newFakeBody("taco")
+	t.respondWith(&http.StreamingResponse{
+		StatusCode:    200,
+		Body:          body,
+		ContentLength: 4,
+		Headers: map[string]string{
+			"Etag":          `"deadbeef"`,
+			"Last-Modified": "Mon, 18 Mar 1985 15:33:17 GMT",
+		},
+	})
+
+	// Call
+	r, info, err := t.bucket.GetObjectWithOptions("a", nil)
+	AssertEq(nil, err)
+
+	ExpectEq("a", info.Key)
+	ExpectEq(4, info.Size)
+	ExpectEq(`"deadbeef"`, info.ETag)
+	ExpectEq(1985, info.LastModified.Year())
+
+	data, err := ioutil.ReadAll(r)
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+	ExpectFalse(body.closed)
+}
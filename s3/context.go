@@ -0,0 +1,55 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"context"
+	"github.com/jacobsa/aws/s3/http"
+)
+
+func (b *bucket) WithContext(ctx context.Context) Bucket {
+	bound := *b
+	bound.httpConn = bindContext(b.httpConn, ctx)
+	return &bound
+}
+
+// Return a connection that attaches the supplied context to each request
+// before passing it on to c. If c is itself bound to a context, the new context
+// replaces it rather than being overridden by it.
+func bindContext(c http.Conn, ctx context.Context) http.Conn {
+	if bound, ok := c.(*contextConn); ok {
+		c = bound.wrapped
+	}
+
+	return &contextConn{c, ctx}
+}
+
+// A connection that attaches a context to each request before passing it on.
+type contextConn struct {
+	wrapped http.Conn
+	ctx     context.Context
+}
+
+func (c *contextConn) SendRequest(r *http.Request) (*http.Response, error) {
+	r.Context = c.ctx
+	return c.wrapped.SendRequest(r)
+}
+
+func (c *contextConn) StreamRequest(
+	r *http.Request) (*http.StreamingResponse, error) {
+	r.Context = c.ctx
+	return c.wrapped.StreamRequest(r)
+}
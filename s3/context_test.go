@@ -0,0 +1,116 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"context"
+	"errors"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"testing"
+)
+
+func TestContext(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type WithContextTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&WithContextTest{}) }
+
+// Set up the signer to succeed and the conn to capture the request passed to
+// SendRequest, returning a pointer that will be filled in.
+func (t *WithContextTest) captureSentRequest() **http.Request {
+	httpReq := new(*http.Request)
+
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) (*http.Response, error) {
+			*httpReq = r
+			return nil, errors.New("")
+		}))
+
+	return httpReq
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *WithContextTest) StreamingRequest() {
+	ctx := context.WithValue(context.Background(), "taco", "burrito")
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	var httpReq *http.Request
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) (*http.StreamingResponse, error) {
+			httpReq = r
+			return nil, errors.New("")
+		}))
+
+	// Call
+	t.bucket.WithContext(ctx).GetObject("a")
+
+	AssertNe(nil, httpReq)
+	ExpectEq(ctx, httpReq.Context)
+}
+
+func (t *WithContextTest) NonStreamingRequest() {
+	ctx := context.WithValue(context.Background(), "taco", "burrito")
+	httpReq := t.captureSentRequest()
+
+	// Call
+	t.bucket.WithContext(ctx).DeleteObject("a")
+
+	AssertNe(nil, *httpReq)
+	ExpectEq(ctx, (*httpReq).Context)
+}
+
+func (t *WithContextTest) ReceiverIsUnaffected() {
+	ctx := context.WithValue(context.Background(), "taco", "burrito")
+	t.bucket.WithContext(ctx)
+
+	httpReq := t.captureSentRequest()
+
+	// Call
+	t.bucket.DeleteObject("a")
+
+	AssertNe(nil, *httpReq)
+	ExpectEq(nil, (*httpReq).Context)
+}
+
+func (t *WithContextTest) LaterContextWins() {
+	ctx1 := context.WithValue(context.Background(), "taco", "burrito")
+	ctx2 := context.WithValue(context.Background(), "enchilada", "queso")
+	httpReq := t.captureSentRequest()
+
+	// Call
+	t.bucket.WithContext(ctx1).WithContext(ctx2).DeleteObject("a")
+
+	AssertNe(nil, *httpReq)
+	ExpectEq(ctx2, (*httpReq).Context)
+}
@@ -0,0 +1,196 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/jacobsa/aws/s3/http"
+	"net/url"
+	sys_time "time"
+)
+
+// Controls what happens to an object's metadata when it is copied.
+type MetadataDirective string
+
+const (
+	// Copy the source object's metadata to the destination object.
+	MetadataCopy MetadataDirective = "COPY"
+
+	// Replace the metadata with that specified in the CopyOptions.
+	MetadataReplace MetadataDirective = "REPLACE"
+)
+
+// Options controlling Bucket.CopyObject. The zero value copies an object
+// within the same bucket, along with its metadata.
+type CopyOptions struct {
+	// The bucket containing the source object. If empty, the source object is
+	// in the bucket to which it is being copied.
+	SourceBucket string
+
+	// What to do with the source object's metadata. If empty, MetadataCopy is
+	// used.
+	MetadataDirective MetadataDirective
+
+	// Metadata for the destination object, with the same meanings as the
+	// corresponding fields of StoreOptions. These may be set only when
+	// MetadataDirective is MetadataReplace.
+	ContentType     string
+	ContentEncoding string
+	CacheControl    string
+	Metadata        map[string]string
+
+	// The canned ACL to apply to the destination object. If empty, it is
+	// private regardless of the ACL of the source object.
+	Acl CannedAcl
+
+	// The storage class of the destination object. If empty, it is STANDARD.
+	StorageClass StorageClass
+}
+
+// The result of successfully copying an object.
+type CopyObjectResult struct {
+	// The entity tag of the new object (including quotes).
+	ETag string
+
+	// The time at which the new object was last modified.
+	LastModified sys_time.Time
+}
+
+type copyObjectResult struct {
+	XMLName      xml.Name
+	ETag         string
+	LastModified string
+}
+
+func (b *bucket) CopyObject(
+	srcKey string,
+	dstKey string,
+	opts *CopyOptions) (result *CopyObjectResult, err error) {
+	// Validate the keys.
+	if err := ValidateKey(srcKey); err != nil {
+		return nil, fmt.Errorf("Invalid source key: %v", err)
+	}
+
+	if err := ValidateKey(dstKey); err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+
+	srcBucket := opts.SourceBucket
+	if srcBucket == "" {
+		srcBucket = b.name
+	}
+
+	directive := opts.MetadataDirective
+	switch directive {
+	case "":
+		directive = MetadataCopy
+		fallthrough
+
+	case MetadataCopy:
+		if opts.ContentType != "" ||
+			opts.ContentEncoding != "" ||
+			opts.CacheControl != "" ||
+			len(opts.Metadata) != 0 {
+			return nil, fmt.Errorf("Metadata may be set only with MetadataReplace.")
+		}
+
+	case MetadataReplace:
+
+	default:
+		return nil, fmt.Errorf("Invalid metadata directive: %s", directive)
+	}
+
+	// The source is named by a URL-encoded path, in the same manner as the
+	// paths of other requests.
+	copySource := (&url.URL{Path: fmt.Sprintf("/%s/%s", srcBucket, srcKey)}).RequestURI()
+
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectCOPY.html
+	httpReq := &http.Request{
+		Verb:   "PUT",
+		Path:   b.objectPath(dstKey),
+		Bucket: b.hostBucket(),
+		Headers: map[string]string{
+			"Date":                     b.clock.Now().UTC().Format(sys_time.RFC1123),
+			"x-amz-copy-source":        copySource,
+			"x-amz-metadata-directive": string(directive),
+		},
+	}
+
+	storeOpts := &StoreOptions{
+		ContentType:     opts.ContentType,
+		ContentEncoding: opts.ContentEncoding,
+		CacheControl:    opts.CacheControl,
+		Acl:             opts.Acl,
+		StorageClass:    opts.StorageClass,
+		Metadata:        opts.Metadata,
+	}
+
+	if err := addStoreOptionHeaders(httpReq, storeOpts); err != nil {
+		return nil, err
+	}
+
+	// Sign the request.
+	if err := b.signer.Sign(httpReq); err != nil {
+		return nil, fmt.Errorf("Sign: %v", err)
+	}
+
+	// Send the request.
+	httpResp, err := b.httpConn.SendRequest(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("SendRequest: %v", err)
+	}
+
+	// Check the response.
+	if httpResp.StatusCode != 200 {
+		return nil, newError(httpResp.StatusCode, httpResp.Body)
+	}
+
+	// As with multipart uploads, S3 may report an error after having already
+	// sent a 200 status code.
+	parsed := copyObjectResult{}
+	if err := xml.Unmarshal(httpResp.Body, &parsed); err != nil {
+		return nil, fmt.Errorf(
+			"Invalid data from server (%s): %s",
+			err.Error(),
+			httpResp.Body)
+	}
+
+	switch parsed.XMLName.Local {
+	case "CopyObjectResult":
+	case "Error":
+		return nil, newError(httpResp.StatusCode, httpResp.Body)
+	default:
+		return nil, fmt.Errorf("Invalid data from server: %s", httpResp.Body)
+	}
+
+	result = &CopyObjectResult{
+		ETag: parsed.ETag,
+	}
+
+	if result.LastModified, err = sys_time.Parse(sys_time.RFC3339, parsed.LastModified); err != nil {
+		return nil, fmt.Errorf("Invalid LastModified from server: %s", parsed.LastModified)
+	}
+
+	return result, nil
+}
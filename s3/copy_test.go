@@ -0,0 +1,267 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"testing"
+	"time"
+)
+
+func TestCopy(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type CopyObjectTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&CopyObjectTest{}) }
+
+// Call CopyObject with the supplied options, returning the request passed to
+// the signer.
+func (t *CopyObjectTest) captureRequest(opts *CopyOptions) *http.Request {
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	t.bucket.CopyObject("src", "dst", opts)
+	return httpReq
+}
+
+// Set up the signer and conn to return the supplied response.
+func (t *CopyObjectTest) respondWith(resp *http.Response) {
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *CopyObjectTest) SourceKeyIsInvalid() {
+	// Call
+	_, err := t.bucket.CopyObject("", "dst", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("source key")))
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *CopyObjectTest) DestinationKeyIsInvalid() {
+	// Call
+	_, err := t.bucket.CopyObject("src", "", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *CopyObjectTest) InvalidDirective() {
+	opts := &CopyOptions{MetadataDirective: "taco"}
+
+	// Call
+	_, err := t.bucket.CopyObject("src", "dst", opts)
+
+	ExpectThat(err, ErrorThat(HasSubstr("directive")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *CopyObjectTest) MetadataWithoutReplace() {
+	opts := &CopyOptions{ContentType: "text/plain"}
+
+	// Call
+	_, err := t.bucket.CopyObject("src", "dst", opts)
+
+	ExpectThat(err, ErrorThat(HasSubstr("MetadataReplace")))
+}
+
+func (t *CopyObjectTest) NilOptions() {
+	// Clock
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+
+	httpReq := t.captureRequest(nil)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("PUT", httpReq.Verb)
+	ExpectEq("/some.bucket/dst", httpReq.Path)
+	ExpectEq(nil, httpReq.Body)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", httpReq.Headers["Date"])
+	ExpectEq("/some.bucket/src", httpReq.Headers["x-amz-copy-source"])
+	ExpectEq("COPY", httpReq.Headers["x-amz-metadata-directive"])
+	ExpectEq(3, len(httpReq.Headers), "%v", httpReq.Headers)
+}
+
+func (t *CopyObjectTest) CrossBucketWithReplacedMetadata() {
+	opts := &CopyOptions{
+		SourceBucket:      "other.bucket",
+		MetadataDirective: MetadataReplace,
+		ContentType:       "text/plain",
+		Metadata:          map[string]string{"flavor": "taco"},
+		Acl:               AclPublicRead,
+		StorageClass:      StorageClassReducedRedundancy,
+	}
+
+	httpReq := t.captureRequest(opts)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("/some.bucket/dst", httpReq.Path)
+	ExpectEq("/other.bucket/src", httpReq.Headers["x-amz-copy-source"])
+	ExpectEq("REPLACE", httpReq.Headers["x-amz-metadata-directive"])
+	ExpectEq("text/plain", httpReq.Headers["Content-Type"])
+	ExpectEq("taco", httpReq.Headers["x-amz-meta-flavor"])
+	ExpectEq("public-read", httpReq.Headers["x-amz-acl"])
+	ExpectEq("REDUCED_REDUNDANCY", httpReq.Headers["x-amz-storage-class"])
+}
+
+func (t *CopyObjectTest) SourceKeyIsEncoded() {
+	// Signer
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	// Call
+	t.bucket.CopyObject("타코 ?&", "dst", nil)
+
+	AssertNe(nil, httpReq)
+	ExpectEq(
+		"/some.bucket/%ED%83%80%EC%BD%94%20%3F&",
+		httpReq.Headers["x-amz-copy-source"])
+}
+
+func (t *CopyObjectTest) SignerReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(errors.New("taco")))
+
+	// Call
+	_, err := t.bucket.CopyObject("src", "dst", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *CopyObjectTest) ConnReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	_, err := t.bucket.CopyObject("src", "dst", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("SendRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *CopyObjectTest) ServerReturnsError() {
+	t.respondWith(&http.Response{
+		StatusCode: 404,
+		Body:       []byte("taco"),
+	})
+
+	// Call
+	_, err := t.bucket.CopyObject("src", "dst", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("404")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *CopyObjectTest) ServerReturnsErrorAfterOkStatus() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<Error>
+				<Code>InternalError</Code>
+				<Message>We encountered an internal error.</Message>
+			</Error>`),
+	})
+
+	// Call
+	_, err := t.bucket.CopyObject("src", "dst", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("InternalError")))
+	ExpectThat(err, ErrorThat(HasSubstr("internal error")))
+}
+
+func (t *CopyObjectTest) ResponseBodyIsJunk() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body:       []byte("taco"),
+	})
+
+	// Call
+	_, err := t.bucket.CopyObject("src", "dst", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Invalid")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *CopyObjectTest) InvalidLastModified() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<CopyObjectResult>
+				<LastModified>taco</LastModified>
+				<ETag>"9b2cf535f27731c974343645a3985328"</ETag>
+			</CopyObjectResult>`),
+	})
+
+	// Call
+	_, err := t.bucket.CopyObject("src", "dst", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("LastModified")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *CopyObjectTest) ReturnsResult() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<CopyObjectResult>
+				<LastModified>2009-10-12T17:50:30.000Z</LastModified>
+				<ETag>"9b2cf535f27731c974343645a3985328"</ETag>
+			</CopyObjectResult>`),
+	})
+
+	// Call
+	result, err := t.bucket.CopyObject("src", "dst", nil)
+	AssertEq(nil, err)
+
+	ExpectEq(`"9b2cf535f27731c974343645a3985328"`, result.ETag)
+	ExpectTrue(
+		result.LastModified.Equal(time.Date(2009, time.October, 12, 17, 50, 30, 0, time.UTC)),
+		"%v",
+		result.LastModified)
+}
@@ -0,0 +1,163 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/jacobsa/aws/s3/http"
+	sys_time "time"
+)
+
+// The maximum number of keys that may be passed to Bucket.DeleteObjects.
+const MaxDeleteObjectsKeys = 1000
+
+// The result of a call to Bucket.DeleteObjects.
+type DeleteObjectsResult struct {
+	// The keys that were successfully deleted. Always empty in quiet mode.
+	Deleted []string
+
+	// The keys that could not be deleted, along with the reasons why.
+	Errors []DeleteObjectError
+}
+
+// A failure to delete a particular key within a call to
+// Bucket.DeleteObjects.
+type DeleteObjectError struct {
+	Key     string
+	Code    string
+	Message string
+}
+
+func (e *DeleteObjectError) Error() string {
+	return fmt.Sprintf("Couldn't delete %s: %s: %s", e.Key, e.Code, e.Message)
+}
+
+type deleteObject struct {
+	Key string
+}
+
+type deleteRequest struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool
+	Object  []deleteObject
+}
+
+type deleteResultDeleted struct {
+	Key string
+}
+
+type deleteResult struct {
+	XMLName xml.Name
+	Deleted []deleteResultDeleted
+	Error   []DeleteObjectError
+}
+
+func (b *bucket) DeleteObjects(
+	keys []string,
+	quiet bool) (result *DeleteObjectsResult, err error) {
+	// Validate the keys.
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("At least one key is required.")
+	}
+
+	if len(keys) > MaxDeleteObjectsKeys {
+		return nil, fmt.Errorf(
+			"At most %d keys may be deleted at once; got %d.",
+			MaxDeleteObjectsKeys,
+			len(keys))
+	}
+
+	reqBody := deleteRequest{Quiet: quiet}
+	for _, key := range keys {
+		if err := ValidateKey(key); err != nil {
+			return nil, err
+		}
+
+		reqBody.Object = append(reqBody.Object, deleteObject{key})
+	}
+
+	body, err := xml.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("xml.Marshal: %v", err)
+	}
+
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/multiobjectdeleteapi.html
+	httpReq := &http.Request{
+		Verb:   "POST",
+		Path:   b.bucketPath(),
+		Bucket: b.hostBucket(),
+		Body:   body,
+		Headers: map[string]string{
+			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+		Parameters: map[string]string{
+			"delete": "",
+		},
+	}
+
+	// The Content-MD5 header is required for this request.
+	if err := addMd5Header(httpReq, httpReq.Body); err != nil {
+		return nil, err
+	}
+
+	// Sign the request.
+	if err := b.signer.Sign(httpReq); err != nil {
+		return nil, fmt.Errorf("Sign: %v", err)
+	}
+
+	// Send the request.
+	httpResp, err := b.httpConn.SendRequest(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("SendRequest: %v", err)
+	}
+
+	// Check the response.
+	if httpResp.StatusCode != 200 {
+		return nil, newError(httpResp.StatusCode, httpResp.Body)
+	}
+
+	// Parse the body, which may describe an error despite the status code.
+	parsed := deleteResult{}
+	if err := xml.Unmarshal(httpResp.Body, &parsed); err != nil {
+		return nil, fmt.Errorf(
+			"Invalid data from server (%s): %s",
+			err.Error(),
+			httpResp.Body)
+	}
+
+	switch parsed.XMLName.Local {
+	case "DeleteResult":
+	case "Error":
+		return nil, newError(httpResp.StatusCode, httpResp.Body)
+	default:
+		return nil, fmt.Errorf("Invalid data from server: %s", httpResp.Body)
+	}
+
+	result = &DeleteObjectsResult{
+		Deleted: make([]string, len(parsed.Deleted)),
+		Errors:  parsed.Error,
+	}
+
+	for i, d := range parsed.Deleted {
+		result.Deleted[i] = d.Key
+	}
+
+	return result, nil
+}
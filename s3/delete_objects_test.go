@@ -0,0 +1,232 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"testing"
+	"time"
+)
+
+func TestDeleteObjects(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type DeleteObjectsTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&DeleteObjectsTest{}) }
+
+// Set up the signer and conn to return the supplied response.
+func (t *DeleteObjectsTest) respondWith(resp *http.Response) {
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *DeleteObjectsTest) NoKeys() {
+	// Call
+	_, err := t.bucket.DeleteObjects([]string{}, false)
+
+	ExpectThat(err, ErrorThat(HasSubstr("At least one")))
+}
+
+func (t *DeleteObjectsTest) TooManyKeys() {
+	keys := make([]string, 1001)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%d", i)
+	}
+
+	// Call
+	_, err := t.bucket.DeleteObjects(keys, false)
+
+	ExpectThat(err, ErrorThat(HasSubstr("1000")))
+	ExpectThat(err, ErrorThat(HasSubstr("1001")))
+}
+
+func (t *DeleteObjectsTest) InvalidKey() {
+	// Call
+	_, err := t.bucket.DeleteObjects([]string{"a", "taco\x00burrito"}, false)
+
+	ExpectThat(err, ErrorThat(HasSubstr("U+0000")))
+}
+
+func (t *DeleteObjectsTest) CallsSigner() {
+	keys := []string{"taco", "burrito & enchilada"}
+
+	// Clock
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+
+	// Signer
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	// Call
+	t.bucket.DeleteObjects(keys, true)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("POST", httpReq.Verb)
+	ExpectEq("/some.bucket", httpReq.Path)
+	ExpectThat(httpReq.Parameters, DeepEquals(map[string]string{"delete": ""}))
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", httpReq.Headers["Date"])
+	ExpectEq(computeBase64Md5(httpReq.Body), httpReq.Headers["Content-MD5"])
+
+	ExpectEq(
+		"<Delete>"+
+			"<Quiet>true</Quiet>"+
+			"<Object><Key>taco</Key></Object>"+
+			"<Object><Key>burrito &amp; enchilada</Key></Object>"+
+			"</Delete>",
+		string(httpReq.Body))
+}
+
+func (t *DeleteObjectsTest) SignerReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(errors.New("taco")))
+
+	// Call
+	_, err := t.bucket.DeleteObjects([]string{"a"}, false)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *DeleteObjectsTest) ConnReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	_, err := t.bucket.DeleteObjects([]string{"a"}, false)
+
+	ExpectThat(err, ErrorThat(HasSubstr("SendRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *DeleteObjectsTest) ServerReturnsError() {
+	t.respondWith(&http.Response{
+		StatusCode: 400,
+		Body:       []byte("taco"),
+	})
+
+	// Call
+	_, err := t.bucket.DeleteObjects([]string{"a"}, false)
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("400")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *DeleteObjectsTest) ServerReturnsErrorAfterOkStatus() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<Error>
+				<Code>InternalError</Code>
+				<Message>We encountered an internal error.</Message>
+			</Error>`),
+	})
+
+	// Call
+	_, err := t.bucket.DeleteObjects([]string{"a"}, false)
+
+	ExpectThat(err, ErrorThat(HasSubstr("InternalError")))
+	ExpectThat(err, ErrorThat(HasSubstr("internal error")))
+}
+
+func (t *DeleteObjectsTest) ResponseBodyIsJunk() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body:       []byte("taco"),
+	})
+
+	// Call
+	_, err := t.bucket.DeleteObjects([]string{"a"}, false)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Invalid")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *DeleteObjectsTest) ReturnsResults() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<DeleteResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+				<Deleted>
+					<Key>a</Key>
+				</Deleted>
+				<Error>
+					<Key>b</Key>
+					<Code>AccessDenied</Code>
+					<Message>Access Denied</Message>
+				</Error>
+				<Deleted>
+					<Key>c</Key>
+				</Deleted>
+			</DeleteResult>`),
+	})
+
+	// Call
+	result, err := t.bucket.DeleteObjects([]string{"a", "b", "c"}, false)
+	AssertEq(nil, err)
+
+	ExpectThat(result.Deleted, ElementsAre("a", "c"))
+	AssertEq(1, len(result.Errors))
+	ExpectEq("b", result.Errors[0].Key)
+	ExpectEq("AccessDenied", result.Errors[0].Code)
+	ExpectEq("Access Denied", result.Errors[0].Message)
+}
+
+func (t *DeleteObjectsTest) QuietResponse() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<DeleteResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+			</DeleteResult>`),
+	})
+
+	// Call
+	result, err := t.bucket.DeleteObjects([]string{"a", "b"}, true)
+	AssertEq(nil, err)
+
+	ExpectThat(result.Deleted, ElementsAre())
+	ExpectThat(result.Errors, ElementsAre())
+}
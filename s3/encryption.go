@@ -0,0 +1,94 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/aws/s3/internal/validate"
+)
+
+// A kind of server-side encryption with keys managed by AWS, with which S3
+// may encrypt an object at rest.
+//
+// Reference:
+//     http://docs.aws.amazon.com/AmazonS3/latest/dev/serv-side-encryption.html
+type ServerSideEncryption string
+
+const (
+	// Encrypt with AES-256 using keys managed by S3 (SSE-S3).
+	SseS3 ServerSideEncryption = "AES256"
+
+	// Encrypt using a key held by the AWS Key Management Service (SSE-KMS).
+	SseKms ServerSideEncryption = "aws:kms"
+)
+
+// The length in bytes of the keys used for encryption with customer-provided
+// keys (SSE-C), which is AES-256.
+const CustomerKeyLength = validate.CustomerKeyLength
+
+// Request headers for server-side encryption.
+const (
+	sseHeader               = "x-amz-server-side-encryption"
+	sseKmsKeyIdHeader       = "x-amz-server-side-encryption-aws-kms-key-id"
+	sseCustomerAlgHeader    = "x-amz-server-side-encryption-customer-algorithm"
+	sseCustomerKeyHeader    = "x-amz-server-side-encryption-customer-key"
+	sseCustomerKeyMd5Header = "x-amz-server-side-encryption-customer-key-MD5"
+)
+
+// Add headers requesting that the object be encrypted in the manner called
+// for by the supplied options, which are as documented for StoreOptions.
+func addEncryptionHeaders(
+	r *http.Request,
+	sse ServerSideEncryption,
+	kmsKeyId string,
+	customerKey []byte) error {
+	if err := validate.Encryption(string(sse), kmsKeyId, customerKey); err != nil {
+		return err
+	}
+
+	if sse != "" {
+		r.Headers[sseHeader] = string(sse)
+	}
+
+	if kmsKeyId != "" {
+		r.Headers[sseKmsKeyIdHeader] = kmsKeyId
+	}
+
+	return addCustomerKeyHeaders(r.Headers, customerKey)
+}
+
+// Add the headers that supply a customer-provided encryption key with a
+// request, if the key is non-nil. S3 needs these both to store an object
+// encrypted with the key and to read it back.
+func addCustomerKeyHeaders(headers map[string]string, key []byte) error {
+	if key == nil {
+		return nil
+	}
+
+	if err := validate.CustomerKey(key); err != nil {
+		return err
+	}
+
+	sum := md5.Sum(key)
+
+	headers[sseCustomerAlgHeader] = "AES256"
+	headers[sseCustomerKeyHeader] = base64.StdEncoding.EncodeToString(key)
+	headers[sseCustomerKeyMd5Header] = base64.StdEncoding.EncodeToString(sum[:])
+
+	return nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"github.com/jacobsa/aws"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"io/ioutil"
+	sys_http "net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestEndpoint(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// OpenBucketAtEndpoint
+////////////////////////////////////////////////////////////////////////
+
+type OpenBucketAtEndpointTest struct {
+	// The requests received by the server, and the bodies of their requests.
+	reqs   []*sys_http.Request
+	bodies []string
+
+	server *httptest.Server
+}
+
+func init() { RegisterTestSuite(&OpenBucketAtEndpointTest{}) }
+
+func (t *OpenBucketAtEndpointTest) SetUp(i *TestInfo) {
+	handler := func(w sys_http.ResponseWriter, r *sys_http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		t.reqs = append(t.reqs, r)
+		t.bodies = append(t.bodies, string(body))
+		w.Header().Set("ETag", `"foo"`)
+	}
+
+	t.server = httptest.NewServer(sys_http.HandlerFunc(handler))
+}
+
+func (t *OpenBucketAtEndpointTest) TearDown() {
+	t.server.Close()
+}
+
+func (t *OpenBucketAtEndpointTest) endpoint(path string) *url.URL {
+	u, err := url.Parse(t.server.URL + path)
+	AssertEq(nil, err)
+	return u
+}
+
+func (t *OpenBucketAtEndpointTest) NoHost() {
+	_, err := OpenBucketAtEndpoint(
+		"some-bucket",
+		&url.URL{Scheme: "http", Path: "/foo"},
+		PathStyle,
+		aws.AccessKey{})
+
+	ExpectThat(err, ErrorThat(HasSubstr("no host")))
+}
+
+func (t *OpenBucketAtEndpointTest) HasQuery() {
+	_, err := OpenBucketAtEndpoint(
+		"some-bucket",
+		&url.URL{Scheme: "http", Host: "localhost:9000", RawQuery: "a=b"},
+		PathStyle,
+		aws.AccessKey{})
+
+	ExpectThat(err, ErrorThat(HasSubstr("query")))
+}
+
+func (t *OpenBucketAtEndpointTest) UnsupportedScheme() {
+	_, err := OpenBucketAtEndpoint(
+		"some-bucket",
+		&url.URL{Scheme: "ftp", Host: "localhost:9000"},
+		PathStyle,
+		aws.AccessKey{})
+
+	ExpectThat(err, ErrorThat(HasSubstr("scheme")))
+	ExpectThat(err, ErrorThat(HasSubstr("ftp")))
+}
+
+func (t *OpenBucketAtEndpointTest) StyleOverridesOption() {
+	b, err := OpenBucketAtEndpoint(
+		"some-bucket",
+		&url.URL{Scheme: "http", Host: "localhost:9000"},
+		PathStyle,
+		aws.AccessKey{},
+		WithAddressingStyle(VirtualHostedStyle))
+
+	AssertEq(nil, err)
+	ExpectFalse(b.(*bucket).virtualHosted)
+}
+
+func (t *OpenBucketAtEndpointTest) SendsRequestsToEndpoint() {
+	b, err := OpenBucketAtEndpoint(
+		"some-bucket",
+		t.endpoint(""),
+		PathStyle,
+		aws.AccessKey{Id: "foo", Secret: "bar"},
+		WithSignatureV4())
+
+	AssertEq(nil, err)
+
+	// Call
+	err = b.StoreObject("a/b", []byte("taco"))
+	AssertEq(nil, err)
+
+	AssertEq(1, len(t.reqs))
+	ExpectEq("PUT", t.reqs[0].Method)
+	ExpectEq("/some-bucket/a/b", t.reqs[0].URL.Path)
+	ExpectEq("taco", t.bodies[0])
+	ExpectThat(
+		t.reqs[0].Header.Get("Authorization"),
+		HasSubstr("/us-east-1/s3/aws4_request"))
+}
+
+func (t *OpenBucketAtEndpointTest) PathPrefix() {
+	b, err := OpenBucketAtEndpoint(
+		"some-bucket",
+		t.endpoint("/s3/"),
+		PathStyle,
+		aws.AccessKey{Id: "foo", Secret: "bar"},
+		WithSignatureV4(),
+		WithSigningRegion("taco"))
+
+	AssertEq(nil, err)
+
+	// Call
+	err = b.StoreObject("a/b", []byte("taco"))
+	AssertEq(nil, err)
+
+	AssertEq(1, len(t.reqs))
+	ExpectEq("/s3/some-bucket/a/b", t.reqs[0].URL.Path)
+	ExpectThat(
+		t.reqs[0].Header.Get("Authorization"),
+		HasSubstr("/taco/s3/aws4_request"))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Path prefixes
+////////////////////////////////////////////////////////////////////////
+
+type PathPrefixTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&PathPrefixTest{}) }
+
+func (t *PathPrefixTest) open(virtualHosted bool) {
+	var err error
+	t.bucket, err = openBucket(
+		"some-bucket",
+		&url.URL{Scheme: "http", Host: "localhost:9000", Path: "/s3/"},
+		virtualHosted,
+		t.httpConn,
+		t.signer,
+		t.presigner,
+		t.v4Presigner,
+		t.clock)
+
+	AssertEq(nil, err)
+}
+
+// Call the supplied function, returning the request passed to the signer.
+func (t *PathPrefixTest) captureRequest(f func()) *http.Request {
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	f()
+	return httpReq
+}
+
+func (t *PathPrefixTest) PathStyle() {
+	t.open(false)
+
+	objectReq := t.captureRequest(func() { t.bucket.GetObject("a") })
+	bucketReq := t.captureRequest(func() { t.bucket.ListObjects(ListRequest{}) })
+
+	AssertNe(nil, objectReq)
+	AssertNe(nil, bucketReq)
+	ExpectEq("/s3/some-bucket/a", objectReq.Path)
+	ExpectEq("/s3/some-bucket", bucketReq.Path)
+}
+
+func (t *PathPrefixTest) VirtualHostedStyle() {
+	t.open(true)
+
+	objectReq := t.captureRequest(func() { t.bucket.GetObject("a") })
+	bucketReq := t.captureRequest(func() { t.bucket.ListObjects(ListRequest{}) })
+
+	AssertNe(nil, objectReq)
+	AssertNe(nil, bucketReq)
+	ExpectEq("/s3/a", objectReq.Path)
+	ExpectEq("/s3/", bucketReq.Path)
+}
+
+func (t *PathPrefixTest) PresignedUrl() {
+	t.open(false)
+
+	// Presigner
+	ExpectCall(t.presigner, "Presign")(Any(), Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Call
+	u, err := t.bucket.PresignUrl("a", &PresignOptions{Expiry: time.Hour})
+	AssertEq(nil, err)
+
+	ExpectEq("http://localhost:9000/s3/some-bucket/a", u)
+}
@@ -0,0 +1,169 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+)
+
+// Error is returned when S3 responds to a request with an error status, or
+// with an <Error> document in place of the expected result.
+//
+// Reference:
+//     http://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html
+type Error struct {
+	// The HTTP status code of the response.
+	StatusCode int
+
+	// The S3 error code, e.g. "NoSuchKey". Empty if the response body could not
+	// be parsed, as is always the case for HEAD requests.
+	Code string
+
+	// A human-readable description of the error. If the response body could not
+	// be parsed, this is the body itself.
+	Message string
+
+	// The key to which the error pertains, if the server said.
+	Key string
+
+	// Identifiers for the request, useful when asking Amazon for help.
+	RequestId string
+	HostId    string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("Error from server: %d %s", e.StatusCode, e.Message)
+	}
+
+	msg := fmt.Sprintf(
+		"Error from server: %d %s: %s",
+		e.StatusCode,
+		e.Code,
+		e.Message)
+
+	if e.Key != "" {
+		msg += fmt.Sprintf(" (key: %s)", e.Key)
+	}
+
+	return msg
+}
+
+type errorResponse struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string
+	Message   string
+	Key       string
+	RequestId string
+	HostId    string
+}
+
+// Create an *Error for a response with the given status code and body.
+func newError(statusCode int, body []byte) *Error {
+	parsed := errorResponse{}
+	if err := xml.Unmarshal(body, &parsed); err != nil || parsed.Code == "" {
+		return &Error{StatusCode: statusCode, Message: string(body)}
+	}
+
+	return &Error{
+		StatusCode: statusCode,
+		Code:       parsed.Code,
+		Message:    parsed.Message,
+		Key:        parsed.Key,
+		RequestId:  parsed.RequestId,
+		HostId:     parsed.HostId,
+	}
+}
+
+// IsNotFound returns true if the supplied error, or any error it wraps,
+// indicates that the bucket, object, or upload named by a request doesn't
+// exist.
+func IsNotFound(err error) bool {
+	var s3Err *Error
+	if !errors.As(err, &s3Err) {
+		return false
+	}
+
+	switch s3Err.Code {
+	case "NoSuchBucket", "NoSuchKey", "NoSuchUpload", "NoSuchVersion":
+		return true
+
+	case "":
+		return s3Err.StatusCode == 404
+	}
+
+	return false
+}
+
+// IsAccessDenied returns true if the supplied error, or any error it wraps,
+// indicates that the access key isn't permitted to make a request.
+func IsAccessDenied(err error) bool {
+	var s3Err *Error
+	if !errors.As(err, &s3Err) {
+		return false
+	}
+
+	switch s3Err.Code {
+	case "AccessDenied":
+		return true
+
+	case "":
+		return s3Err.StatusCode == 403
+	}
+
+	return false
+}
+
+// RangeNotSatisfiableError is returned by Bucket.GetObjectRange when the
+// requested range starts beyond the end of the object (HTTP 416).
+type RangeNotSatisfiableError struct {
+	// The range that was requested, in the form of an HTTP Range header.
+	Range string
+
+	// The size of the object, or -1 if the server didn't say.
+	ObjectSize int64
+}
+
+func (e *RangeNotSatisfiableError) Error() string {
+	return fmt.Sprintf(
+		"Range not satisfiable: %s (object size %d)",
+		e.Range,
+		e.ObjectSize)
+}
+
+// NotModifiedError is returned by a conditional read when the object has not
+// been modified according to the conditions supplied (HTTP 304).
+type NotModifiedError struct {
+	// The key that was requested.
+	Key string
+}
+
+func (e *NotModifiedError) Error() string {
+	return fmt.Sprintf("Object not modified: %s", e.Key)
+}
+
+// PreconditionFailedError is returned by a conditional read or write when the
+// object doesn't satisfy the conditions supplied (HTTP 412).
+type PreconditionFailedError struct {
+	// The key that was requested.
+	Key string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return fmt.Sprintf("Precondition failed for object: %s", e.Key)
+}
@@ -0,0 +1,177 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"testing"
+)
+
+func TestErrors(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type ErrorTest struct {
+}
+
+func init() { RegisterTestSuite(&ErrorTest{}) }
+
+const noSuchKeyBody = `
+<?xml version="1.0" encoding="UTF-8"?>
+<Error>
+  <Code>NoSuchKey</Code>
+  <Message>The resource you requested does not exist</Message>
+  <Key>myfoto.jpg</Key>
+  <RequestId>4442587FB7D0A2F9</RequestId>
+  <HostId>taco</HostId>
+</Error>`
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *ErrorTest) ParsesBody() {
+	e := newError(404, []byte(noSuchKeyBody))
+
+	ExpectEq(404, e.StatusCode)
+	ExpectEq("NoSuchKey", e.Code)
+	ExpectEq("The resource you requested does not exist", e.Message)
+	ExpectEq("myfoto.jpg", e.Key)
+	ExpectEq("4442587FB7D0A2F9", e.RequestId)
+	ExpectEq("taco", e.HostId)
+
+	ExpectEq(
+		"Error from server: 404 NoSuchKey: "+
+			"The resource you requested does not exist (key: myfoto.jpg)",
+		e.Error())
+}
+
+func (t *ErrorTest) UnparseableBody() {
+	e := newError(500, []byte("taco"))
+
+	ExpectEq(500, e.StatusCode)
+	ExpectEq("", e.Code)
+	ExpectEq("taco", e.Message)
+	ExpectEq("Error from server: 500 taco", e.Error())
+}
+
+func (t *ErrorTest) WrongRootElement() {
+	e := newError(500, []byte("<Taco><Code>Burrito</Code></Taco>"))
+
+	ExpectEq("", e.Code)
+	ExpectEq("<Taco><Code>Burrito</Code></Taco>", e.Message)
+}
+
+func (t *ErrorTest) IsNotFound() {
+	ExpectFalse(IsNotFound(nil))
+	ExpectFalse(IsNotFound(errors.New("NoSuchKey")))
+	ExpectFalse(IsNotFound(&Error{StatusCode: 403, Code: "AccessDenied"}))
+	ExpectFalse(IsNotFound(&Error{StatusCode: 500}))
+
+	ExpectTrue(IsNotFound(&Error{StatusCode: 404}))
+	ExpectTrue(IsNotFound(&Error{StatusCode: 404, Code: "NoSuchBucket"}))
+	ExpectTrue(IsNotFound(newError(404, []byte(noSuchKeyBody))))
+}
+
+func (t *ErrorTest) IsAccessDenied() {
+	ExpectFalse(IsAccessDenied(nil))
+	ExpectFalse(IsAccessDenied(errors.New("AccessDenied")))
+	ExpectFalse(IsAccessDenied(&Error{StatusCode: 403, Code: "SignatureDoesNotMatch"}))
+	ExpectFalse(IsAccessDenied(&Error{StatusCode: 404}))
+
+	ExpectTrue(IsAccessDenied(&Error{StatusCode: 403}))
+	ExpectTrue(IsAccessDenied(&Error{StatusCode: 403, Code: "AccessDenied"}))
+}
+
+func (t *ErrorTest) PredicatesSeeThroughWrapping() {
+	notFound := fmt.Errorf("ListObjects: %w", &Error{StatusCode: 404})
+	denied := fmt.Errorf("foo: %w", fmt.Errorf("bar: %w", &Error{StatusCode: 403}))
+
+	ExpectTrue(IsNotFound(notFound))
+	ExpectFalse(IsAccessDenied(notFound))
+
+	ExpectTrue(IsAccessDenied(denied))
+	ExpectFalse(IsNotFound(denied))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Errors returned by the bucket
+////////////////////////////////////////////////////////////////////////
+
+type ServerErrorTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&ServerErrorTest{}) }
+
+func (t *ServerErrorTest) ReturnsStructuredError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.StreamingResponse{
+		StatusCode: 404,
+		Body:       newFakeBody(noSuchKeyBody),
+	}
+
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	_, _, err := t.bucket.GetObjectWithOptions("a", nil)
+
+	AssertNe(nil, err)
+	ExpectTrue(IsNotFound(err))
+}
+
+func (t *ServerErrorTest) ErrorDocumentWithOkStatus() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<Error>
+				<Code>InternalError</Code>
+				<Message>We encountered an internal error.</Message>
+				<RequestId>taco</RequestId>
+			</Error>`),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	_, err := t.bucket.CopyObject("a", "b", nil)
+
+	AssertNe(nil, err)
+	s3Err, ok := err.(*Error)
+	AssertTrue(ok, "%v", err)
+
+	ExpectEq(200, s3Err.StatusCode)
+	ExpectEq("InternalError", s3Err.Code)
+	ExpectEq("We encountered an internal error.", s3Err.Message)
+	ExpectEq("taco", s3Err.RequestId)
+}
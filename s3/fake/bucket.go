@@ -0,0 +1,1318 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fake contains an in-memory implementation of s3.Bucket, for use in
+// tests of code that talks to S3.
+package fake
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"github.com/jacobsa/aws/s3"
+	"github.com/jacobsa/aws/s3/internal/validate"
+	"github.com/jacobsa/aws/time"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	sys_time "time"
+)
+
+// The maximum number of keys returned by a single call to ListKeys or
+// ListObjects, as with S3.
+const maxListKeys = 1000
+
+// The minimum size of every part of a multipart upload except the last, as
+// with S3.
+const minPartSize = 5 << 20
+
+// NewBucket returns an empty in-memory bucket with the given name. It enforces
+// the same constraints on keys as S3 and reports errors in the same manner as
+// the bucket returned by s3.OpenBucket, e.g. returning an error for which
+// s3.IsNotFound is true when reading a key that doesn't exist. It is safe for
+// concurrent use.
+func NewBucket(name string) s3.Bucket {
+	return newBucket(name, time.RealClock())
+}
+
+func newBucket(name string, clock time.Clock) s3.Bucket {
+	return &bucket{
+		name: name,
+		ctx:  context.Background(),
+		state: &state{
+			clock:    clock,
+			objects:  map[string]*object{},
+			versions: map[string][]*object{},
+			uploads:  map[string]*upload{},
+		},
+	}
+}
+
+////////////////////////////////////////////////////////////////////////
+// State
+////////////////////////////////////////////////////////////////////////
+
+// A version of an object stored in the bucket, or a delete marker. Objects are
+// never modified once stored, so they may be read without holding the state's
+// lock.
+type object struct {
+	// Empty for the version S3 calls "null" if versioning has never been
+	// enabled.
+	versionId    string
+	deleteMarker bool
+
+	data            []byte
+	etag            string
+	lastModified    sys_time.Time
+	contentType     string
+	contentEncoding string
+	cacheControl    string
+
+	// Names are lower case.
+	metadata map[string]string
+
+	// The server-side encryption requested when the object was stored. For
+	// customer-provided keys, only the MD5 sum of the key is kept, as with S3.
+	sse            s3.ServerSideEncryption
+	customerKeyMd5 []byte
+}
+
+type part struct {
+	data []byte
+	etag string
+}
+
+type upload struct {
+	key   string
+	parts map[int]part
+}
+
+// State shared by a bucket and all of the views returned by its WithContext
+// method.
+type state struct {
+	clock time.Clock
+
+	mu sync.Mutex
+
+	// The latest version of each object, omitting keys whose latest version is
+	// a delete marker.
+	//
+	// GUARDED_BY(mu)
+	objects map[string]*object
+
+	// All versions of each key, newest first, including delete markers.
+	//
+	// INVARIANT: For each key, objects[key] is versions[key][0] if that is not
+	// a delete marker.
+	//
+	// GUARDED_BY(mu)
+	versions map[string][]*object
+
+	// GUARDED_BY(mu)
+	versioning s3.VersioningStatus
+
+	// GUARDED_BY(mu)
+	nextVersionId uint64
+
+	// GUARDED_BY(mu)
+	uploads map[string]*upload
+
+	// GUARDED_BY(mu)
+	nextUploadId uint64
+}
+
+type bucket struct {
+	name  string
+	ctx   context.Context
+	state *state
+}
+
+func (b *bucket) WithContext(ctx context.Context) s3.Bucket {
+	return &bucket{
+		name:  b.name,
+		ctx:   ctx,
+		state: b.state,
+	}
+}
+
+// Return the object with the given key, or nil if there is none.
+func (b *bucket) lookUp(key string) *object {
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	return b.state.objects[key]
+}
+
+// Return the version of the object with the given key that has the given ID.
+// As with S3, asking for a delete marker is an error.
+func (b *bucket) lookUpVersion(key string, versionId string) (*object, error) {
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	for _, o := range b.state.versions[key] {
+		if externalVersionId(o) != versionId {
+			continue
+		}
+
+		if o.deleteMarker {
+			return nil, &s3.Error{
+				StatusCode: 405,
+				Code:       "MethodNotAllowed",
+				Message:    "The specified method is not allowed against this resource.",
+				Key:        key,
+			}
+		}
+
+		return o, nil
+	}
+
+	return nil, noSuchVersion(key, versionId)
+}
+
+// Return the entity tag S3 would assign to an object with the given contents.
+func computeETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// Read all of the data from r, which must amount to size bytes if size is
+// non-negative.
+func readAll(r io.Reader, size int64) ([]byte, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ReadAll: %v", err)
+	}
+
+	if size >= 0 && int64(len(data)) != size {
+		return nil, fmt.Errorf("Expected %d bytes of data, but read %d.", size, len(data))
+	}
+
+	return data, nil
+}
+
+// Check the supplied user-defined metadata in the same way as the real bucket
+// does before sending it, returning a copy with lower-cased names.
+func normalizeMetadata(metadata map[string]string) (map[string]string, error) {
+	if err := validate.Metadata(metadata); err != nil {
+		return nil, err
+	}
+
+	result := map[string]string{}
+	for name, val := range metadata {
+		result[strings.ToLower(name)] = val
+	}
+
+	return result, nil
+}
+
+func noSuchKey(key string) error {
+	return &s3.Error{
+		StatusCode: 404,
+		Code:       "NoSuchKey",
+		Message:    "The specified key does not exist.",
+		Key:        key,
+	}
+}
+
+func noSuchUpload(uploadId string) error {
+	return &s3.Error{
+		StatusCode: 404,
+		Code:       "NoSuchUpload",
+		Message:    fmt.Sprintf("The specified upload does not exist: %s", uploadId),
+	}
+}
+
+func noSuchVersion(key string, versionId string) error {
+	return &s3.Error{
+		StatusCode: 404,
+		Code:       "NoSuchVersion",
+		Message:    fmt.Sprintf("The specified version does not exist: %s", versionId),
+		Key:        key,
+	}
+}
+
+// Return the ID by which the supplied version is known in listings.
+func externalVersionId(o *object) string {
+	if o.versionId == "" {
+		return "null"
+	}
+
+	return o.versionId
+}
+
+func makeObjectInfo(key string, o *object) *s3.ObjectInfo {
+	info := &s3.ObjectInfo{
+		Key:                  key,
+		Size:                 int64(len(o.data)),
+		ETag:                 o.etag,
+		VersionId:            o.versionId,
+		LastModified:         o.lastModified,
+		ContentType:          o.contentType,
+		ServerSideEncryption: o.sse,
+		ContentEncoding:      o.contentEncoding,
+		CacheControl:         o.cacheControl,
+		Metadata:             map[string]string{},
+	}
+
+	for name, val := range o.metadata {
+		info.Metadata[name] = val
+	}
+
+	return info
+}
+
+// Return the MD5 sum of the supplied customer-provided key, or nil if there
+// is no key.
+func customerKeyMd5(key []byte) []byte {
+	if key == nil {
+		return nil
+	}
+
+	sum := md5.Sum(key)
+	return sum[:]
+}
+
+// Return the error S3 gives when reading the supplied object with the
+// supplied customer-provided key (nil if none), if any.
+func checkCustomerKey(key string, o *object, customerKey []byte) error {
+	switch {
+	case o.customerKeyMd5 == nil && customerKey == nil:
+		return nil
+
+	case o.customerKeyMd5 == nil:
+		return &s3.Error{
+			StatusCode: 400,
+			Code:       "InvalidRequest",
+			Message:    "The encryption parameters are not applicable to this object.",
+			Key:        key,
+		}
+
+	case customerKey == nil:
+		return &s3.Error{
+			StatusCode: 400,
+			Code:       "InvalidRequest",
+			Message: "The object was stored using a form of Server Side Encryption. " +
+				"The correct parameters must be provided to retrieve the object.",
+			Key: key,
+		}
+
+	case !bytes.Equal(customerKeyMd5(customerKey), o.customerKeyMd5):
+		return &s3.Error{
+			StatusCode: 403,
+			Code:       "AccessDenied",
+			Message:    "Access Denied",
+			Key:        key,
+		}
+	}
+
+	return nil
+}
+
+// A reader that fails once the context it is bound to is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(p)
+}
+
+func (r *contextReader) Close() error {
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// Reading
+////////////////////////////////////////////////////////////////////////
+
+func (b *bucket) GetObject(key string) (data []byte, err error) {
+	r, _, err := b.GetObjectReader(key)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (b *bucket) GetObjectReader(key string) (r io.ReadCloser, size int64, err error) {
+	r, info, err := b.GetObjectWithOptions(key, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r, info.Size, nil
+}
+
+func (b *bucket) GetObjectWithOptions(
+	key string,
+	opts *s3.GetOptions) (r io.ReadCloser, info *s3.ObjectInfo, err error) {
+	if err := s3.ValidateKey(key); err != nil {
+		return nil, nil, err
+	}
+
+	if opts == nil {
+		opts = &s3.GetOptions{}
+	}
+
+	if err := validate.CustomerKey(opts.CustomerKey); err != nil {
+		return nil, nil, err
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var o *object
+	if opts.VersionId != "" {
+		if o, err = b.lookUpVersion(key, opts.VersionId); err != nil {
+			return nil, nil, err
+		}
+	} else if o = b.lookUp(key); o == nil {
+		return nil, nil, noSuchKey(key)
+	}
+
+	if err := checkCustomerKey(key, o, opts.CustomerKey); err != nil {
+		return nil, nil, err
+	}
+
+	// Check the conditions in the order given by RFC 7232: a satisfied If-Match
+	// overrides If-Unmodified-Since, and the presence of If-None-Match means
+	// that If-Modified-Since is ignored.
+	if opts.IfMatch != "" {
+		if opts.IfMatch != "*" && opts.IfMatch != o.etag {
+			return nil, nil, &s3.PreconditionFailedError{Key: key}
+		}
+	} else if !opts.IfUnmodifiedSince.IsZero() && o.lastModified.After(opts.IfUnmodifiedSince) {
+		return nil, nil, &s3.PreconditionFailedError{Key: key}
+	}
+
+	if opts.IfNoneMatch != "" {
+		if opts.IfNoneMatch == "*" || opts.IfNoneMatch == o.etag {
+			return nil, nil, &s3.NotModifiedError{Key: key}
+		}
+	} else if !opts.IfModifiedSince.IsZero() && !o.lastModified.After(opts.IfModifiedSince) {
+		return nil, nil, &s3.NotModifiedError{Key: key}
+	}
+
+	r = &contextReader{b.ctx, bytes.NewReader(o.data)}
+	return r, makeObjectInfo(key, o), nil
+}
+
+func (b *bucket) GetObjectRange(
+	key string,
+	offset int64,
+	length int64) (data []byte, objectSize int64, err error) {
+	return b.GetObjectRangeWithOptions(key, offset, length, nil)
+}
+
+func (b *bucket) GetObjectRangeWithOptions(
+	key string,
+	offset int64,
+	length int64,
+	opts *s3.RangeOptions) (data []byte, objectSize int64, err error) {
+	if length == 0 && offset >= 0 {
+		return nil, 0, fmt.Errorf("Length must be non-zero.")
+	}
+
+	if err := s3.ValidateKey(key); err != nil {
+		return nil, 0, err
+	}
+
+	if opts == nil {
+		opts = &s3.RangeOptions{}
+	}
+
+	if err := validate.CustomerKey(opts.CustomerKey); err != nil {
+		return nil, 0, err
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	o := b.lookUp(key)
+	if o == nil {
+		return nil, 0, noSuchKey(key)
+	}
+
+	if err := checkCustomerKey(key, o, opts.CustomerKey); err != nil {
+		return nil, 0, err
+	}
+
+	size := int64(len(o.data))
+
+	// A suffix range covers the whole object if the object is too short.
+	if offset < 0 {
+		start := size + offset
+		if start < 0 {
+			start = 0
+		}
+
+		return append([]byte{}, o.data[start:]...), size, nil
+	}
+
+	if offset >= size {
+		rangeHeader := fmt.Sprintf("bytes=%d-", offset)
+		if length > 0 {
+			rangeHeader += strconv.FormatInt(offset+length-1, 10)
+		}
+
+		return nil, 0, &s3.RangeNotSatisfiableError{Range: rangeHeader, ObjectSize: size}
+	}
+
+	end := size
+	if length > 0 && offset+length < size {
+		end = offset + length
+	}
+
+	return append([]byte{}, o.data[offset:end]...), size, nil
+}
+
+func (b *bucket) StatObject(key string) (info *s3.ObjectInfo, err error) {
+	return b.StatObjectWithOptions(key, nil)
+}
+
+func (b *bucket) StatObjectWithOptions(
+	key string,
+	opts *s3.StatOptions) (info *s3.ObjectInfo, err error) {
+	if err := s3.ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &s3.StatOptions{}
+	}
+
+	if err := validate.CustomerKey(opts.CustomerKey); err != nil {
+		return nil, err
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	o := b.lookUp(key)
+	if o == nil {
+		return nil, noSuchKey(key)
+	}
+
+	if err := checkCustomerKey(key, o, opts.CustomerKey); err != nil {
+		return nil, err
+	}
+
+	return makeObjectInfo(key, o), nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// Writing
+////////////////////////////////////////////////////////////////////////
+
+func (b *bucket) StoreObject(key string, data []byte) error {
+	_, err := b.StoreObjectWithOptions(key, bytes.NewReader(data), int64(len(data)), nil)
+	return err
+}
+
+func (b *bucket) StoreObjectFromReader(key string, r io.Reader, size int64) error {
+	_, err := b.StoreObjectWithOptions(key, r, size, nil)
+	return err
+}
+
+func (b *bucket) StoreObjectWithOptions(
+	key string,
+	r io.Reader,
+	size int64,
+	opts *s3.StoreOptions) (result *s3.StoreResult, err error) {
+	if err := s3.ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &s3.StoreOptions{}
+	}
+
+	metadata, err := normalizeMetadata(opts.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validate.Encryption(
+		string(opts.ServerSideEncryption),
+		opts.KmsKeyId,
+		opts.CustomerKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readAll(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	o := &object{
+		data:            data,
+		etag:            computeETag(data),
+		contentType:     opts.ContentType,
+		contentEncoding: opts.ContentEncoding,
+		cacheControl:    opts.CacheControl,
+		metadata:        metadata,
+		sse:             opts.ServerSideEncryption,
+		customerKeyMd5:  customerKeyMd5(opts.CustomerKey),
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	// Check the preconditions against the current object, if any.
+	existing := b.state.objects[key]
+	if opts.IfMatch != "" {
+		if existing == nil || (opts.IfMatch != "*" && opts.IfMatch != existing.etag) {
+			return nil, &s3.PreconditionFailedError{Key: key}
+		}
+	}
+
+	if opts.IfNoneMatch != "" && existing != nil {
+		if opts.IfNoneMatch == "*" || opts.IfNoneMatch == existing.etag {
+			return nil, &s3.PreconditionFailedError{Key: key}
+		}
+	}
+
+	b.storeLocked(key, o)
+	return &s3.StoreResult{ETag: o.etag, VersionId: o.versionId}, nil
+}
+
+// Fill in defaults, the modification time, and the version ID for the supplied
+// object, then store it as the latest version of the given key. Unless
+// versioning is enabled, it replaces the null version.
+//
+// LOCKS_REQUIRED(b.state.mu)
+func (b *bucket) storeLocked(key string, o *object) {
+	if o.contentType == "" && !o.deleteMarker {
+		o.contentType = "binary/octet-stream"
+	}
+
+	// HTTP dates have a resolution of one second.
+	o.lastModified = b.state.clock.Now().UTC().Truncate(sys_time.Second)
+
+	switch b.state.versioning {
+	case s3.VersioningEnabled:
+		b.state.nextVersionId++
+		o.versionId = fmt.Sprintf("version-%d", b.state.nextVersionId)
+
+	case s3.VersioningSuspended:
+		o.versionId = "null"
+	}
+
+	versions := []*object{o}
+	for _, v := range b.state.versions[key] {
+		if externalVersionId(o) == "null" && externalVersionId(v) == "null" {
+			continue
+		}
+
+		versions = append(versions, v)
+	}
+
+	b.state.versions[key] = versions
+	b.updateLatestLocked(key)
+}
+
+// Bring the objects map up to date with the versions of the given key.
+//
+// LOCKS_REQUIRED(b.state.mu)
+func (b *bucket) updateLatestLocked(key string) {
+	versions := b.state.versions[key]
+	switch {
+	case len(versions) == 0:
+		delete(b.state.versions, key)
+		delete(b.state.objects, key)
+
+	case versions[0].deleteMarker:
+		delete(b.state.objects, key)
+
+	default:
+		b.state.objects[key] = versions[0]
+	}
+}
+
+func (b *bucket) CopyObject(
+	srcKey string,
+	dstKey string,
+	opts *s3.CopyOptions) (result *s3.CopyObjectResult, err error) {
+	if err := s3.ValidateKey(srcKey); err != nil {
+		return nil, fmt.Errorf("Invalid source key: %v", err)
+	}
+
+	if err := s3.ValidateKey(dstKey); err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &s3.CopyOptions{}
+	}
+
+	if opts.SourceBucket != "" && opts.SourceBucket != b.name {
+		return nil, fmt.Errorf("Copying from another bucket is not supported.")
+	}
+
+	directive := opts.MetadataDirective
+	switch directive {
+	case "", s3.MetadataCopy:
+		if opts.ContentType != "" ||
+			opts.ContentEncoding != "" ||
+			opts.CacheControl != "" ||
+			len(opts.Metadata) != 0 {
+			return nil, fmt.Errorf("Metadata may be set only with MetadataReplace.")
+		}
+
+	case s3.MetadataReplace:
+
+	default:
+		return nil, fmt.Errorf("Invalid metadata directive: %s", directive)
+	}
+
+	metadata, err := normalizeMetadata(opts.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	src := b.state.objects[srcKey]
+	if src == nil {
+		return nil, noSuchKey(srcKey)
+	}
+
+	if err := checkCustomerKey(srcKey, src, nil); err != nil {
+		return nil, err
+	}
+
+	o := &object{
+		data:            src.data,
+		etag:            src.etag,
+		contentType:     src.contentType,
+		contentEncoding: src.contentEncoding,
+		cacheControl:    src.cacheControl,
+		metadata:        src.metadata,
+	}
+
+	if directive == s3.MetadataReplace {
+		o.contentType = opts.ContentType
+		o.contentEncoding = opts.ContentEncoding
+		o.cacheControl = opts.CacheControl
+		o.metadata = metadata
+	}
+
+	b.storeLocked(dstKey, o)
+
+	result = &s3.CopyObjectResult{
+		ETag:         o.etag,
+		LastModified: o.lastModified,
+	}
+
+	return result, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// Deleting
+////////////////////////////////////////////////////////////////////////
+
+func (b *bucket) DeleteObject(key string) error {
+	if err := s3.ValidateKey(key); err != nil {
+		return err
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	b.deleteLocked(key)
+	return nil
+}
+
+// Delete the object with the given key, or if versioning has ever been
+// enabled, hide it behind a delete marker.
+//
+// LOCKS_REQUIRED(b.state.mu)
+func (b *bucket) deleteLocked(key string) {
+	if b.state.versioning == "" {
+		delete(b.state.versions, key)
+		b.updateLatestLocked(key)
+		return
+	}
+
+	b.storeLocked(key, &object{deleteMarker: true})
+}
+
+func (b *bucket) DeleteObjectVersion(key string, versionId string) error {
+	if err := s3.ValidateKey(key); err != nil {
+		return err
+	}
+
+	if versionId == "" {
+		return fmt.Errorf("A version ID is required.")
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	// As with S3, deleting a version that doesn't exist is not an error.
+	var versions []*object
+	for _, v := range b.state.versions[key] {
+		if externalVersionId(v) != versionId {
+			versions = append(versions, v)
+		}
+	}
+
+	b.state.versions[key] = versions
+	b.updateLatestLocked(key)
+
+	return nil
+}
+
+func (b *bucket) DeleteObjects(
+	keys []string,
+	quiet bool) (result *s3.DeleteObjectsResult, err error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("At least one key is required.")
+	}
+
+	if len(keys) > s3.MaxDeleteObjectsKeys {
+		return nil, fmt.Errorf(
+			"At most %d keys may be deleted at once; got %d.",
+			s3.MaxDeleteObjectsKeys,
+			len(keys))
+	}
+
+	for _, key := range keys {
+		if err := s3.ValidateKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	result = &s3.DeleteObjectsResult{Deleted: []string{}}
+	for _, key := range keys {
+		b.deleteLocked(key)
+		if !quiet {
+			result.Deleted = append(result.Deleted, key)
+		}
+	}
+
+	return result, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// Multipart uploads
+////////////////////////////////////////////////////////////////////////
+
+func (b *bucket) InitiateMultipartUpload(key string) (uploadId string, err error) {
+	if err := s3.ValidateKey(key); err != nil {
+		return "", err
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return "", err
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	b.state.nextUploadId++
+	uploadId = fmt.Sprintf("upload-%d", b.state.nextUploadId)
+	b.state.uploads[uploadId] = &upload{
+		key:   key,
+		parts: map[int]part{},
+	}
+
+	return uploadId, nil
+}
+
+// Return the upload with the given ID, which must be for the given key.
+//
+// LOCKS_REQUIRED(b.state.mu)
+func (b *bucket) findUploadLocked(key string, uploadId string) (*upload, error) {
+	u := b.state.uploads[uploadId]
+	if u == nil || u.key != key {
+		return nil, noSuchUpload(uploadId)
+	}
+
+	return u, nil
+}
+
+func (b *bucket) UploadPart(
+	key string,
+	uploadId string,
+	partNumber int,
+	r io.Reader,
+	size int64) (etag string, err error) {
+	if err := s3.ValidateKey(key); err != nil {
+		return "", err
+	}
+
+	if partNumber < 1 || partNumber > 10000 {
+		return "", fmt.Errorf("Invalid part number: %d", partNumber)
+	}
+
+	data, err := readAll(r, size)
+	if err != nil {
+		return "", err
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return "", err
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	u, err := b.findUploadLocked(key, uploadId)
+	if err != nil {
+		return "", err
+	}
+
+	p := part{data: data, etag: computeETag(data)}
+	u.parts[partNumber] = p
+
+	return p.etag, nil
+}
+
+func (b *bucket) CompleteMultipartUpload(
+	key string,
+	uploadId string,
+	parts []s3.CompletedPart) error {
+	if err := s3.ValidateKey(key); err != nil {
+		return err
+	}
+
+	if len(parts) == 0 {
+		return fmt.Errorf("At least one part is required.")
+	}
+
+	for i, p := range parts {
+		if i > 0 && p.PartNumber <= parts[i-1].PartNumber {
+			return fmt.Errorf("Parts must be in increasing order of part number.")
+		}
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	u, err := b.findUploadLocked(key, uploadId)
+	if err != nil {
+		return err
+	}
+
+	// Assemble the object. As with S3, its entity tag is the MD5 sum of the
+	// concatenated binary MD5 sums of the parts, followed by the part count.
+	var data []byte
+	sums := md5.New()
+	for i, cp := range parts {
+		p, ok := u.parts[cp.PartNumber]
+		if !ok || p.etag != cp.ETag {
+			return &s3.Error{
+				StatusCode: 400,
+				Code:       "InvalidPart",
+				Message:    fmt.Sprintf("Part %d could not be found.", cp.PartNumber),
+			}
+		}
+
+		if i < len(parts)-1 && len(p.data) < minPartSize {
+			return &s3.Error{
+				StatusCode: 400,
+				Code:       "EntityTooSmall",
+				Message:    fmt.Sprintf("Part %d is smaller than the minimum allowed size.", cp.PartNumber),
+			}
+		}
+
+		data = append(data, p.data...)
+
+		sum, _ := hex.DecodeString(strings.Trim(p.etag, `"`))
+		sums.Write(sum)
+	}
+
+	o := &object{
+		data:     data,
+		etag:     fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sums.Sum(nil)), len(parts)),
+		metadata: map[string]string{},
+	}
+
+	b.storeLocked(key, o)
+	delete(b.state.uploads, uploadId)
+
+	return nil
+}
+
+func (b *bucket) AbortMultipartUpload(key string, uploadId string) error {
+	if err := s3.ValidateKey(key); err != nil {
+		return err
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	if _, err := b.findUploadLocked(key, uploadId); err != nil {
+		return err
+	}
+
+	delete(b.state.uploads, uploadId)
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// Listing
+////////////////////////////////////////////////////////////////////////
+
+func (b *bucket) ListKeys(prevKey string) (keys []string, err error) {
+	result, err := b.ListObjects(s3.ListRequest{Marker: prevKey})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Keys, nil
+}
+
+func (b *bucket) ListObjects(req s3.ListRequest) (result *s3.ListResult, err error) {
+	// Check the request in the same way as the real bucket.
+	if err := s3.ValidateKey(req.Marker); err != nil && req.Marker != "" {
+		return nil, err
+	}
+
+	if err := s3.ValidateKey(req.Prefix); err != nil && req.Prefix != "" {
+		return nil, fmt.Errorf("Invalid prefix: %v", err)
+	}
+
+	if err := s3.ValidateKey(req.Delimiter); err != nil && req.Delimiter != "" {
+		return nil, fmt.Errorf("Invalid delimiter: %v", err)
+	}
+
+	if req.MaxKeys < 0 {
+		return nil, fmt.Errorf("Invalid max keys: %d", req.MaxKeys)
+	}
+
+	maxKeys := req.MaxKeys
+	if maxKeys == 0 || maxKeys > maxListKeys {
+		maxKeys = maxListKeys
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Find the matching keys, in order.
+	var keys []string
+
+	b.state.mu.Lock()
+	for key := range b.state.objects {
+		if key > req.Marker && strings.HasPrefix(key, req.Prefix) {
+			keys = append(keys, key)
+		}
+	}
+	b.state.mu.Unlock()
+
+	sort.Strings(keys)
+
+	// Roll up keys into common prefixes where called for, stopping once we have
+	// enough results.
+	result = &s3.ListResult{
+		Keys:           []string{},
+		CommonPrefixes: []string{},
+	}
+
+	count := 0
+	for _, key := range keys {
+		commonPrefix := ""
+		if req.Delimiter != "" {
+			rest := key[len(req.Prefix):]
+			if i := strings.Index(rest, req.Delimiter); i >= 0 {
+				commonPrefix = key[:len(req.Prefix)+i+len(req.Delimiter)]
+			}
+		}
+
+		// Skip keys belonging to a common prefix already returned, either by
+		// this call or by the one that yielded the marker.
+		if commonPrefix != "" {
+			n := len(result.CommonPrefixes)
+			if commonPrefix <= req.Marker || (n > 0 && result.CommonPrefixes[n-1] == commonPrefix) {
+				continue
+			}
+		}
+
+		if count == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+
+		count++
+		if commonPrefix != "" {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix)
+			result.NextMarker = commonPrefix
+		} else {
+			result.Keys = append(result.Keys, key)
+			result.NextMarker = key
+		}
+	}
+
+	if !result.IsTruncated {
+		result.NextMarker = ""
+	}
+
+	return result, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// Versioning
+////////////////////////////////////////////////////////////////////////
+
+func (b *bucket) SetVersioning(status s3.VersioningStatus) error {
+	switch status {
+	case s3.VersioningEnabled, s3.VersioningSuspended:
+	default:
+		return fmt.Errorf("Invalid versioning status: %q", status)
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	b.state.versioning = status
+	return nil
+}
+
+func (b *bucket) GetVersioning() (status s3.VersioningStatus, err error) {
+	if err := b.ctx.Err(); err != nil {
+		return "", err
+	}
+
+	b.state.mu.Lock()
+	defer b.state.mu.Unlock()
+
+	return b.state.versioning, nil
+}
+
+func (b *bucket) ListObjectVersions(
+	req s3.ListVersionsRequest) (result *s3.ListVersionsResult, err error) {
+	// Check the request in the same way as the real bucket.
+	if err := s3.ValidateKey(req.KeyMarker); err != nil && req.KeyMarker != "" {
+		return nil, err
+	}
+
+	if req.VersionIdMarker != "" && req.KeyMarker == "" {
+		return nil, fmt.Errorf("A version ID marker requires a key marker.")
+	}
+
+	if err := s3.ValidateKey(req.Prefix); err != nil && req.Prefix != "" {
+		return nil, fmt.Errorf("Invalid prefix: %v", err)
+	}
+
+	if err := s3.ValidateKey(req.Delimiter); err != nil && req.Delimiter != "" {
+		return nil, fmt.Errorf("Invalid delimiter: %v", err)
+	}
+
+	if req.MaxKeys < 0 {
+		return nil, fmt.Errorf("Invalid max keys: %d", req.MaxKeys)
+	}
+
+	maxKeys := req.MaxKeys
+	if maxKeys == 0 || maxKeys > maxListKeys {
+		maxKeys = maxListKeys
+	}
+
+	if err := b.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Find the matching keys, in order, along with their versions. The marker
+	// key itself is included only if we're to resume part way through its
+	// versions.
+	var keys []string
+	versions := map[string][]*object{}
+
+	b.state.mu.Lock()
+	for key, vs := range b.state.versions {
+		if !strings.HasPrefix(key, req.Prefix) {
+			continue
+		}
+
+		if key > req.KeyMarker || (key == req.KeyMarker && req.VersionIdMarker != "") {
+			keys = append(keys, key)
+			versions[key] = vs
+		}
+	}
+	b.state.mu.Unlock()
+
+	sort.Strings(keys)
+
+	// Roll up keys into common prefixes where called for, stopping once we have
+	// enough results.
+	result = &s3.ListVersionsResult{
+		Versions:       []s3.ObjectVersion{},
+		CommonPrefixes: []string{},
+	}
+
+	count := 0
+	for _, key := range keys {
+		commonPrefix := ""
+		if req.Delimiter != "" {
+			rest := key[len(req.Prefix):]
+			if i := strings.Index(rest, req.Delimiter); i >= 0 {
+				commonPrefix = key[:len(req.Prefix)+i+len(req.Delimiter)]
+			}
+		}
+
+		if commonPrefix != "" {
+			n := len(result.CommonPrefixes)
+			if commonPrefix <= req.KeyMarker || (n > 0 && result.CommonPrefixes[n-1] == commonPrefix) {
+				continue
+			}
+
+			if count == maxKeys {
+				result.IsTruncated = true
+				break
+			}
+
+			count++
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix)
+			result.NextKeyMarker = commonPrefix
+			result.NextVersionIdMarker = ""
+			continue
+		}
+
+		// Skip versions up to and including the marker, if it's for this key.
+		vs := versions[key]
+		if key == req.KeyMarker {
+			for i, v := range vs {
+				if externalVersionId(v) == req.VersionIdMarker {
+					vs = vs[i+1:]
+					break
+				}
+			}
+		}
+
+		for _, v := range vs {
+			if count == maxKeys {
+				result.IsTruncated = true
+				break
+			}
+
+			count++
+			result.Versions = append(result.Versions, s3.ObjectVersion{
+				Key:            key,
+				VersionId:      externalVersionId(v),
+				IsLatest:       v == versions[key][0],
+				IsDeleteMarker: v.deleteMarker,
+				LastModified:   v.lastModified,
+				ETag:           v.etag,
+				Size:           int64(len(v.data)),
+			})
+
+			result.NextKeyMarker = key
+			result.NextVersionIdMarker = externalVersionId(v)
+		}
+
+		if result.IsTruncated {
+			break
+		}
+	}
+
+	if !result.IsTruncated {
+		result.NextKeyMarker = ""
+		result.NextVersionIdMarker = ""
+	}
+
+	return result, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// Presigning
+////////////////////////////////////////////////////////////////////////
+
+// PresignUrl checks its arguments in the same way as the real bucket, but the
+// URL it returns refers to a host that doesn't exist.
+func (b *bucket) PresignUrl(key string, opts *s3.PresignOptions) (string, error) {
+	if err := s3.ValidateKey(key); err != nil {
+		return "", err
+	}
+
+	if opts == nil {
+		opts = &s3.PresignOptions{}
+	}
+
+	if err := validate.PresignOptions(opts.Verb, opts.Expiry, opts.ContentType); err != nil {
+		return "", err
+	}
+
+	verb := opts.Verb
+	if verb == "" {
+		verb = "GET"
+	}
+
+	query := url.Values{}
+	query.Set("Verb", verb)
+	query.Set("Expires", strconv.FormatInt(b.state.clock.Now().Add(opts.Expiry).Unix(), 10))
+
+	u := &url.URL{
+		Scheme:   "https",
+		Host:     "s3.fake.invalid",
+		Path:     "/" + b.name + "/" + key,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String(), nil
+}
@@ -0,0 +1,808 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fake
+
+import (
+	"context"
+	"fmt"
+	"github.com/jacobsa/aws/s3"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+type BucketTest struct {
+	clock  fakeClock
+	bucket s3.Bucket
+}
+
+func init() { RegisterTestSuite(&BucketTest{}) }
+
+func (t *BucketTest) SetUp(i *TestInfo) {
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+	t.bucket = newBucket("some.bucket", &t.clock)
+}
+
+func (t *BucketTest) store(key string, contents string) {
+	AssertEq(nil, t.bucket.StoreObject(key, []byte(contents)))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *BucketTest) InvalidKeys() {
+	var err error
+
+	_, err = t.bucket.GetObject("")
+	ExpectThat(err, Error(HasSubstr("empty")))
+
+	err = t.bucket.StoreObject("taco\x00", []byte{})
+	ExpectThat(err, Error(HasSubstr("codepoint")))
+
+	err = t.bucket.StoreObject(strings.Repeat("a", 1025), []byte{})
+	ExpectThat(err, Error(HasSubstr("1024")))
+
+	_, err = t.bucket.ListKeys("\xff")
+	ExpectThat(err, Error(HasSubstr("UTF-8")))
+}
+
+func (t *BucketTest) StoreThenGet() {
+	t.store("a", "taco")
+
+	data, err := t.bucket.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	r, size, err := t.bucket.GetObjectReader("a")
+	AssertEq(nil, err)
+	ExpectEq(4, size)
+
+	data, err = ioutil.ReadAll(r)
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+	ExpectEq(nil, r.Close())
+}
+
+func (t *BucketTest) GetNonExistentObject() {
+	_, err := t.bucket.GetObject("a")
+
+	ExpectTrue(s3.IsNotFound(err))
+	ExpectThat(err, Error(HasSubstr("NoSuchKey")))
+	ExpectThat(err, Error(HasSubstr("a")))
+}
+
+func (t *BucketTest) StatObject() {
+	opts := &s3.StoreOptions{
+		ContentType: "text/plain",
+		Metadata:    map[string]string{"Foo-Bar": "baz"},
+	}
+
+	result, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("taco"), 4, opts)
+	AssertEq(nil, err)
+	ExpectEq(`"f869ce1c8414a264bb11e14a2c8850ed"`, result.ETag)
+
+	info, err := t.bucket.StatObject("a")
+	AssertEq(nil, err)
+
+	ExpectEq("a", info.Key)
+	ExpectEq(4, info.Size)
+	ExpectEq(result.ETag, info.ETag)
+	ExpectTrue(info.LastModified.Equal(time.Date(1985, time.March, 18, 15, 33, 17, 0, time.UTC)))
+	ExpectEq("text/plain", info.ContentType)
+	ExpectThat(info.Metadata, DeepEquals(map[string]string{"foo-bar": "baz"}))
+}
+
+func (t *BucketTest) StatNonExistentObject() {
+	_, err := t.bucket.StatObject("a")
+
+	ExpectTrue(s3.IsNotFound(err), "%v", err)
+}
+
+func (t *BucketTest) StoreWithWrongSize() {
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("taco"), 5, nil)
+
+	ExpectThat(err, Error(HasSubstr("Expected 5 bytes")))
+}
+
+func (t *BucketTest) StoreWithInvalidMetadata() {
+	opts := &s3.StoreOptions{Metadata: map[string]string{"foo bar": "baz"}}
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader(""), 0, opts)
+
+	ExpectThat(err, Error(HasSubstr("metadata name")))
+}
+
+func (t *BucketTest) StorePreconditions() {
+	var err error
+	var ok bool
+
+	// If-None-Match: * succeeds only for a new key.
+	opts := &s3.StoreOptions{IfNoneMatch: "*"}
+	_, err = t.bucket.StoreObjectWithOptions("a", strings.NewReader("taco"), 4, opts)
+	AssertEq(nil, err)
+
+	_, err = t.bucket.StoreObjectWithOptions("a", strings.NewReader("burrito"), 7, opts)
+	_, ok = err.(*s3.PreconditionFailedError)
+	ExpectTrue(ok)
+
+	// If-Match succeeds only for the current entity tag.
+	info, err := t.bucket.StatObject("a")
+	AssertEq(nil, err)
+
+	opts = &s3.StoreOptions{IfMatch: `"foo"`}
+	_, err = t.bucket.StoreObjectWithOptions("a", strings.NewReader("burrito"), 7, opts)
+	_, ok = err.(*s3.PreconditionFailedError)
+	ExpectTrue(ok)
+
+	opts = &s3.StoreOptions{IfMatch: info.ETag}
+	_, err = t.bucket.StoreObjectWithOptions("a", strings.NewReader("burrito"), 7, opts)
+	AssertEq(nil, err)
+
+	data, err := t.bucket.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("burrito", string(data))
+}
+
+func (t *BucketTest) ConditionalGet() {
+	var err error
+	var ok bool
+
+	t.store("a", "taco")
+	info, err := t.bucket.StatObject("a")
+	AssertEq(nil, err)
+
+	_, _, err = t.bucket.GetObjectWithOptions("a", &s3.GetOptions{IfNoneMatch: info.ETag})
+	_, ok = err.(*s3.NotModifiedError)
+	ExpectTrue(ok)
+
+	_, _, err = t.bucket.GetObjectWithOptions("a", &s3.GetOptions{IfMatch: `"foo"`})
+	_, ok = err.(*s3.PreconditionFailedError)
+	ExpectTrue(ok)
+
+	opts := &s3.GetOptions{IfModifiedSince: info.LastModified}
+	_, _, err = t.bucket.GetObjectWithOptions("a", opts)
+	_, ok = err.(*s3.NotModifiedError)
+	ExpectTrue(ok)
+
+	opts = &s3.GetOptions{IfUnmodifiedSince: info.LastModified.Add(-time.Second)}
+	_, _, err = t.bucket.GetObjectWithOptions("a", opts)
+	_, ok = err.(*s3.PreconditionFailedError)
+	ExpectTrue(ok)
+
+	opts = &s3.GetOptions{
+		IfMatch:         info.ETag,
+		IfModifiedSince: info.LastModified.Add(-time.Second),
+	}
+
+	r, gotInfo, err := t.bucket.GetObjectWithOptions("a", opts)
+	AssertEq(nil, err)
+	ExpectEq(info.ETag, gotInfo.ETag)
+
+	data, err := ioutil.ReadAll(r)
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+}
+
+func (t *BucketTest) GetObjectRange() {
+	t.store("a", "0123456789")
+
+	type testCase struct {
+		offset   int64
+		length   int64
+		expected string
+	}
+
+	cases := []testCase{
+		{0, 1, "0"},
+		{3, 4, "3456"},
+		{7, 100, "789"},
+		{7, -1, "789"},
+		{-3, 0, "789"},
+		{-100, 0, "0123456789"},
+	}
+
+	for _, c := range cases {
+		data, size, err := t.bucket.GetObjectRange("a", c.offset, c.length)
+		AssertEq(nil, err, "Case: %v", c)
+		ExpectEq(10, size, "Case: %v", c)
+		ExpectEq(c.expected, string(data), "Case: %v", c)
+	}
+}
+
+func (t *BucketTest) GetObjectRangeBeyondEnd() {
+	t.store("a", "0123456789")
+
+	_, _, err := t.bucket.GetObjectRange("a", 10, 5)
+
+	rangeErr, ok := err.(*s3.RangeNotSatisfiableError)
+	AssertTrue(ok, "Error: %v", err)
+	ExpectEq("bytes=10-14", rangeErr.Range)
+	ExpectEq(10, rangeErr.ObjectSize)
+}
+
+func (t *BucketTest) GetObjectRangeZeroLength() {
+	t.store("a", "0123456789")
+
+	_, _, err := t.bucket.GetObjectRange("a", 3, 0)
+
+	ExpectThat(err, Error(HasSubstr("Length")))
+}
+
+func (t *BucketTest) CopyObject() {
+	opts := &s3.StoreOptions{
+		ContentType: "text/plain",
+		Metadata:    map[string]string{"foo": "bar"},
+	}
+
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("taco"), 4, opts)
+	AssertEq(nil, err)
+
+	// Copy the metadata.
+	_, err = t.bucket.CopyObject("a", "b", nil)
+	AssertEq(nil, err)
+
+	info, err := t.bucket.StatObject("b")
+	AssertEq(nil, err)
+	ExpectEq("text/plain", info.ContentType)
+	ExpectThat(info.Metadata, DeepEquals(map[string]string{"foo": "bar"}))
+
+	// Replace the metadata.
+	copyOpts := &s3.CopyOptions{
+		MetadataDirective: s3.MetadataReplace,
+		Metadata:          map[string]string{"baz": "qux"},
+	}
+
+	_, err = t.bucket.CopyObject("a", "c", copyOpts)
+	AssertEq(nil, err)
+
+	info, err = t.bucket.StatObject("c")
+	AssertEq(nil, err)
+	ExpectEq("binary/octet-stream", info.ContentType)
+	ExpectThat(info.Metadata, DeepEquals(map[string]string{"baz": "qux"}))
+
+	data, err := t.bucket.GetObject("c")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+}
+
+func (t *BucketTest) CopyNonExistentObject() {
+	_, err := t.bucket.CopyObject("a", "b", nil)
+
+	ExpectTrue(s3.IsNotFound(err))
+}
+
+func (t *BucketTest) DeleteObject() {
+	t.store("a", "taco")
+
+	AssertEq(nil, t.bucket.DeleteObject("a"))
+	_, err := t.bucket.GetObject("a")
+	ExpectTrue(s3.IsNotFound(err))
+
+	// Deleting again is fine.
+	ExpectEq(nil, t.bucket.DeleteObject("a"))
+}
+
+func (t *BucketTest) DeleteObjects() {
+	t.store("a", "")
+	t.store("b", "")
+	t.store("c", "")
+
+	result, err := t.bucket.DeleteObjects([]string{"a", "c", "d"}, false)
+	AssertEq(nil, err)
+	ExpectThat(result.Deleted, ElementsAre("a", "c", "d"))
+	ExpectThat(result.Errors, ElementsAre())
+
+	keys, err := t.bucket.ListKeys("")
+	AssertEq(nil, err)
+	ExpectThat(keys, ElementsAre("b"))
+
+	result, err = t.bucket.DeleteObjects([]string{"b"}, true)
+	AssertEq(nil, err)
+	ExpectThat(result.Deleted, ElementsAre())
+
+	_, err = t.bucket.DeleteObjects([]string{}, true)
+	ExpectThat(err, Error(HasSubstr("At least one")))
+}
+
+func (t *BucketTest) MultipartUpload() {
+	uploadId, err := t.bucket.InitiateMultipartUpload("a")
+	AssertEq(nil, err)
+
+	first := strings.Repeat("x", minPartSize)
+	etag1, err := t.bucket.UploadPart("a", uploadId, 1, strings.NewReader(first), -1)
+	AssertEq(nil, err)
+
+	etag2, err := t.bucket.UploadPart("a", uploadId, 2, strings.NewReader("taco"), 4)
+	AssertEq(nil, err)
+
+	// Nothing is visible until the upload is complete.
+	_, err = t.bucket.StatObject("a")
+	ExpectTrue(s3.IsNotFound(err))
+
+	parts := []s3.CompletedPart{{PartNumber: 1, ETag: etag1}, {PartNumber: 2, ETag: etag2}}
+	AssertEq(nil, t.bucket.CompleteMultipartUpload("a", uploadId, parts))
+
+	data, err := t.bucket.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq(first+"taco", string(data))
+
+	info, err := t.bucket.StatObject("a")
+	AssertEq(nil, err)
+	ExpectThat(info.ETag, MatchesRegexp(`^"[0-9a-f]{32}-2"$`))
+
+	// The upload is gone.
+	err = t.bucket.AbortMultipartUpload("a", uploadId)
+	ExpectTrue(s3.IsNotFound(err))
+}
+
+func (t *BucketTest) MultipartUploadWithSmallPart() {
+	uploadId, err := t.bucket.InitiateMultipartUpload("a")
+	AssertEq(nil, err)
+
+	etag1, err := t.bucket.UploadPart("a", uploadId, 1, strings.NewReader("taco"), 4)
+	AssertEq(nil, err)
+
+	etag2, err := t.bucket.UploadPart("a", uploadId, 2, strings.NewReader("burrito"), 7)
+	AssertEq(nil, err)
+
+	parts := []s3.CompletedPart{{PartNumber: 1, ETag: etag1}, {PartNumber: 2, ETag: etag2}}
+	err = t.bucket.CompleteMultipartUpload("a", uploadId, parts)
+	ExpectThat(err, Error(HasSubstr("EntityTooSmall")))
+}
+
+func (t *BucketTest) MultipartUploadWithWrongETag() {
+	uploadId, err := t.bucket.InitiateMultipartUpload("a")
+	AssertEq(nil, err)
+
+	_, err = t.bucket.UploadPart("a", uploadId, 1, strings.NewReader("taco"), 4)
+	AssertEq(nil, err)
+
+	parts := []s3.CompletedPart{{PartNumber: 1, ETag: `"foo"`}}
+	err = t.bucket.CompleteMultipartUpload("a", uploadId, parts)
+	ExpectThat(err, Error(HasSubstr("InvalidPart")))
+}
+
+func (t *BucketTest) UnknownUpload() {
+	_, err := t.bucket.UploadPart("a", "foo", 1, strings.NewReader(""), 0)
+	ExpectTrue(s3.IsNotFound(err))
+	ExpectThat(err, Error(HasSubstr("NoSuchUpload")))
+
+	uploadId, err := t.bucket.InitiateMultipartUpload("a")
+	AssertEq(nil, err)
+
+	err = t.bucket.AbortMultipartUpload("b", uploadId)
+	ExpectTrue(s3.IsNotFound(err))
+
+	ExpectEq(nil, t.bucket.AbortMultipartUpload("a", uploadId))
+}
+
+func (t *BucketTest) ListKeysPagesInBatches() {
+	// Store enough keys for three batches, in an order other than sorted.
+	var expected []string
+	for i := 0; i < 2500; i++ {
+		key := fmt.Sprintf("%d", (i*7)%2500)
+		expected = append(expected, key)
+		t.store(key, "")
+	}
+
+	sort.Strings(expected)
+
+	// List them.
+	var keys []string
+	var batchSizes []int
+	for {
+		var prevKey string
+		if len(keys) > 0 {
+			prevKey = keys[len(keys)-1]
+		}
+
+		batch, err := t.bucket.ListKeys(prevKey)
+		AssertEq(nil, err)
+		if len(batch) == 0 {
+			break
+		}
+
+		keys = append(keys, batch...)
+		batchSizes = append(batchSizes, len(batch))
+	}
+
+	ExpectThat(batchSizes, ElementsAre(1000, 1000, 500))
+	ExpectThat(keys, DeepEquals(expected))
+}
+
+func (t *BucketTest) ListObjectsWithDelimiter() {
+	t.store("foo/a", "")
+	t.store("foo/bar/b", "")
+	t.store("foo/bar/c", "")
+	t.store("foo/baz/d", "")
+	t.store("foo/e", "")
+	t.store("qux", "")
+
+	req := s3.ListRequest{Prefix: "foo/", Delimiter: "/", MaxKeys: 2}
+
+	result, err := t.bucket.ListObjects(req)
+	AssertEq(nil, err)
+	ExpectThat(result.Keys, ElementsAre("foo/a"))
+	ExpectThat(result.CommonPrefixes, ElementsAre("foo/bar/"))
+	ExpectTrue(result.IsTruncated)
+	ExpectEq("foo/bar/", result.NextMarker)
+
+	req.Marker = result.NextMarker
+	result, err = t.bucket.ListObjects(req)
+	AssertEq(nil, err)
+	ExpectThat(result.Keys, ElementsAre("foo/e"))
+	ExpectThat(result.CommonPrefixes, ElementsAre("foo/baz/"))
+	ExpectFalse(result.IsTruncated)
+	ExpectEq("", result.NextMarker)
+}
+
+func (t *BucketTest) ServerSideEncryption() {
+	opts := &s3.StoreOptions{ServerSideEncryption: s3.SseKms, KmsKeyId: "taco"}
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("burrito"), -1, opts)
+	AssertEq(nil, err)
+
+	info, err := t.bucket.StatObject("a")
+	AssertEq(nil, err)
+	ExpectEq(s3.SseKms, info.ServerSideEncryption)
+
+	// Invalid options are rejected.
+	opts = &s3.StoreOptions{ServerSideEncryption: s3.SseS3, KmsKeyId: "taco"}
+	_, err = t.bucket.StoreObjectWithOptions("a", strings.NewReader(""), 0, opts)
+	ExpectThat(err, Error(HasSubstr("SseKms")))
+}
+
+func (t *BucketTest) CustomerKey() {
+	key := []byte(strings.Repeat("k", s3.CustomerKeyLength))
+	opts := &s3.StoreOptions{CustomerKey: key}
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("taco"), -1, opts)
+	AssertEq(nil, err)
+
+	// With the right key.
+	r, info, err := t.bucket.GetObjectWithOptions("a", &s3.GetOptions{CustomerKey: key})
+	AssertEq(nil, err)
+	ExpectEq("", info.ServerSideEncryption)
+
+	data, err := ioutil.ReadAll(r)
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	info, err = t.bucket.StatObjectWithOptions("a", &s3.StatOptions{CustomerKey: key})
+	AssertEq(nil, err)
+	ExpectEq(4, info.Size)
+
+	data, size, err := t.bucket.GetObjectRangeWithOptions(
+		"a", 1, 2, &s3.RangeOptions{CustomerKey: key})
+
+	AssertEq(nil, err)
+	ExpectEq("ac", string(data))
+	ExpectEq(4, size)
+
+	// Without a key.
+	_, err = t.bucket.GetObject("a")
+	ExpectThat(err, Error(HasSubstr("InvalidRequest")))
+
+	_, err = t.bucket.StatObject("a")
+	ExpectThat(err, Error(HasSubstr("InvalidRequest")))
+
+	_, _, err = t.bucket.GetObjectRange("a", 0, 1)
+	ExpectThat(err, Error(HasSubstr("InvalidRequest")))
+
+	_, err = t.bucket.CopyObject("a", "b", nil)
+	ExpectThat(err, Error(HasSubstr("InvalidRequest")))
+
+	// With the wrong key.
+	wrongKey := []byte(strings.Repeat("w", s3.CustomerKeyLength))
+	_, _, err = t.bucket.GetObjectWithOptions("a", &s3.GetOptions{CustomerKey: wrongKey})
+	ExpectThat(err, Error(HasSubstr("AccessDenied")))
+
+	_, err = t.bucket.StatObjectWithOptions("a", &s3.StatOptions{CustomerKey: wrongKey})
+	ExpectThat(err, Error(HasSubstr("AccessDenied")))
+
+	_, _, err = t.bucket.GetObjectRangeWithOptions(
+		"a", 0, 1, &s3.RangeOptions{CustomerKey: wrongKey})
+
+	ExpectThat(err, Error(HasSubstr("AccessDenied")))
+
+	// With a key for an object that doesn't need one.
+	t.store("b", "burrito")
+	_, _, err = t.bucket.GetObjectWithOptions("b", &s3.GetOptions{CustomerKey: key})
+	ExpectThat(err, Error(HasSubstr("not applicable")))
+
+	// With a key of the wrong length.
+	_, _, err = t.bucket.GetObjectWithOptions("a", &s3.GetOptions{CustomerKey: key[1:]})
+	ExpectThat(err, Error(HasSubstr("32 bytes")))
+
+	_, err = t.bucket.StatObjectWithOptions("a", &s3.StatOptions{CustomerKey: key[1:]})
+	ExpectThat(err, Error(HasSubstr("32 bytes")))
+
+	_, _, err = t.bucket.GetObjectRangeWithOptions(
+		"a", 0, 1, &s3.RangeOptions{CustomerKey: key[1:]})
+
+	ExpectThat(err, Error(HasSubstr("32 bytes")))
+}
+
+// Return the keys, version IDs, and delete marker flags of the versions
+// listed for the supplied request, as strings like "a@version-1" and
+// "a@version-2 (deleted)".
+func (t *BucketTest) listVersions(req s3.ListVersionsRequest) []string {
+	result, err := t.bucket.ListObjectVersions(req)
+	AssertEq(nil, err)
+
+	var versions []string
+	for _, v := range result.Versions {
+		s := v.Key + "@" + v.VersionId
+		if v.IsDeleteMarker {
+			s += " (deleted)"
+		}
+
+		versions = append(versions, s)
+	}
+
+	return versions
+}
+
+func (t *BucketTest) VersioningDisabled() {
+	status, err := t.bucket.GetVersioning()
+	AssertEq(nil, err)
+	ExpectEq("", status)
+
+	t.store("a", "taco")
+	t.store("a", "burrito")
+
+	result, err := t.bucket.StoreObjectWithOptions("b", strings.NewReader(""), 0, nil)
+	AssertEq(nil, err)
+	ExpectEq("", result.VersionId)
+
+	ExpectThat(t.listVersions(s3.ListVersionsRequest{}), ElementsAre("a@null", "b@null"))
+
+	// Deleting removes the object entirely.
+	AssertEq(nil, t.bucket.DeleteObject("a"))
+	ExpectThat(t.listVersions(s3.ListVersionsRequest{}), ElementsAre("b@null"))
+}
+
+func (t *BucketTest) VersioningEnabled() {
+	t.store("a", "taco")
+	AssertEq(nil, t.bucket.SetVersioning(s3.VersioningEnabled))
+
+	status, err := t.bucket.GetVersioning()
+	AssertEq(nil, err)
+	ExpectEq(s3.VersioningEnabled, status)
+
+	// Overwrite the object, keeping the old version.
+	t.clock.now = t.clock.now.Add(time.Hour)
+	result, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("burrito"), -1, nil)
+	AssertEq(nil, err)
+	AssertNe("", result.VersionId)
+	newVersion := result.VersionId
+
+	data, err := t.bucket.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("burrito", string(data))
+
+	r, info, err := t.bucket.GetObjectWithOptions("a", &s3.GetOptions{VersionId: "null"})
+	AssertEq(nil, err)
+	data, _ = ioutil.ReadAll(r)
+	ExpectEq("taco", string(data))
+	ExpectEq("", info.VersionId)
+
+	// Delete it, hiding both versions behind a delete marker.
+	AssertEq(nil, t.bucket.DeleteObject("a"))
+
+	_, err = t.bucket.GetObject("a")
+	ExpectTrue(s3.IsNotFound(err))
+
+	listing, err := t.bucket.ListObjectVersions(s3.ListVersionsRequest{})
+	AssertEq(nil, err)
+	AssertEq(3, len(listing.Versions))
+
+	marker := listing.Versions[0]
+	ExpectTrue(marker.IsDeleteMarker)
+	ExpectTrue(marker.IsLatest)
+	ExpectEq("", marker.ETag)
+
+	ExpectThat(
+		listing.Versions[1],
+		DeepEquals(s3.ObjectVersion{
+			Key:          "a",
+			VersionId:    newVersion,
+			LastModified: time.Date(1985, time.March, 18, 16, 33, 17, 0, time.UTC),
+			ETag:         computeETag([]byte("burrito")),
+			Size:         7,
+		}))
+
+	ExpectEq("null", listing.Versions[2].VersionId)
+	ExpectFalse(listing.Versions[2].IsLatest)
+
+	// Reading the delete marker is an error.
+	_, _, err = t.bucket.GetObjectWithOptions("a", &s3.GetOptions{VersionId: marker.VersionId})
+	ExpectThat(err, Error(HasSubstr("MethodNotAllowed")))
+
+	_, _, err = t.bucket.GetObjectWithOptions("a", &s3.GetOptions{VersionId: "taco"})
+	ExpectThat(err, Error(HasSubstr("NoSuchVersion")))
+
+	// Deleting the delete marker restores the newest version.
+	AssertEq(nil, t.bucket.DeleteObjectVersion("a", marker.VersionId))
+
+	data, err = t.bucket.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("burrito", string(data))
+
+	// Deleting that version restores the previous one.
+	AssertEq(nil, t.bucket.DeleteObjectVersion("a", newVersion))
+
+	data, err = t.bucket.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	// Deleting the last version removes the key.
+	AssertEq(nil, t.bucket.DeleteObjectVersion("a", "null"))
+	ExpectThat(t.listVersions(s3.ListVersionsRequest{}), ElementsAre())
+
+	// Deleting a version that doesn't exist is fine.
+	ExpectEq(nil, t.bucket.DeleteObjectVersion("a", "null"))
+}
+
+func (t *BucketTest) VersioningSuspended() {
+	AssertEq(nil, t.bucket.SetVersioning(s3.VersioningEnabled))
+	t.store("a", "taco")
+
+	AssertEq(nil, t.bucket.SetVersioning(s3.VersioningSuspended))
+	t.store("a", "burrito")
+	t.store("a", "enchilada")
+
+	// The null version is replaced, but the earlier version is kept.
+	ExpectThat(
+		t.listVersions(s3.ListVersionsRequest{}),
+		ElementsAre("a@null", "a@version-1"))
+
+	info, err := t.bucket.StatObject("a")
+	AssertEq(nil, err)
+	ExpectEq("null", info.VersionId)
+
+	// Deleting places a null delete marker.
+	AssertEq(nil, t.bucket.DeleteObject("a"))
+	ExpectThat(
+		t.listVersions(s3.ListVersionsRequest{}),
+		ElementsAre("a@null (deleted)", "a@version-1"))
+
+	ExpectThat(t.bucket.SetVersioning(""), Error(HasSubstr("Invalid")))
+}
+
+func (t *BucketTest) ListObjectVersionsPagesInBatches() {
+	AssertEq(nil, t.bucket.SetVersioning(s3.VersioningEnabled))
+	t.store("a", "")
+	t.store("b", "")
+	t.store("b", "")
+	t.store("b", "")
+	t.store("c/d", "")
+	t.store("c/e", "")
+	t.store("f", "")
+
+	var versions, prefixes []string
+	req := s3.ListVersionsRequest{Delimiter: "/", MaxKeys: 2}
+	for {
+		result, err := t.bucket.ListObjectVersions(req)
+		AssertEq(nil, err)
+		AssertLe(len(result.Versions)+len(result.CommonPrefixes), 2)
+
+		for _, v := range result.Versions {
+			versions = append(versions, v.Key+"@"+v.VersionId)
+		}
+
+		prefixes = append(prefixes, result.CommonPrefixes...)
+
+		if !result.IsTruncated {
+			ExpectEq("", result.NextKeyMarker)
+			break
+		}
+
+		req.KeyMarker = result.NextKeyMarker
+		req.VersionIdMarker = result.NextVersionIdMarker
+	}
+
+	ExpectThat(
+		versions,
+		ElementsAre(
+			"a@version-1",
+			"b@version-4",
+			"b@version-3",
+			"b@version-2",
+			"f@version-7"))
+
+	ExpectThat(prefixes, ElementsAre("c/"))
+}
+
+func (t *BucketTest) PresignUrl() {
+	url, err := t.bucket.PresignUrl("a", &s3.PresignOptions{Expiry: time.Minute})
+	AssertEq(nil, err)
+	ExpectThat(url, HasSubstr("/some.bucket/a?"))
+
+	_, err = t.bucket.PresignUrl("a", &s3.PresignOptions{Verb: "DELETE", Expiry: time.Minute})
+	ExpectThat(err, Error(HasSubstr("verb")))
+}
+
+func (t *BucketTest) WithContext() {
+	t.store("a", "taco")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := t.bucket.WithContext(ctx)
+
+	r, _, err := b.GetObjectReader("a")
+	AssertEq(nil, err)
+	cancel()
+
+	_, err = ioutil.ReadAll(r)
+	ExpectEq(context.Canceled, err)
+
+	_, err = b.GetObject("a")
+	ExpectEq(context.Canceled, err)
+
+	err = b.StoreObject("b", []byte{})
+	ExpectEq(context.Canceled, err)
+
+	// The original is unaffected, and shares the same contents.
+	data, err := t.bucket.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+}
+
+func (t *BucketTest) ConcurrentUse() {
+	const numWorkers = 8
+	const numKeys = 100
+
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < numKeys; j++ {
+				key := fmt.Sprintf("%d/%d", i, j)
+				if err := t.bucket.StoreObject(key, []byte(key)); err != nil {
+					AddFailure("StoreObject: %v", err)
+				}
+
+				if _, err := t.bucket.ListKeys(""); err != nil {
+					AddFailure("ListKeys: %v", err)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	result, err := t.bucket.ListObjects(s3.ListRequest{})
+	AssertEq(nil, err)
+	ExpectEq(numWorkers*numKeys, len(result.Keys))
+}
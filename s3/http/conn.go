@@ -18,9 +18,11 @@ package http
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 // A connection to a particular server over a particular protocol (HTTP or
@@ -30,11 +32,22 @@ type Conn interface {
 	// only if a response was received from the server. (That is, a 500 error
 	// from the server will be returned here as a response with a nil error).
 	SendRequest(r *Request) (*Response, error)
+
+	// Like SendRequest, but return the response body as a stream rather than
+	// reading it into memory. If the error is nil, the caller must close the
+	// response body.
+	StreamRequest(r *Request) (*StreamingResponse, error)
 }
 
 // Return a connection to the supplied endpoint, based on its scheme and host
 // fields.
 func NewConn(endpoint *url.URL) (c Conn, err error) {
+	return NewConnWithClient(endpoint, http.DefaultClient)
+}
+
+// Like NewConn, but send requests using the supplied HTTP client rather than
+// http.DefaultClient.
+func NewConnWithClient(endpoint *url.URL, client *http.Client) (c Conn, err error) {
 	switch endpoint.Scheme {
 	case "http", "https":
 	default:
@@ -42,12 +55,13 @@ func NewConn(endpoint *url.URL) (c Conn, err error) {
 		return
 	}
 
-	c = &conn{endpoint}
+	c = &conn{endpoint, client}
 	return
 }
 
 type conn struct {
 	endpoint *url.URL
+	client   *http.Client
 }
 
 func makeRawQuery(r *Request) string {
@@ -59,7 +73,40 @@ func makeRawQuery(r *Request) string {
 	return values.Encode()
 }
 
+func convertHeaders(h http.Header) map[string]string {
+	result := make(map[string]string, len(h))
+	for key, vals := range h {
+		result[key] = strings.Join(vals, ",")
+	}
+
+	return result
+}
+
 func (c *conn) SendRequest(r *Request) (resp *Response, err error) {
+	// Send the request.
+	streamingResp, err := c.StreamRequest(r)
+	if err != nil {
+		return
+	}
+
+	// Make sure the body reader is closed no matter how we exit.
+	defer streamingResp.Body.Close()
+
+	// Convert the response.
+	resp = &Response{
+		StatusCode: streamingResp.StatusCode,
+		Headers:    streamingResp.Headers,
+	}
+
+	if resp.Body, err = ioutil.ReadAll(streamingResp.Body); err != nil {
+		err = &Error{"ioutil.ReadAll", err}
+		return
+	}
+
+	return
+}
+
+func (c *conn) StreamRequest(r *Request) (resp *StreamingResponse, err error) {
 	// Create an appropriate URL.
 	url := url.URL{
 		Scheme:   c.endpoint.Scheme,
@@ -68,38 +115,63 @@ func (c *conn) SendRequest(r *Request) (resp *Response, err error) {
 		RawQuery: makeRawQuery(r),
 	}
 
+	if r.Bucket != "" {
+		url.Host = r.Bucket + "." + url.Host
+	}
+
 	urlStr := url.String()
 
+	// Choose an appropriate body.
+	var body io.Reader = bytes.NewBuffer(r.Body)
+	if r.BodyReader != nil {
+		body = r.BodyReader
+	}
+
 	// Create a request to the system HTTP library.
-	sysReq, err := http.NewRequest(r.Verb, urlStr, bytes.NewBuffer(r.Body))
+	sysReq, err := http.NewRequest(r.Verb, urlStr, body)
 	if err != nil {
 		err = &Error{"http.NewRequest", err}
 		return
 	}
 
+	if r.Context != nil {
+		sysReq = sysReq.WithContext(r.Context)
+	}
+
+	// The system library can't know the length of an arbitrary stream, and
+	// treats a zero length as unknown unless there is no body at all.
+	if r.BodyReader != nil {
+		sysReq.ContentLength = r.ContentLength
+		if r.ContentLength == 0 {
+			sysReq.Body = http.NoBody
+		}
+	}
+
 	// Copy headers.
 	for key, val := range r.Headers {
 		sysReq.Header.Set(key, val)
 	}
 
+	// Unless told otherwise, the system library asks for gzip and silently
+	// decompresses the response, which would mangle objects stored with a
+	// Content-Encoding of gzip. Ask for objects exactly as they were stored.
+	if sysReq.Header.Get("Accept-Encoding") == "" {
+		sysReq.Header.Set("Accept-Encoding", "identity")
+	}
+
 	// Call the system HTTP library.
-	sysResp, err := http.DefaultClient.Do(sysReq)
+	sysResp, err := c.client.Do(sysReq)
 	if err != nil {
-		err = &Error{"http.DefaultClient.Do", err}
+		err = &Error{"http.Client.Do", err}
 		return
 	}
 
-	// Make sure the body reader is closed no matter how we exit.
-	defer sysResp.Body.Close()
-
-	// Convert the response.
-	resp = &Response{
-		StatusCode: sysResp.StatusCode,
-	}
-
-	if resp.Body, err = ioutil.ReadAll(sysResp.Body); err != nil {
-		err = &Error{"ioutil.ReadAll", err}
-		return
+	// Convert the response, handing off ownership of the body to the caller.
+	resp = &StreamingResponse{
+		StatusCode:    sysResp.StatusCode,
+		ContentLength: sysResp.ContentLength,
+		Body:          sysResp.Body,
+		Headers:       convertHeaders(sysResp.Header),
 	}
 
 	return
@@ -16,12 +16,15 @@
 package http_test
 
 import (
+	"context"
 	"github.com/jacobsa/aws/s3/http"
 	. "github.com/jacobsa/oglematchers"
 	. "github.com/jacobsa/ogletest"
+	"io/ioutil"
 	sys_http "net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -33,10 +36,12 @@ func TestConn(t *testing.T) { RunTests(t) }
 
 type localHandler struct {
 	// Input seen.
-	req *sys_http.Request
+	req     *sys_http.Request
+	reqBody []byte
 
 	// To be returned.
 	statusCode int
+	headers    map[string][]string
 	body       []byte
 }
 
@@ -48,13 +53,34 @@ func (h *localHandler) ServeHTTP(w sys_http.ResponseWriter, r *sys_http.Request)
 
 	h.req = r
 
+	var err error
+	if h.reqBody, err = ioutil.ReadAll(r.Body); err != nil {
+		panic(err)
+	}
+
 	// Write out the response.
+	for key, vals := range h.headers {
+		w.Header()[key] = vals
+	}
+
 	w.WriteHeader(h.statusCode)
 	if _, err := w.Write(h.body); err != nil {
 		panic(err)
 	}
 }
 
+// A transport that records the request it is asked to send and returns a
+// canned response.
+type fakeTransport struct {
+	req  *sys_http.Request
+	resp *sys_http.Response
+}
+
+func (t *fakeTransport) RoundTrip(r *sys_http.Request) (*sys_http.Response, error) {
+	t.req = r
+	return t.resp, nil
+}
+
 type ConnTest struct {
 	handler  localHandler
 	server   *httptest.Server
@@ -64,6 +90,7 @@ type ConnTest struct {
 func init() { RegisterTestSuite(&ConnTest{}) }
 
 func (t *ConnTest) SetUp(i *TestInfo) {
+	t.handler.statusCode = 200
 	t.server = httptest.NewServer(&t.handler)
 
 	var err error
@@ -106,6 +133,82 @@ func (t *ConnTest) UnknownHost() {
 	ExpectThat(err, Error(HasSubstr("no such host")))
 }
 
+func (t *ConnTest) ContextCancelled() {
+	// Connection
+	conn, err := http.NewConn(t.endpoint)
+	AssertEq(nil, err)
+
+	// Request
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := &http.Request{
+		Verb:    "GET",
+		Path:    "/foo",
+		Headers: map[string]string{},
+		Context: ctx,
+	}
+
+	// Call
+	_, err = conn.SendRequest(req)
+
+	ExpectThat(err, Error(HasSubstr("canceled")))
+	ExpectEq(nil, t.handler.req)
+}
+
+func (t *ConnTest) UsesSuppliedClient() {
+	// Client
+	transport := &fakeTransport{
+		resp: &sys_http.Response{
+			StatusCode: 203,
+			Body:       ioutil.NopCloser(strings.NewReader("taco")),
+		},
+	}
+
+	client := &sys_http.Client{Transport: transport}
+
+	// Connection
+	conn, err := http.NewConnWithClient(t.endpoint, client)
+	AssertEq(nil, err)
+
+	// Request
+	req := &http.Request{
+		Verb:    "GET",
+		Path:    "/foo",
+		Headers: map[string]string{},
+	}
+
+	// Call
+	resp, err := conn.SendRequest(req)
+	AssertEq(nil, err)
+
+	AssertNe(nil, transport.req)
+	ExpectEq("/foo", transport.req.URL.Path)
+	ExpectEq(nil, t.handler.req)
+
+	ExpectEq(203, resp.StatusCode)
+	ExpectEq("taco", string(resp.Body))
+}
+
+func (t *ConnTest) BucketPrependedToHost() {
+	// Connection
+	conn, err := http.NewConn(&url.URL{Scheme: "http", Host: "foo.sidofhdksjhf"})
+	AssertEq(nil, err)
+
+	// Request
+	req := &http.Request{
+		Verb:    "GET",
+		Bucket:  "taco",
+		Path:    "/foo",
+		Headers: map[string]string{},
+	}
+
+	// Call
+	_, err = conn.SendRequest(req)
+
+	ExpectThat(err, Error(HasSubstr("taco.foo.sidofhdksjhf")))
+}
+
 func (t *ConnTest) PassesOnRequestInfo() {
 	// Connection
 	conn, err := http.NewConn(t.endpoint)
@@ -135,6 +238,123 @@ func (t *ConnTest) PassesOnRequestInfo() {
 	ExpectThat(sysReq.Header["Enchilada"], ElementsAre("queso"))
 }
 
+func (t *ConnTest) AsksForIdentityEncoding() {
+	// Connection
+	conn, err := http.NewConn(t.endpoint)
+	AssertEq(nil, err)
+
+	// Request
+	req := &http.Request{
+		Verb:    "GET",
+		Path:    "/foo",
+		Headers: map[string]string{},
+	}
+
+	// Call
+	_, err = conn.SendRequest(req)
+	AssertEq(nil, err)
+
+	AssertNe(nil, t.handler.req)
+	ExpectThat(t.handler.req.Header["Accept-Encoding"], ElementsAre("identity"))
+}
+
+func (t *ConnTest) DoesNotDecompressGzipBody() {
+	// Handler
+	t.handler.headers = map[string][]string{
+		"Content-Encoding": []string{"gzip"},
+	}
+
+	t.handler.body = []byte{0x1f, 0x8b, 0xde, 0xad, 0xbe, 0xef}
+
+	// Connection
+	conn, err := http.NewConn(t.endpoint)
+	AssertEq(nil, err)
+
+	// Request
+	req := &http.Request{
+		Verb:    "GET",
+		Path:    "/foo",
+		Headers: map[string]string{},
+	}
+
+	// Call
+	resp, err := conn.SendRequest(req)
+	AssertEq(nil, err)
+
+	ExpectEq("gzip", resp.Headers["Content-Encoding"])
+	ExpectThat(resp.Body, DeepEquals(t.handler.body))
+}
+
+func (t *ConnTest) PassesOnBody() {
+	// Connection
+	conn, err := http.NewConn(t.endpoint)
+	AssertEq(nil, err)
+
+	// Request
+	req := &http.Request{
+		Verb:    "PUT",
+		Path:    "/foo/bar",
+		Headers: map[string]string{},
+		Body:    []byte("taco"),
+	}
+
+	// Call
+	_, err = conn.SendRequest(req)
+	AssertEq(nil, err)
+
+	AssertNe(nil, t.handler.req)
+	ExpectEq(4, t.handler.req.ContentLength)
+	ExpectThat(t.handler.reqBody, DeepEquals([]byte("taco")))
+}
+
+func (t *ConnTest) PassesOnBodyReader() {
+	// Connection
+	conn, err := http.NewConn(t.endpoint)
+	AssertEq(nil, err)
+
+	// Request
+	req := &http.Request{
+		Verb:          "PUT",
+		Path:          "/foo/bar",
+		Headers:       map[string]string{},
+		BodyReader:    strings.NewReader("burrito"),
+		ContentLength: 7,
+	}
+
+	// Call
+	_, err = conn.SendRequest(req)
+	AssertEq(nil, err)
+
+	AssertNe(nil, t.handler.req)
+	ExpectEq(7, t.handler.req.ContentLength)
+	ExpectThat(t.handler.req.TransferEncoding, ElementsAre())
+	ExpectThat(t.handler.reqBody, DeepEquals([]byte("burrito")))
+}
+
+func (t *ConnTest) PassesOnEmptyBodyReader() {
+	// Connection
+	conn, err := http.NewConn(t.endpoint)
+	AssertEq(nil, err)
+
+	// Request
+	req := &http.Request{
+		Verb:          "PUT",
+		Path:          "/foo/bar",
+		Headers:       map[string]string{},
+		BodyReader:    strings.NewReader(""),
+		ContentLength: 0,
+	}
+
+	// Call
+	_, err = conn.SendRequest(req)
+	AssertEq(nil, err)
+
+	AssertNe(nil, t.handler.req)
+	ExpectEq(0, t.handler.req.ContentLength)
+	ExpectThat(t.handler.req.TransferEncoding, ElementsAre())
+	ExpectThat(t.handler.reqBody, ElementsAre())
+}
+
 func (t *ConnTest) RequestContainsNoParameters() {
 	// Connection
 	conn, err := http.NewConn(t.endpoint)
@@ -292,6 +512,34 @@ func (t *ConnTest) ReturnsBody() {
 	ExpectThat(resp.Body, DeepEquals(t.handler.body))
 }
 
+func (t *ConnTest) ReturnsHeaders() {
+	// Handler
+	t.handler.headers = map[string][]string{
+		"Etag":           []string{"\"taco\""},
+		"x-amz-meta-foo": []string{"bar"},
+		"X-Multiple":     []string{"a", "b"},
+	}
+
+	// Connection
+	conn, err := http.NewConn(t.endpoint)
+	AssertEq(nil, err)
+
+	// Request
+	req := &http.Request{
+		Verb:    "GET",
+		Path:    "/",
+		Headers: map[string]string{},
+	}
+
+	// Call
+	resp, err := conn.SendRequest(req)
+	AssertEq(nil, err)
+
+	ExpectEq("\"taco\"", resp.Headers["Etag"])
+	ExpectEq("bar", resp.Headers["X-Amz-Meta-Foo"])
+	ExpectEq("a,b", resp.Headers["X-Multiple"])
+}
+
 func (t *ConnTest) ServerReturnsEmptyBody() {
 	// Handler
 	t.handler.body = []byte{}
@@ -321,3 +569,53 @@ func (t *ConnTest) HttpsAllowed() {
 	_, err := http.NewConn(t.endpoint)
 	AssertEq(nil, err)
 }
+
+func (t *ConnTest) StreamRequestReturnsStatusCodeAndLength() {
+	// Handler
+	t.handler.statusCode = 404
+	t.handler.body = []byte("taco")
+
+	// Connection
+	conn, err := http.NewConn(t.endpoint)
+	AssertEq(nil, err)
+
+	// Request
+	req := &http.Request{
+		Verb:    "GET",
+		Path:    "/",
+		Headers: map[string]string{},
+	}
+
+	// Call
+	resp, err := conn.StreamRequest(req)
+	AssertEq(nil, err)
+	defer resp.Body.Close()
+
+	ExpectEq(404, resp.StatusCode)
+	ExpectEq(4, resp.ContentLength)
+}
+
+func (t *ConnTest) StreamRequestReturnsBody() {
+	// Handler
+	t.handler.body = []byte{0xde, 0xad, 0x00, 0xbe, 0xef}
+
+	// Connection
+	conn, err := http.NewConn(t.endpoint)
+	AssertEq(nil, err)
+
+	// Request
+	req := &http.Request{
+		Verb:    "GET",
+		Path:    "/",
+		Headers: map[string]string{},
+	}
+
+	// Call
+	resp, err := conn.StreamRequest(req)
+	AssertEq(nil, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	AssertEq(nil, err)
+	ExpectThat(body, DeepEquals(t.handler.body))
+}
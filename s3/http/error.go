@@ -32,3 +32,8 @@ type Error struct {
 func (e *Error) Error() string {
 	return fmt.Sprintf("%s: %v", e.Operation, e.OriginalErr)
 }
+
+// Return the original error, for use with errors.Is and errors.As.
+func (e *Error) Unwrap() error {
+	return e.OriginalErr
+}
@@ -69,3 +69,32 @@ func (m *mockConn) SendRequest(p0 *http.Request) (o0 *http.Response, o1 error) {
 
 	return
 }
+
+func (m *mockConn) StreamRequest(p0 *http.Request) (o0 *http.StreamingResponse, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"StreamRequest",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockConn.StreamRequest: invalid return values: %v", retVals))
+	}
+
+	// o0 *http.StreamingResponse
+	if retVals[0] != nil {
+		o0 = retVals[0].(*http.StreamingResponse)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
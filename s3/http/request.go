@@ -15,13 +15,18 @@
 
 package http
 
+import (
+	"context"
+	"io"
+)
+
 // An HTTP request to S3.
 type Request struct {
 	// The HTTP verb; e.g. "PUT" or "GET".
 	Verb string
 
-	// The path of the HTTP request URI, including the bucket name. This should
-	// not be URL-encoded.
+	// The path of the HTTP request URI, including the bucket name unless Bucket
+	// is set. This should not be URL-encoded.
 	//
 	// For example:
 	//     /mybucket
@@ -29,6 +34,17 @@ type Request struct {
 	//
 	Path string
 
+	// If non-empty, the request uses virtual-hosted-style addressing: it is
+	// sent to the host formed by prepending this bucket name to the
+	// connection's endpoint host, and Path doesn't include the bucket name.
+	//
+	// For example, with an endpoint of s3.amazonaws.com:
+	//     Bucket: mybucket
+	//     Path:   /foo/bar/baz.jpg
+	//
+	// results in a request to mybucket.s3.amazonaws.com/foo/bar/baz.jpg.
+	Bucket string
+
 	// Request parameters, as defined by the S3 service being called. These
 	// should not be URL-encoded.
 	//
@@ -43,4 +59,16 @@ type Request struct {
 
 	// The body of the request.
 	Body []byte
+
+	// If non-nil, a stream from which the body of the request is read in place
+	// of Body. ContentLength must then be set to the number of bytes that the
+	// stream will yield.
+	BodyReader io.Reader
+
+	// The length in bytes of BodyReader. Ignored if BodyReader is nil.
+	ContentLength int64
+
+	// If non-nil, a context whose cancellation or deadline aborts the request,
+	// including the reading of a streamed response body.
+	Context context.Context
 }
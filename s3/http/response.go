@@ -15,6 +15,10 @@
 
 package http
 
+import (
+	"io"
+)
+
 // An HTTP response from S3.
 type Response struct {
 	// The HTTP status code, e.g. 200 or 404.
@@ -22,4 +26,25 @@ type Response struct {
 
 	// The response body. This is the empty slice if the body was empty.
 	Body []byte
+
+	// The HTTP headers returned by the server, keyed by their canonical form as
+	// defined by net/http.CanonicalHeaderKey (e.g. "Etag" or "X-Amz-Meta-Foo").
+	// Multiple values for a single header are joined with commas.
+	Headers map[string]string
+}
+
+// An HTTP response from S3 whose body has not yet been read.
+type StreamingResponse struct {
+	// The HTTP status code, e.g. 200 or 404.
+	StatusCode int
+
+	// The length of the response body in bytes, or -1 if the server didn't say.
+	ContentLength int64
+
+	// The response body. The caller must close this when done with it.
+	Body io.ReadCloser
+
+	// The HTTP headers returned by the server, in the same form as for
+	// Response.Headers.
+	Headers map[string]string
 }
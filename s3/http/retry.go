@@ -0,0 +1,266 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+)
+
+// Parameters controlling the behavior of a connection returned by
+// NewRetryingConn.
+type RetryPolicy struct {
+	// The maximum number of times a request is sent, including the first.
+	// Values less than one are treated as one.
+	MaxAttempts int
+
+	// The delay before the first retry. The delay doubles with each subsequent
+	// retry, up to MaxBackoff if it is positive. The actual delay is chosen at
+	// random from the upper half of this range.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Return a connection that sends requests using the wrapped connection,
+// retrying those that fail with a server error status or a transient network
+// error according to the supplied policy. Network errors are transient if they
+// are timeouts, or if the connection was refused or reset.
+//
+// Only idempotent (GET, HEAD, PUT, or DELETE) requests are retried, since a
+// request that failed may already have been acted on by the server. Requests
+// whose BodyReader doesn't implement io.Seeker are never retried either, since
+// their bodies can't be sent again.
+//
+// Before each retry, prepare (if non-nil) is called with the request, giving
+// the caller a chance to update its Date header and signature. Retrying stops
+// early if the request's context is done.
+func NewRetryingConn(
+	wrapped Conn,
+	policy RetryPolicy,
+	prepare func(*Request) error) Conn {
+	return newRetryingConn(wrapped, policy, prepare, sleep, rand.Float64)
+}
+
+// A version of NewRetryingConn with the ability to inject dependencies, for
+// testability.
+func newRetryingConn(
+	wrapped Conn,
+	policy RetryPolicy,
+	prepare func(*Request) error,
+	sleep func(context.Context, time.Duration) error,
+	random func() float64) Conn {
+	return &retryingConn{wrapped, policy, prepare, sleep, random}
+}
+
+type retryingConn struct {
+	wrapped Conn
+	policy  RetryPolicy
+	prepare func(*Request) error
+	sleep   func(context.Context, time.Duration) error
+	random  func() float64
+}
+
+// Wait for the given duration, or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Return true if a response with the given status code indicates a problem
+// that may go away if the request is retried.
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case 500, 502, 503, 504:
+		return true
+	}
+
+	return false
+}
+
+// Return true if requests with the given verb may safely be sent more than
+// once.
+func isIdempotent(verb string) bool {
+	switch verb {
+	case "GET", "HEAD", "PUT", "DELETE":
+		return true
+	}
+
+	return false
+}
+
+// Return true if the supplied error returned by a connection indicates a
+// network problem that may go away if the request is retried. Anything other
+// than a timeout or a refused or reset connection, such as a failed TLS
+// handshake or a bad certificate, won't be fixed by trying again.
+func isRetryableError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Return the delay before the given retry, numbered from one.
+func (c *retryingConn) backoff(retry int) time.Duration {
+	d := c.policy.InitialBackoff
+	for i := 1; i < retry; i++ {
+		if c.policy.MaxBackoff > 0 && d >= c.policy.MaxBackoff {
+			break
+		}
+
+		d *= 2
+	}
+
+	if c.policy.MaxBackoff > 0 && d > c.policy.MaxBackoff {
+		d = c.policy.MaxBackoff
+	}
+
+	// Jitter the delay, so that clients that failed together don't retry in
+	// lockstep.
+	return d/2 + time.Duration(c.random()*float64(d/2))
+}
+
+// Call send until it succeeds, fails in a way that isn't worth retrying, or
+// the policy's attempts are exhausted. send returns the status code of the
+// response it received, and discard releases the response when it is to be
+// retried.
+func (c *retryingConn) sendWithRetries(
+	r *Request,
+	send func() (statusCode int, err error),
+	discard func()) (err error) {
+	// A request that isn't idempotent may have been acted on by the server even
+	// though it failed, so it can't safely be sent again.
+	maxAttempts := c.policy.MaxAttempts
+	if !isIdempotent(r.Verb) {
+		maxAttempts = 1
+	}
+
+	// A streaming body must be rewound before it can be sent again.
+	var seeker io.Seeker
+	var bodyStart int64
+
+	if r.BodyReader != nil {
+		var ok bool
+		if seeker, ok = r.BodyReader.(io.Seeker); !ok {
+			maxAttempts = 1
+		} else if bodyStart, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			err = &Error{"Seek", err}
+			return
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		var statusCode int
+		statusCode, err = send()
+
+		var retryable bool
+		if err != nil {
+			retryable = isRetryableError(err)
+		} else {
+			retryable = isRetryableStatus(statusCode)
+		}
+
+		if !retryable || attempt >= maxAttempts {
+			return
+		}
+
+		if err == nil {
+			discard()
+		}
+
+		// Wait, then get the request ready to go again. Give up if the request's
+		// context is done in the meantime.
+		ctx := r.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		if err = c.sleep(ctx, c.backoff(attempt)); err != nil {
+			return
+		}
+
+		if seeker != nil {
+			if _, err = seeker.Seek(bodyStart, io.SeekStart); err != nil {
+				err = &Error{"Seek", err}
+				return
+			}
+		}
+
+		if c.prepare != nil {
+			if err = c.prepare(r); err != nil {
+				err = &Error{"prepare", err}
+				return
+			}
+		}
+	}
+}
+
+func (c *retryingConn) SendRequest(r *Request) (resp *Response, err error) {
+	send := func() (int, error) {
+		var err error
+		if resp, err = c.wrapped.SendRequest(r); err != nil {
+			return 0, err
+		}
+
+		return resp.StatusCode, nil
+	}
+
+	if err = c.sendWithRetries(r, send, func() {}); err != nil {
+		resp = nil
+	}
+
+	return
+}
+
+func (c *retryingConn) StreamRequest(r *Request) (resp *StreamingResponse, err error) {
+	send := func() (int, error) {
+		var err error
+		if resp, err = c.wrapped.StreamRequest(r); err != nil {
+			return 0, err
+		}
+
+		return resp.StatusCode, nil
+	}
+
+	discard := func() { resp.Body.Close() }
+
+	if err = c.sendWithRetries(r, send, discard); err != nil {
+		resp = nil
+	}
+
+	return
+}
@@ -0,0 +1,429 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package http_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/aws/s3/http/mock"
+	. "github.com/jacobsa/oglematchers"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+// A network error that reports itself as a timeout.
+type timeoutError struct{}
+
+func (e timeoutError) Error() string   { return "i/o timeout" }
+func (e timeoutError) Timeout() bool   { return true }
+func (e timeoutError) Temporary() bool { return true }
+
+type RetryingConnTest struct {
+	wrapped  mock_http.MockConn
+	policy   http.RetryPolicy
+	prepared []*http.Request
+	sleeps   []time.Duration
+	random   float64
+	req      *http.Request
+}
+
+func init() { RegisterTestSuite(&RetryingConnTest{}) }
+
+func (t *RetryingConnTest) SetUp(i *TestInfo) {
+	t.wrapped = mock_http.NewMockConn(i.MockController, "wrapped")
+	t.policy = http.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+
+	t.req = &http.Request{Verb: "GET", Path: "/foo", Headers: map[string]string{}}
+}
+
+func (t *RetryingConnTest) prepare(r *http.Request) error {
+	t.prepared = append(t.prepared, r)
+	return nil
+}
+
+func (t *RetryingConnTest) sleep(ctx context.Context, d time.Duration) error {
+	t.sleeps = append(t.sleeps, d)
+	return ctx.Err()
+}
+
+func (t *RetryingConnTest) conn() http.Conn {
+	return http.NewRetryingConnForTesting(
+		t.wrapped,
+		t.policy,
+		t.prepare,
+		t.sleep,
+		func() float64 { return t.random })
+}
+
+func respondWithStatus(statusCode int) oglemock.Action {
+	return oglemock.Return(&http.Response{StatusCode: statusCode}, nil)
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *RetryingConnTest) SuccessIsNotRetried() {
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(respondWithStatus(200))
+
+	// Call
+	resp, err := t.conn().SendRequest(t.req)
+	AssertEq(nil, err)
+
+	ExpectEq(200, resp.StatusCode)
+	ExpectEq(0, len(t.prepared))
+	ExpectEq(0, len(t.sleeps))
+}
+
+func (t *RetryingConnTest) ClientErrorIsNotRetried() {
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(respondWithStatus(404))
+
+	// Call
+	resp, err := t.conn().SendRequest(t.req)
+	AssertEq(nil, err)
+
+	ExpectEq(404, resp.StatusCode)
+	ExpectEq(0, len(t.prepared))
+}
+
+func (t *RetryingConnTest) ServerErrorsAreRetried() {
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(respondWithStatus(503)).
+		WillOnce(respondWithStatus(500)).
+		WillOnce(respondWithStatus(200))
+
+	// Call
+	resp, err := t.conn().SendRequest(t.req)
+	AssertEq(nil, err)
+
+	ExpectEq(200, resp.StatusCode)
+	ExpectThat(t.prepared, ElementsAre(t.req, t.req))
+}
+
+func (t *RetryingConnTest) GivesUpAfterMaxAttempts() {
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		Times(3).
+		WillRepeatedly(respondWithStatus(503))
+
+	// Call
+	resp, err := t.conn().SendRequest(t.req)
+	AssertEq(nil, err)
+
+	ExpectEq(503, resp.StatusCode)
+	ExpectEq(2, len(t.prepared))
+}
+
+func (t *RetryingConnTest) NetworkErrorsAreRetried() {
+	resetErr := &net.OpError{
+		Op:  "read",
+		Net: "tcp",
+		Err: &os.SyscallError{Syscall: "read", Err: syscall.ECONNRESET},
+	}
+
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(oglemock.Return(nil, &http.Error{"http.Client.Do", resetErr})).
+		WillOnce(oglemock.Return(nil, &http.Error{"ioutil.ReadAll", io.ErrUnexpectedEOF})).
+		WillOnce(respondWithStatus(200))
+
+	// Call
+	resp, err := t.conn().SendRequest(t.req)
+	AssertEq(nil, err)
+
+	ExpectEq(200, resp.StatusCode)
+}
+
+func (t *RetryingConnTest) RefusedDialIsRetried() {
+	refusedErr := &url.Error{
+		Op:  "Get",
+		URL: "https://example.com/foo",
+		Err: &net.OpError{
+			Op:  "dial",
+			Net: "tcp",
+			Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED},
+		},
+	}
+
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(oglemock.Return(nil, &http.Error{"http.Client.Do", refusedErr})).
+		WillOnce(respondWithStatus(200))
+
+	// Call
+	resp, err := t.conn().SendRequest(t.req)
+	AssertEq(nil, err)
+
+	ExpectEq(200, resp.StatusCode)
+	ExpectEq(1, len(t.prepared))
+}
+
+func (t *RetryingConnTest) TimeoutIsRetried() {
+	timeoutErr := &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}
+
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(oglemock.Return(nil, &http.Error{"http.Client.Do", timeoutErr})).
+		WillOnce(respondWithStatus(200))
+
+	// Call
+	resp, err := t.conn().SendRequest(t.req)
+	AssertEq(nil, err)
+
+	ExpectEq(200, resp.StatusCode)
+}
+
+func (t *RetryingConnTest) TimeoutIsNotRetriedForPost() {
+	t.req.Verb = "POST"
+	timeoutErr := &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}
+
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(oglemock.Return(nil, &http.Error{"http.Client.Do", timeoutErr}))
+
+	// Call
+	_, err := t.conn().SendRequest(t.req)
+
+	ExpectThat(err, Error(HasSubstr("timeout")))
+	ExpectEq(0, len(t.prepared))
+}
+
+func (t *RetryingConnTest) ServerErrorIsNotRetriedForPost() {
+	t.req.Verb = "POST"
+
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(respondWithStatus(503))
+
+	// Call
+	resp, err := t.conn().SendRequest(t.req)
+	AssertEq(nil, err)
+
+	ExpectEq(503, resp.StatusCode)
+	ExpectEq(0, len(t.prepared))
+}
+
+func (t *RetryingConnTest) ResetIsNotRetriedForPost() {
+	t.req.Verb = "POST"
+	resetErr := &net.OpError{
+		Op:  "read",
+		Net: "tcp",
+		Err: &os.SyscallError{Syscall: "read", Err: syscall.ECONNRESET},
+	}
+
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(oglemock.Return(nil, &http.Error{"http.Client.Do", resetErr}))
+
+	// Call
+	_, err := t.conn().SendRequest(t.req)
+
+	ExpectThat(err, Error(HasSubstr("reset")))
+	ExpectEq(0, len(t.prepared))
+}
+
+func (t *RetryingConnTest) CertificateErrorIsNotRetried() {
+	certErr := &url.Error{
+		Op:  "Get",
+		URL: "https://example.com/foo",
+		Err: x509.UnknownAuthorityError{},
+	}
+
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(oglemock.Return(nil, &http.Error{"http.Client.Do", certErr}))
+
+	// Call
+	_, err := t.conn().SendRequest(t.req)
+
+	ExpectThat(err, Error(HasSubstr("certificate")))
+	ExpectEq(0, len(t.prepared))
+	ExpectEq(0, len(t.sleeps))
+}
+
+func (t *RetryingConnTest) OtherNetworkErrorsAreNotRetried() {
+	netErr := &net.OpError{Op: "remote error", Net: "tcp", Err: errors.New("taco")}
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(oglemock.Return(nil, netErr))
+
+	// Call
+	_, err := t.conn().SendRequest(t.req)
+
+	ExpectThat(err, Error(HasSubstr("taco")))
+	ExpectEq(0, len(t.prepared))
+}
+
+func (t *RetryingConnTest) OtherErrorsAreNotRetried() {
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	_, err := t.conn().SendRequest(t.req)
+
+	ExpectThat(err, Error(Equals("taco")))
+	ExpectEq(0, len(t.prepared))
+}
+
+func (t *RetryingConnTest) FinalErrorIsReturned() {
+	netErr := &net.OpError{
+		Op:  "dial",
+		Net: "tcp",
+		Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED},
+	}
+
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		Times(3).
+		WillRepeatedly(oglemock.Return(nil, netErr))
+
+	// Call
+	resp, err := t.conn().SendRequest(t.req)
+
+	ExpectEq(nil, resp)
+	ExpectThat(err, Error(HasSubstr("refused")))
+}
+
+func (t *RetryingConnTest) PrepareReturnsError() {
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(respondWithStatus(503))
+
+	conn := http.NewRetryingConnForTesting(
+		t.wrapped,
+		t.policy,
+		func(r *http.Request) error { return errors.New("taco") },
+		func(ctx context.Context, d time.Duration) error { return nil },
+		func() float64 { return 0 })
+
+	// Call
+	_, err := conn.SendRequest(t.req)
+
+	ExpectThat(err, Error(HasSubstr("prepare")))
+	ExpectThat(err, Error(HasSubstr("taco")))
+}
+
+func (t *RetryingConnTest) BackoffIsExponentialAndJittered() {
+	t.policy.MaxAttempts = 6
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		Times(6).
+		WillRepeatedly(respondWithStatus(503))
+
+	// Call
+	t.random = 0.5
+	t.conn().SendRequest(t.req)
+
+	ExpectThat(
+		t.sleeps,
+		ElementsAre(
+			75*time.Millisecond,
+			150*time.Millisecond,
+			300*time.Millisecond,
+			600*time.Millisecond,
+			750*time.Millisecond))
+}
+
+func (t *RetryingConnTest) SeekableBodyIsRewound() {
+	t.req.BodyReader = strings.NewReader("taco")
+	t.req.ContentLength = 4
+
+	var bodies []string
+	readBody := func(r *http.Request) (*http.Response, error) {
+		b, err := ioutil.ReadAll(r.BodyReader)
+		AssertEq(nil, err)
+		bodies = append(bodies, string(b))
+		return &http.Response{StatusCode: 500}, nil
+	}
+
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		Times(3).
+		WillRepeatedly(oglemock.Invoke(readBody))
+
+	// Call
+	t.conn().SendRequest(t.req)
+
+	ExpectThat(bodies, ElementsAre("taco", "taco", "taco"))
+}
+
+func (t *RetryingConnTest) UnseekableBodyIsNotRetried() {
+	t.req.BodyReader = io.LimitReader(strings.NewReader("taco"), 4)
+	t.req.ContentLength = 4
+
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(respondWithStatus(503))
+
+	// Call
+	resp, err := t.conn().SendRequest(t.req)
+	AssertEq(nil, err)
+
+	ExpectEq(503, resp.StatusCode)
+}
+
+func (t *RetryingConnTest) StreamRequestClosesDiscardedBodies() {
+	body0 := &closeRecorder{Reader: strings.NewReader("")}
+	body1 := &closeRecorder{Reader: strings.NewReader("")}
+
+	ExpectCall(t.wrapped, "StreamRequest")(t.req).
+		WillOnce(oglemock.Return(&http.StreamingResponse{StatusCode: 503, Body: body0}, nil)).
+		WillOnce(oglemock.Return(&http.StreamingResponse{StatusCode: 200, Body: body1}, nil))
+
+	// Call
+	resp, err := t.conn().StreamRequest(t.req)
+	AssertEq(nil, err)
+
+	ExpectEq(body1, resp.Body)
+	ExpectTrue(body0.closed)
+	ExpectFalse(body1.closed)
+}
+
+func (t *RetryingConnTest) ContextCancelled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.req.Context = ctx
+
+	ExpectCall(t.wrapped, "SendRequest")(t.req).
+		WillOnce(oglemock.Invoke(func(r *http.Request) (*http.Response, error) {
+			cancel()
+			return &http.Response{StatusCode: 503}, nil
+		}))
+
+	// Call
+	_, err := t.conn().SendRequest(t.req)
+
+	ExpectEq(context.Canceled, err)
+	ExpectEq(0, len(t.prepared))
+}
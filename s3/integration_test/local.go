@@ -0,0 +1,37 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package main
+
+import (
+	"github.com/jacobsa/aws"
+	"github.com/jacobsa/aws/s3/s3test"
+)
+
+// Start an in-process server that speaks the S3 protocol, and point the flags
+// at it so that the tests need no S3 account. The caller should close the
+// server when done with it.
+func startLocalServer() *s3test.Server {
+	const bucketName = "integration-test"
+
+	g_accessKey = aws.AccessKey{Id: "local-key-id", Secret: "local-key-secret"}
+	server := s3test.NewServer(g_accessKey, bucketName)
+
+	*g_bucketName = bucketName
+	*g_endpoint = server.URL
+	g_httpClient = server.Client()
+
+	return server
+}
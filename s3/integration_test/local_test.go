@@ -0,0 +1,35 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+package main
+
+import (
+	"github.com/jacobsa/ogletest"
+	"testing"
+)
+
+// Run the integration tests against an in-process server, so that they can be
+// run hermetically with go test. The flags controlling signatures and retries
+// apply as usual.
+func TestLocal(t *testing.T) {
+	if *g_virtualHosted {
+		t.Skip("The local server supports only path-style addressing.")
+	}
+
+	server := startLocalServer()
+	defer server.Close()
+
+	ogletest.RunTests(t)
+}
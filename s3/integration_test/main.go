@@ -22,6 +22,10 @@
 //
 // Before doing this, create an empty bucket (or delete the contents of an
 // existing bucket) using the S3 management console.
+//
+// Alternatively, run the tests against an in-process server that needs no
+// account by passing -local in place of the other flags, or by running
+// go test.
 
 package main
 
@@ -30,6 +34,7 @@ import (
 	"fmt"
 	"github.com/jacobsa/aws"
 	"github.com/jacobsa/ogletest"
+	"net/http"
 	"os"
 	"regexp"
 	"testing"
@@ -42,8 +47,16 @@ import (
 var g_keyId = flag.String("key_id", "", "Access key ID.")
 var g_bucketName = flag.String("bucket", "", "Bucket name.")
 var g_region = flag.String("region", "", "Region endpoint server.")
+var g_endpoint = flag.String("endpoint", "", "Endpoint URL of an S3-compatible service, in place of -region.")
+var g_sigV4 = flag.Bool("sig_v4", false, "Use AWS Signature Version 4.")
+var g_virtualHosted = flag.Bool("virtual_hosted", false, "Use virtual-hosted-style addressing.")
+var g_retry = flag.Bool("retry", false, "Retry requests that fail transiently.")
+var g_local = flag.Bool("local", false, "Use an in-process server in place of S3.")
 var g_accessKey aws.AccessKey
 
+// The HTTP client with which to talk to the server.
+var g_httpClient = http.DefaultClient
+
 ////////////////////////////////////////////////////////////////////////
 // main
 ////////////////////////////////////////////////////////////////////////
@@ -51,29 +64,18 @@ var g_accessKey aws.AccessKey
 func main() {
 	flag.Parse()
 
-	if *g_keyId == "" {
-		fmt.Println("You must set the -key_id flag.")
-		fmt.Println("Find a key ID here:")
-		fmt.Println("    https://portal.aws.amazon.com/gp/aws/securityCredentials")
-		os.Exit(1)
-	}
-
-	if *g_bucketName == "" {
-		fmt.Println("You must set the -bucket flag.")
-		fmt.Println("Manage your buckets here:")
-		fmt.Println("    https://console.aws.amazon.com/s3/")
-		os.Exit(1)
-	}
+	if *g_local {
+		if *g_virtualHosted {
+			fmt.Println("The local server supports only path-style addressing.")
+			os.Exit(1)
+		}
 
-	if *g_region == "" {
-		fmt.Println("You must set the -region flag. See region.go.")
-		os.Exit(1)
+		// The server lives until the process exits.
+		startLocalServer()
+	} else {
+		readFlags()
 	}
 
-	// Read in the access key.
-	g_accessKey.Id = *g_keyId
-	g_accessKey.Secret = readPassword("Access key secret: ")
-
 	// Run the tests.
 	matchString := func(pat, str string) (bool, error) {
 		re, err := regexp.Compile(pat)
@@ -96,3 +98,30 @@ func main() {
 		[]testing.InternalExample{},
 	)
 }
+
+// Check the flags that describe a real S3 account, and read in the access
+// key.
+func readFlags() {
+	if *g_keyId == "" {
+		fmt.Println("You must set the -key_id flag.")
+		fmt.Println("Find a key ID here:")
+		fmt.Println("    https://portal.aws.amazon.com/gp/aws/securityCredentials")
+		os.Exit(1)
+	}
+
+	if *g_bucketName == "" {
+		fmt.Println("You must set the -bucket flag.")
+		fmt.Println("Manage your buckets here:")
+		fmt.Println("    https://console.aws.amazon.com/s3/")
+		os.Exit(1)
+	}
+
+	if *g_region == "" && *g_endpoint == "" {
+		fmt.Println("You must set the -region or -endpoint flag. See region.go.")
+		os.Exit(1)
+	}
+
+	// Read in the access key.
+	g_accessKey.Id = *g_keyId
+	g_accessKey.Secret = readPassword("Access key secret: ")
+}
@@ -16,12 +16,21 @@
 package main
 
 import (
+	"bytes"
+	"crypto/md5"
 	"fmt"
+	"github.com/jacobsa/aws"
 	"github.com/jacobsa/aws/s3"
+	"github.com/jacobsa/aws/s3/s3util"
 	. "github.com/jacobsa/oglematchers"
 	. "github.com/jacobsa/ogletest"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"strings"
 	"sync"
+	"time"
 )
 
 ////////////////////////////////////////////////////////////////////////
@@ -98,11 +107,58 @@ func (t *BucketTest) ensureDeleted(key string) {
 	t.keysToDelete = append(t.keysToDelete, key)
 }
 
+// Return the options with which buckets should be opened, according to flags.
+func bucketOptions() (opts []s3.Option) {
+	if *g_sigV4 {
+		opts = append(opts, s3.WithSignatureV4())
+	}
+
+	if *g_virtualHosted {
+		opts = append(opts, s3.WithAddressingStyle(s3.VirtualHostedStyle))
+	}
+
+	if *g_retry {
+		opts = append(opts, s3.WithRetryPolicy(s3.DefaultRetryPolicy))
+	}
+
+	opts = append(opts, s3.WithHttpClient(g_httpClient))
+
+	return
+}
+
+// Open the bucket named by the flags, using the supplied key.
+func openBucket(key aws.AccessKey) (s3.Bucket, error) {
+	if *g_endpoint == "" {
+		return s3.OpenBucket(
+			*g_bucketName,
+			s3.Region(*g_region),
+			key,
+			bucketOptions()...)
+	}
+
+	endpoint, err := url.Parse(*g_endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("url.Parse: %v", err)
+	}
+
+	style := s3.PathStyle
+	if *g_virtualHosted {
+		style = s3.VirtualHostedStyle
+	}
+
+	return s3.OpenBucketAtEndpoint(
+		*g_bucketName,
+		endpoint,
+		style,
+		key,
+		bucketOptions()...)
+}
+
 func (t *BucketTest) SetUp(i *TestInfo) {
 	var err error
 
 	// Open a bucket.
-	t.bucket, err = s3.OpenBucket(*g_bucketName, s3.Region(*g_region), g_accessKey)
+	t.bucket, err = openBucket(g_accessKey)
 	AssertEq(nil, err)
 }
 
@@ -131,7 +187,7 @@ func (t *BucketTest) WrongAccessKeySecret() {
 	wrongKey := g_accessKey
 	wrongKey.Secret += "taco"
 
-	bucket, err := s3.OpenBucket(*g_bucketName, s3.Region(*g_region), wrongKey)
+	bucket, err := openBucket(wrongKey)
 	AssertEq(nil, err)
 
 	// Attempt to do something.
@@ -254,6 +310,7 @@ func (t *BucketTest) GetNonExistentObject() {
 	ExpectThat(err, Error(HasSubstr("404")))
 	ExpectThat(err, Error(HasSubstr("some_key")))
 	ExpectThat(err, Error(HasSubstr("exist")))
+	ExpectTrue(s3.IsNotFound(err))
 }
 
 func (t *BucketTest) StoreThenGetEmptyObject() {
@@ -288,6 +345,424 @@ func (t *BucketTest) StoreThenGetNonEmptyObject() {
 	ExpectThat(returnedData, DeepEquals(data))
 }
 
+func (t *BucketTest) StoreThenStreamObject() {
+	key := "some_key"
+	t.ensureDeleted(key)
+
+	data := []byte{0x17, 0x19, 0x00, 0x02, 0x03}
+
+	// Store
+	err := t.bucket.StoreObject(key, data)
+	AssertEq(nil, err)
+
+	// Get
+	r, size, err := t.bucket.GetObjectReader(key)
+	AssertEq(nil, err)
+	defer r.Close()
+
+	ExpectEq(len(data), size)
+
+	returnedData, err := ioutil.ReadAll(r)
+	AssertEq(nil, err)
+	ExpectThat(returnedData, DeepEquals(data))
+}
+
+func (t *BucketTest) StoreFromReaderThenGetObject() {
+	key := "some_key"
+	t.ensureDeleted(key)
+
+	data := []byte{0x17, 0x19, 0x00, 0x02, 0x03}
+
+	// Store, hiding the reader's Seek method so that the data is buffered.
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write(data)
+		pw.Close()
+	}()
+
+	err := t.bucket.StoreObjectFromReader(key, pr, -1)
+	AssertEq(nil, err)
+
+	// Get
+	returnedData, err := t.bucket.GetObject(key)
+	AssertEq(nil, err)
+	ExpectThat(returnedData, DeepEquals(data))
+}
+
+func (t *BucketTest) MultipartUpload() {
+	key := "some_key"
+	t.ensureDeleted(key)
+
+	// Create enough data for a few parts, the last of which is short.
+	data := make([]byte, 2*s3util.MinPartSize+17)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// Upload
+	uploader := s3util.Uploader{
+		Bucket:   t.bucket,
+		PartSize: s3util.MinPartSize,
+	}
+
+	err := uploader.Upload(key, bytes.NewReader(data))
+	AssertEq(nil, err)
+
+	// Get
+	returnedData, err := t.bucket.GetObject(key)
+	AssertEq(nil, err)
+	ExpectThat(returnedData, DeepEquals(data))
+}
+
+func (t *BucketTest) AbortMultipartUpload() {
+	key := "some_key"
+
+	// Begin an upload and store a part.
+	uploadId, err := t.bucket.InitiateMultipartUpload(key)
+	AssertEq(nil, err)
+
+	_, err = t.bucket.UploadPart(key, uploadId, 1, bytes.NewReader([]byte("taco")), 4)
+	AssertEq(nil, err)
+
+	// Abort
+	err = t.bucket.AbortMultipartUpload(key, uploadId)
+	AssertEq(nil, err)
+
+	// The object should not exist.
+	_, err = t.bucket.GetObject(key)
+	ExpectThat(err, Error(HasSubstr("404")))
+}
+
+func (t *BucketTest) GetObjectRange() {
+	key := "some_key"
+	t.ensureDeleted(key)
+
+	// Store
+	err := t.bucket.StoreObject(key, []byte("tacoburrito"))
+	AssertEq(nil, err)
+
+	// Middle of the object.
+	data, size, err := t.bucket.GetObjectRange(key, 4, 3)
+	AssertEq(nil, err)
+	ExpectEq("bur", string(data))
+	ExpectEq(11, size)
+
+	// Suffix.
+	data, size, err = t.bucket.GetObjectRange(key, -4, -1)
+	AssertEq(nil, err)
+	ExpectEq("rito", string(data))
+	ExpectEq(11, size)
+
+	// Beyond the end.
+	_, _, err = t.bucket.GetObjectRange(key, 11, -1)
+	rangeErr, ok := err.(*s3.RangeNotSatisfiableError)
+	AssertTrue(ok, "%v", err)
+	ExpectEq(11, rangeErr.ObjectSize)
+}
+
+func (t *BucketTest) StatNonExistentObject() {
+	_, err := t.bucket.StatObject("some_key")
+
+	AssertNe(nil, err)
+	ExpectTrue(s3.IsNotFound(err), "%v", err)
+}
+
+func (t *BucketTest) StoreThenStatObject() {
+	key := "some_key"
+	t.ensureDeleted(key)
+
+	data := []byte("taco")
+
+	// Store
+	err := t.bucket.StoreObject(key, data)
+	AssertEq(nil, err)
+
+	// Stat
+	info, err := t.bucket.StatObject(key)
+	AssertEq(nil, err)
+
+	ExpectEq(key, info.Key)
+	ExpectEq(len(data), info.Size)
+	ExpectThat(info.ETag, HasSubstr(fmt.Sprintf("%x", md5.Sum(data))))
+	ExpectFalse(info.LastModified.IsZero())
+}
+
+func (t *BucketTest) StoreWithOptionsThenStat() {
+	key := "some_key"
+	t.ensureDeleted(key)
+
+	// Store
+	opts := &s3.StoreOptions{
+		ContentType:  "text/plain",
+		CacheControl: "max-age=60",
+		StorageClass: s3.StorageClassReducedRedundancy,
+		Metadata: map[string]string{
+			"flavor": "taco",
+		},
+	}
+
+	result, err := t.bucket.StoreObjectWithOptions(
+		key,
+		strings.NewReader("burrito"),
+		-1,
+		opts)
+
+	AssertEq(nil, err)
+
+	// Stat
+	info, err := t.bucket.StatObject(key)
+	AssertEq(nil, err)
+
+	ExpectEq(result.ETag, info.ETag)
+	ExpectEq(len("burrito"), info.Size)
+	ExpectEq("text/plain", info.ContentType)
+	ExpectEq("taco", info.Metadata["flavor"])
+}
+
+func (t *BucketTest) ServerSideEncryption() {
+	key := "some_key"
+	t.ensureDeleted(key)
+
+	// Store
+	opts := &s3.StoreOptions{ServerSideEncryption: s3.SseS3}
+	_, err := t.bucket.StoreObjectWithOptions(
+		key,
+		strings.NewReader("burrito"),
+		-1,
+		opts)
+
+	AssertEq(nil, err)
+
+	// Stat
+	info, err := t.bucket.StatObject(key)
+	AssertEq(nil, err)
+	ExpectEq(s3.SseS3, info.ServerSideEncryption)
+}
+
+func (t *BucketTest) CustomerProvidedKey() {
+	key := "some_key"
+	t.ensureDeleted(key)
+
+	customerKey := []byte("0123456789abcdef0123456789abcdef")
+
+	// Store
+	opts := &s3.StoreOptions{CustomerKey: customerKey}
+	_, err := t.bucket.StoreObjectWithOptions(
+		key,
+		strings.NewReader("burrito"),
+		-1,
+		opts)
+
+	AssertEq(nil, err)
+
+	// Get with the key
+	r, _, err := t.bucket.GetObjectWithOptions(key, &s3.GetOptions{CustomerKey: customerKey})
+	AssertEq(nil, err)
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	AssertEq(nil, err)
+	ExpectEq("burrito", string(data))
+
+	// Stat with the key
+	info, err := t.bucket.StatObjectWithOptions(key, &s3.StatOptions{CustomerKey: customerKey})
+	AssertEq(nil, err)
+	ExpectEq(len("burrito"), info.Size)
+
+	// Get a range with the key
+	data, size, err := t.bucket.GetObjectRangeWithOptions(
+		key,
+		1,
+		3,
+		&s3.RangeOptions{CustomerKey: customerKey})
+
+	AssertEq(nil, err)
+	ExpectEq("urr", string(data))
+	ExpectEq(len("burrito"), size)
+
+	// Get without it
+	_, err = t.bucket.GetObject(key)
+	ExpectThat(err, Error(HasSubstr("400")))
+
+	_, err = t.bucket.StatObject(key)
+	ExpectThat(err, Error(HasSubstr("400")))
+
+	_, _, err = t.bucket.GetObjectRange(key, 1, 3)
+	ExpectThat(err, Error(HasSubstr("400")))
+}
+
+func (t *BucketTest) CompressedObject() {
+	key := "some_key"
+	t.ensureDeleted(key)
+
+	contents := strings.Repeat("burrito", 1000)
+	compressing := s3util.NewCompressingBucket(t.bucket, s3util.Gzip)
+
+	// Store
+	err := compressing.StoreObject(key, []byte(contents))
+	AssertEq(nil, err)
+
+	// The data must come back from the server still compressed, rather than
+	// being decompressed on the way by the HTTP library.
+	data, err := t.bucket.GetObject(key)
+	AssertEq(nil, err)
+	ExpectLt(len(data), len(contents)/10)
+
+	// Get
+	data, err = compressing.GetObject(key)
+	AssertEq(nil, err)
+	ExpectEq(contents, string(data))
+}
+
+func (t *BucketTest) ConditionalGetAndStore() {
+	key := "some_key"
+	t.ensureDeleted(key)
+
+	// Store, requiring that the object not already exist.
+	result, err := t.bucket.StoreObjectWithOptions(
+		key,
+		strings.NewReader("taco"),
+		-1,
+		&s3.StoreOptions{IfNoneMatch: "*"})
+
+	AssertEq(nil, err)
+
+	// A read conditional on the entity tag differing should fail.
+	_, _, err = t.bucket.GetObjectWithOptions(
+		key,
+		&s3.GetOptions{IfNoneMatch: result.ETag})
+
+	_, ok := err.(*s3.NotModifiedError)
+	ExpectTrue(ok, "%v", err)
+
+	// A read conditional on a different tag matching should fail.
+	_, _, err = t.bucket.GetObjectWithOptions(
+		key,
+		&s3.GetOptions{IfMatch: `"deadbeef"`})
+
+	_, ok = err.(*s3.PreconditionFailedError)
+	ExpectTrue(ok, "%v", err)
+
+	// A read conditional on the tag matching should succeed.
+	r, info, err := t.bucket.GetObjectWithOptions(
+		key,
+		&s3.GetOptions{IfMatch: result.ETag})
+
+	AssertEq(nil, err)
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+	ExpectEq(result.ETag, info.ETag)
+
+	// A write conditional on a stale tag should fail.
+	_, err = t.bucket.StoreObjectWithOptions(
+		key,
+		strings.NewReader("burrito"),
+		-1,
+		&s3.StoreOptions{IfMatch: `"deadbeef"`})
+
+	_, ok = err.(*s3.PreconditionFailedError)
+	ExpectTrue(ok, "%v", err)
+
+	// A write conditional on the current tag should succeed.
+	_, err = t.bucket.StoreObjectWithOptions(
+		key,
+		strings.NewReader("burrito"),
+		-1,
+		&s3.StoreOptions{IfMatch: result.ETag})
+
+	AssertEq(nil, err)
+}
+
+func (t *BucketTest) CopyObject() {
+	src := "some_key"
+	dst := "other_key"
+	t.ensureDeleted(src)
+	t.ensureDeleted(dst)
+
+	// Store
+	opts := &s3.StoreOptions{
+		ContentType: "text/plain",
+		Metadata:    map[string]string{"flavor": "taco"},
+	}
+
+	stored, err := t.bucket.StoreObjectWithOptions(
+		src,
+		strings.NewReader("burrito"),
+		-1,
+		opts)
+
+	AssertEq(nil, err)
+
+	// Copy, keeping the metadata.
+	copied, err := t.bucket.CopyObject(src, dst, nil)
+	AssertEq(nil, err)
+	ExpectEq(stored.ETag, copied.ETag)
+
+	data, err := t.bucket.GetObject(dst)
+	AssertEq(nil, err)
+	ExpectEq("burrito", string(data))
+
+	info, err := t.bucket.StatObject(dst)
+	AssertEq(nil, err)
+	ExpectEq("text/plain", info.ContentType)
+	ExpectEq("taco", info.Metadata["flavor"])
+
+	// Copy again, replacing the metadata.
+	copyOpts := &s3.CopyOptions{
+		MetadataDirective: s3.MetadataReplace,
+		Metadata:          map[string]string{"flavor": "enchilada"},
+	}
+
+	_, err = t.bucket.CopyObject(src, dst, copyOpts)
+	AssertEq(nil, err)
+
+	info, err = t.bucket.StatObject(dst)
+	AssertEq(nil, err)
+	ExpectEq("enchilada", info.Metadata["flavor"])
+}
+
+func (t *BucketTest) PresignedUrls() {
+	key := "some_key"
+	t.ensureDeleted(key)
+
+	for _, v4 := range []bool{false, true} {
+		// Upload using a presigned PUT URL.
+		putUrl, err := t.bucket.PresignUrl(
+			key,
+			&s3.PresignOptions{Verb: "PUT", Expiry: time.Minute, UseSignatureV4: v4})
+
+		AssertEq(nil, err)
+
+		req, err := http.NewRequest("PUT", putUrl, strings.NewReader("taco"))
+		AssertEq(nil, err)
+
+		resp, err := g_httpClient.Do(req)
+		AssertEq(nil, err)
+		resp.Body.Close()
+		AssertEq(200, resp.StatusCode, "v4: %v", v4)
+
+		// Download using a presigned GET URL.
+		getUrl, err := t.bucket.PresignUrl(
+			key,
+			&s3.PresignOptions{Expiry: time.Minute, UseSignatureV4: v4})
+
+		AssertEq(nil, err)
+
+		resp, err = g_httpClient.Get(getUrl)
+		AssertEq(nil, err)
+
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		AssertEq(nil, err)
+		AssertEq(200, resp.StatusCode, "v4: %v, body: %s", v4, data)
+		ExpectEq("taco", string(data))
+	}
+}
+
 func (t *BucketTest) OverwriteObject() {
 	key := "some_key"
 	t.ensureDeleted(key)
@@ -454,6 +929,106 @@ func (t *BucketTest) ListManyKeys() {
 	ExpectThat(keysListed, DeepEquals(allKeys))
 }
 
+func (t *BucketTest) ListWithPrefixAndDelimiter() {
+	var err error
+
+	// Create some keys that look like a directory hierarchy.
+	toCreate := []string{
+		"dir/a",
+		"dir/b",
+		"dir/sub/c",
+		"dir/sub/d",
+		"dir/zzz/e",
+		"other",
+	}
+
+	err = runForRange(len(toCreate), func(i int) error {
+		key := toCreate[i]
+		t.ensureDeleted(key)
+		return t.bucket.StoreObject(key, []byte{})
+	})
+
+	AssertEq(nil, err)
+
+	// List the "directory".
+	result, err := t.bucket.ListObjects(
+		s3.ListRequest{Prefix: "dir/", Delimiter: "/"})
+
+	AssertEq(nil, err)
+	ExpectThat(result.Keys, ElementsAre("dir/a", "dir/b"))
+	ExpectThat(result.CommonPrefixes, ElementsAre("dir/sub/", "dir/zzz/"))
+	ExpectFalse(result.IsTruncated)
+
+	// Page through it one entry at a time.
+	var entries []string
+	req := s3.ListRequest{Prefix: "dir/", Delimiter: "/", MaxKeys: 1}
+	for {
+		result, err = t.bucket.ListObjects(req)
+		AssertEq(nil, err)
+
+		entries = append(entries, result.Keys...)
+		entries = append(entries, result.CommonPrefixes...)
+
+		if !result.IsTruncated {
+			break
+		}
+
+		req.Marker = result.NextMarker
+	}
+
+	ExpectThat(entries, ElementsAre("dir/a", "dir/b", "dir/sub/", "dir/zzz/"))
+
+	// List everything under the prefix.
+	keys, err := s3util.ListAllKeysWithPrefix(t.bucket, "dir/sub/")
+	AssertEq(nil, err)
+	ExpectThat(keys, ElementsAre("dir/sub/c", "dir/sub/d"))
+}
+
+func (t *BucketTest) DeleteObjects() {
+	var err error
+
+	toCreate := []string{"a", "b", "c"}
+	err = runForRange(len(toCreate), func(i int) error {
+		key := toCreate[i]
+		t.ensureDeleted(key)
+		return t.bucket.StoreObject(key, []byte{})
+	})
+
+	AssertEq(nil, err)
+
+	// Delete two of them, plus one that doesn't exist.
+	result, err := t.bucket.DeleteObjects([]string{"a", "c", "d"}, false)
+	AssertEq(nil, err)
+
+	ExpectThat(result.Deleted, ElementsAre("a", "c", "d"))
+	ExpectThat(result.Errors, ElementsAre())
+
+	keys, err := t.bucket.ListKeys("")
+	AssertEq(nil, err)
+	ExpectThat(keys, ElementsAre("b"))
+}
+
+func (t *BucketTest) DeleteAllKeysWithPrefix() {
+	var err error
+
+	toCreate := []string{"dir/a", "dir/b", "dir/sub/c", "other"}
+	err = runForRange(len(toCreate), func(i int) error {
+		key := toCreate[i]
+		t.ensureDeleted(key)
+		return t.bucket.StoreObject(key, []byte{})
+	})
+
+	AssertEq(nil, err)
+
+	// Delete
+	err = s3util.DeleteAllKeysWithPrefix(t.bucket, "dir/")
+	AssertEq(nil, err)
+
+	keys, err := t.bucket.ListKeys("")
+	AssertEq(nil, err)
+	ExpectThat(keys, ElementsAre("other"))
+}
+
 func (t *BucketTest) KeyContainingKorean() {
 	var keys []string
 	var err error
@@ -0,0 +1,115 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validate checks the arguments to Bucket methods, so that the bucket
+// returned by s3.OpenBucket and the fake in package fake reject the same
+// arguments.
+package validate
+
+import (
+	"fmt"
+	"time"
+)
+
+// The length in bytes of the keys used for encryption with customer-provided
+// keys (SSE-C), which is AES-256. Exported as s3.CustomerKeyLength.
+const CustomerKeyLength = 32
+
+// CustomerKey returns an error if the supplied customer-provided key is
+// non-nil but not CustomerKeyLength bytes long.
+func CustomerKey(key []byte) error {
+	if key != nil && len(key) != CustomerKeyLength {
+		return fmt.Errorf(
+			"Customer keys must be %d bytes long; got %d.",
+			CustomerKeyLength,
+			len(key))
+	}
+
+	return nil
+}
+
+// Encryption returns an error if the supplied encryption settings, which are
+// as documented for the fields of s3.StoreOptions, are illegal or can't be
+// combined.
+func Encryption(sse string, kmsKeyId string, customerKey []byte) error {
+	// The values of s3.SseS3 and s3.SseKms.
+	switch sse {
+	case "", "AES256", "aws:kms":
+	default:
+		return fmt.Errorf("Invalid server-side encryption: %q", sse)
+	}
+
+	if kmsKeyId != "" && sse != "aws:kms" {
+		return fmt.Errorf("A KMS key ID may be set only with SseKms.")
+	}
+
+	if customerKey != nil && sse != "" {
+		return fmt.Errorf("A customer key may not be combined with %s.", sse)
+	}
+
+	return CustomerKey(customerKey)
+}
+
+func isMetadataNameChar(c byte) bool {
+	return ('a' <= c && c <= 'z') ||
+		('A' <= c && c <= 'Z') ||
+		('0' <= c && c <= '9') ||
+		c == '-' ||
+		c == '_'
+}
+
+// Metadata returns an error if the supplied user-defined metadata is not
+// legal, as described in the documentation for s3.StoreOptions.Metadata.
+func Metadata(metadata map[string]string) error {
+	for name, val := range metadata {
+		if name == "" {
+			return fmt.Errorf("Metadata names must be non-empty.")
+		}
+
+		for i := 0; i < len(name); i++ {
+			if !isMetadataNameChar(name[i]) {
+				return fmt.Errorf("Invalid metadata name: %q", name)
+			}
+		}
+
+		for i := 0; i < len(val); i++ {
+			if val[i] < 0x20 || val[i] > 0x7e {
+				return fmt.Errorf("Invalid value for metadata %s: %q", name, val)
+			}
+		}
+	}
+
+	return nil
+}
+
+// PresignOptions returns an error if the supplied fields of an
+// s3.PresignOptions struct are not legal, as described in its documentation.
+func PresignOptions(verb string, expiry time.Duration, contentType string) error {
+	switch verb {
+	case "", "GET", "PUT":
+	default:
+		return fmt.Errorf("Unsupported verb: %s", verb)
+	}
+
+	if expiry <= 0 {
+		return fmt.Errorf("Expiry must be positive.")
+	}
+
+	if contentType != "" && verb != "PUT" {
+		return fmt.Errorf("Content type may be specified only for PUT.")
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/jacobsa/aws/s3/http"
+	"strconv"
+	sys_time "time"
+)
+
+// A request to list the contents of a bucket. See Bucket.ListObjects.
+type ListRequest struct {
+	// If non-empty, only keys beginning with this prefix are returned.
+	Prefix string
+
+	// If non-empty, keys that contain the delimiter after the prefix are not
+	// returned individually. Instead they are rolled up into a single common
+	// prefix that extends up to and including the first occurrence of the
+	// delimiter. For example, with a prefix of "foo/" and a delimiter of "/",
+	// the keys "foo/bar/baz" and "foo/bar/qux" are returned as the single
+	// common prefix "foo/bar/".
+	Delimiter string
+
+	// If non-empty, only keys and common prefixes strictly greater than this
+	// are returned. It must be a valid key.
+	Marker string
+
+	// The maximum number of keys plus common prefixes to return. If zero, the
+	// server's default (1000 for S3) is used.
+	MaxKeys int
+}
+
+// The result of a request to list the contents of a bucket. See
+// Bucket.ListObjects.
+type ListResult struct {
+	// The keys matching the request, in order.
+	Keys []string
+
+	// The common prefixes that matching keys were rolled up into, in order.
+	// Empty unless a delimiter was specified.
+	CommonPrefixes []string
+
+	// Whether there are further keys or common prefixes matching the request.
+	IsTruncated bool
+
+	// If IsTruncated is true, the marker to use to obtain the next batch of
+	// results.
+	NextMarker string
+}
+
+type bucketContents struct {
+	Key string
+}
+
+type bucketCommonPrefixes struct {
+	Prefix string
+}
+
+type listBucketResult struct {
+	XMLName        xml.Name
+	Contents       []bucketContents
+	CommonPrefixes []bucketCommonPrefixes
+	IsTruncated    bool
+	NextMarker     string
+}
+
+func (b *bucket) ListObjects(req ListRequest) (result *ListResult, err error) {
+	// Make sure the marker is empty or valid.
+	if err := ValidateKey(req.Marker); err != nil && req.Marker != "" {
+		return nil, err
+	}
+
+	// The prefix and delimiter are subject to the same constraints as keys, for
+	// the same reasons.
+	if err := ValidateKey(req.Prefix); err != nil && req.Prefix != "" {
+		return nil, fmt.Errorf("Invalid prefix: %v", err)
+	}
+
+	if err := ValidateKey(req.Delimiter); err != nil && req.Delimiter != "" {
+		return nil, fmt.Errorf("Invalid delimiter: %v", err)
+	}
+
+	if req.MaxKeys < 0 {
+		return nil, fmt.Errorf("Invalid max keys: %d", req.MaxKeys)
+	}
+
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.amazonwebservices.com/AmazonS3/latest/API/RESTBucketGET.html
+	httpReq := &http.Request{
+		Verb:   "GET",
+		Path:   b.bucketPath(),
+		Bucket: b.hostBucket(),
+		Headers: map[string]string{
+			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+		Parameters: map[string]string{},
+	}
+
+	if req.Marker != "" {
+		httpReq.Parameters["marker"] = req.Marker
+	}
+
+	if req.Prefix != "" {
+		httpReq.Parameters["prefix"] = req.Prefix
+	}
+
+	if req.Delimiter != "" {
+		httpReq.Parameters["delimiter"] = req.Delimiter
+	}
+
+	if req.MaxKeys != 0 {
+		httpReq.Parameters["max-keys"] = strconv.Itoa(req.MaxKeys)
+	}
+
+	// Sign the request.
+	if err := b.signer.Sign(httpReq); err != nil {
+		return nil, fmt.Errorf("Sign: %v", err)
+	}
+
+	// Send the request.
+	httpResp, err := b.httpConn.SendRequest(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("SendRequest: %v", err)
+	}
+
+	// Check the response.
+	if httpResp.StatusCode != 200 {
+		return nil, newError(httpResp.StatusCode, httpResp.Body)
+	}
+
+	// Attempt to parse the body.
+	parsed := listBucketResult{}
+	if err := xml.Unmarshal(httpResp.Body, &parsed); err != nil {
+		return nil, fmt.Errorf(
+			"Invalid data from server (%s): %s",
+			err.Error(),
+			httpResp.Body)
+	}
+
+	// Make sure the server agress with us about the interpretation of the
+	// request.
+	if parsed.XMLName.Local != "ListBucketResult" {
+		return nil, fmt.Errorf("Invalid data from server: %s", httpResp.Body)
+	}
+
+	result = &ListResult{
+		Keys:           make([]string, len(parsed.Contents)),
+		CommonPrefixes: make([]string, len(parsed.CommonPrefixes)),
+		IsTruncated:    parsed.IsTruncated,
+	}
+
+	for i, elem := range parsed.Contents {
+		result.Keys[i] = elem.Key
+	}
+
+	for i, elem := range parsed.CommonPrefixes {
+		result.CommonPrefixes[i] = elem.Prefix
+	}
+
+	// S3 only returns a next marker when a delimiter is in use. Otherwise the
+	// greatest key or common prefix returned serves the same purpose.
+	if result.IsTruncated {
+		result.NextMarker = parsed.NextMarker
+		if result.NextMarker == "" {
+			if n := len(result.Keys); n > 0 {
+				result.NextMarker = result.Keys[n-1]
+			}
+
+			if n := len(result.CommonPrefixes); n > 0 && result.CommonPrefixes[n-1] > result.NextMarker {
+				result.NextMarker = result.CommonPrefixes[n-1]
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,243 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"testing"
+)
+
+func TestList(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type ListObjectsTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&ListObjectsTest{}) }
+
+// Call ListObjects with the supplied request, returning the HTTP request
+// passed to the signer.
+func (t *ListObjectsTest) captureRequest(req ListRequest) *http.Request {
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	t.bucket.ListObjects(req)
+	return httpReq
+}
+
+// Set up the signer and conn to return a 200 response with the supplied body.
+func (t *ListObjectsTest) respondWith(body string) {
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	resp := &http.Response{
+		StatusCode: 200,
+		Body:       []byte(body),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *ListObjectsTest) MarkerIsInvalid() {
+	// Call
+	_, err := t.bucket.ListObjects(ListRequest{Marker: "taco\x00burrito"})
+
+	ExpectThat(err, ErrorThat(HasSubstr("U+0000")))
+}
+
+func (t *ListObjectsTest) PrefixIsInvalid() {
+	// Call
+	_, err := t.bucket.ListObjects(ListRequest{Prefix: "\x80\x81\x82"})
+
+	ExpectThat(err, ErrorThat(HasSubstr("prefix")))
+	ExpectThat(err, ErrorThat(HasSubstr("UTF-8")))
+}
+
+func (t *ListObjectsTest) DelimiterIsInvalid() {
+	// Call
+	_, err := t.bucket.ListObjects(ListRequest{Delimiter: "\uFFFE"})
+
+	ExpectThat(err, ErrorThat(HasSubstr("delimiter")))
+	ExpectThat(err, ErrorThat(HasSubstr("U+FFFE")))
+}
+
+func (t *ListObjectsTest) MaxKeysIsNegative() {
+	// Call
+	_, err := t.bucket.ListObjects(ListRequest{MaxKeys: -1})
+
+	ExpectThat(err, ErrorThat(HasSubstr("max keys")))
+	ExpectThat(err, ErrorThat(HasSubstr("-1")))
+}
+
+func (t *ListObjectsTest) EmptyRequest() {
+	httpReq := t.captureRequest(ListRequest{})
+
+	AssertNe(nil, httpReq)
+	ExpectEq("GET", httpReq.Verb)
+	ExpectEq("/some.bucket", httpReq.Path)
+	ExpectEq(0, len(httpReq.Parameters), "%v", httpReq.Parameters)
+}
+
+func (t *ListObjectsTest) FullRequest() {
+	req := ListRequest{
+		Prefix:    "foo/",
+		Delimiter: "/",
+		Marker:    "foo/bar",
+		MaxKeys:   17,
+	}
+
+	httpReq := t.captureRequest(req)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("GET", httpReq.Verb)
+	ExpectEq("/some.bucket", httpReq.Path)
+	ExpectEq("foo/", httpReq.Parameters["prefix"])
+	ExpectEq("/", httpReq.Parameters["delimiter"])
+	ExpectEq("foo/bar", httpReq.Parameters["marker"])
+	ExpectEq("17", httpReq.Parameters["max-keys"])
+}
+
+func (t *ListObjectsTest) ServerReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 500,
+		Body:       []byte("taco"),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	_, err := t.bucket.ListObjects(ListRequest{})
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("500")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *ListObjectsTest) NotTruncated() {
+	t.respondWith(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+			<IsTruncated>false</IsTruncated>
+			<Contents>
+				<Key>bar</Key>
+			</Contents>
+			<Contents>
+				<Key>foo</Key>
+			</Contents>
+		</ListBucketResult>`)
+
+	// Call
+	result, err := t.bucket.ListObjects(ListRequest{})
+	AssertEq(nil, err)
+
+	ExpectThat(result.Keys, ElementsAre("bar", "foo"))
+	ExpectThat(result.CommonPrefixes, ElementsAre())
+	ExpectFalse(result.IsTruncated)
+	ExpectEq("", result.NextMarker)
+}
+
+func (t *ListObjectsTest) CommonPrefixesWithNextMarker() {
+	t.respondWith(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+			<Prefix>foo/</Prefix>
+			<Delimiter>/</Delimiter>
+			<IsTruncated>true</IsTruncated>
+			<NextMarker>foo/qux/</NextMarker>
+			<Contents>
+				<Key>foo/a</Key>
+			</Contents>
+			<CommonPrefixes>
+				<Prefix>foo/bar/</Prefix>
+			</CommonPrefixes>
+			<CommonPrefixes>
+				<Prefix>foo/qux/</Prefix>
+			</CommonPrefixes>
+		</ListBucketResult>`)
+
+	// Call
+	result, err := t.bucket.ListObjects(ListRequest{Prefix: "foo/", Delimiter: "/"})
+	AssertEq(nil, err)
+
+	ExpectThat(result.Keys, ElementsAre("foo/a"))
+	ExpectThat(result.CommonPrefixes, ElementsAre("foo/bar/", "foo/qux/"))
+	ExpectTrue(result.IsTruncated)
+	ExpectEq("foo/qux/", result.NextMarker)
+}
+
+func (t *ListObjectsTest) TruncatedWithoutNextMarker() {
+	t.respondWith(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+			<IsTruncated>true</IsTruncated>
+			<Contents>
+				<Key>bar</Key>
+			</Contents>
+			<Contents>
+				<Key>foo</Key>
+			</Contents>
+		</ListBucketResult>`)
+
+	// Call
+	result, err := t.bucket.ListObjects(ListRequest{})
+	AssertEq(nil, err)
+
+	ExpectTrue(result.IsTruncated)
+	ExpectEq("foo", result.NextMarker)
+}
+
+func (t *ListObjectsTest) TruncatedWithCommonPrefixLast() {
+	t.respondWith(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+			<IsTruncated>true</IsTruncated>
+			<Contents>
+				<Key>a</Key>
+			</Contents>
+			<CommonPrefixes>
+				<Prefix>b/</Prefix>
+			</CommonPrefixes>
+		</ListBucketResult>`)
+
+	// Call
+	result, err := t.bucket.ListObjects(ListRequest{Delimiter: "/"})
+	AssertEq(nil, err)
+
+	ExpectTrue(result.IsTruncated)
+	ExpectEq("b/", result.NextMarker)
+}
@@ -0,0 +1,31 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"github.com/jacobsa/oglematchers"
+)
+
+// The tests in this package can't dot-import oglematchers, whose Error
+// matcher would collide with the Error type. These stand in for the matchers
+// they use.
+var (
+	Any         = oglematchers.Any
+	DeepEquals  = oglematchers.DeepEquals
+	ElementsAre = oglematchers.ElementsAre
+	ErrorThat   = oglematchers.Error
+	HasSubstr   = oglematchers.HasSubstr
+)
@@ -7,9 +7,11 @@
 package mock_s3
 
 import (
+	context "context"
 	fmt "fmt"
 	s3 "github.com/jacobsa/aws/s3"
 	oglemock "github.com/jacobsa/oglemock"
+	io "io"
 	runtime "runtime"
 	unsafe "unsafe"
 )
@@ -41,6 +43,83 @@ func (m *mockBucket) Oglemock_Description() string {
 	return m.description
 }
 
+func (m *mockBucket) AbortMultipartUpload(p0 string, p1 string) (o0 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"AbortMultipartUpload",
+		file,
+		line,
+		[]interface{}{p0, p1})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockBucket.AbortMultipartUpload: invalid return values: %v", retVals))
+	}
+
+	// o0 error
+	if retVals[0] != nil {
+		o0 = retVals[0].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) CompleteMultipartUpload(p0 string, p1 string, p2 []s3.CompletedPart) (o0 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"CompleteMultipartUpload",
+		file,
+		line,
+		[]interface{}{p0, p1, p2})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockBucket.CompleteMultipartUpload: invalid return values: %v", retVals))
+	}
+
+	// o0 error
+	if retVals[0] != nil {
+		o0 = retVals[0].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) CopyObject(p0 string, p1 string, p2 *s3.CopyOptions) (o0 *s3.CopyObjectResult, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"CopyObject",
+		file,
+		line,
+		[]interface{}{p0, p1, p2})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockBucket.CopyObject: invalid return values: %v", retVals))
+	}
+
+	// o0 *s3.CopyObjectResult
+	if retVals[0] != nil {
+		o0 = retVals[0].(*s3.CopyObjectResult)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
 func (m *mockBucket) DeleteObject(p0 string) (o0 error) {
 	// Get a file name and line number for the caller.
 	_, file, line, _ := runtime.Caller(1)
@@ -65,6 +144,59 @@ func (m *mockBucket) DeleteObject(p0 string) (o0 error) {
 	return
 }
 
+func (m *mockBucket) DeleteObjectVersion(p0 string, p1 string) (o0 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"DeleteObjectVersion",
+		file,
+		line,
+		[]interface{}{p0, p1})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockBucket.DeleteObjectVersion: invalid return values: %v", retVals))
+	}
+
+	// o0 error
+	if retVals[0] != nil {
+		o0 = retVals[0].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) DeleteObjects(p0 []string, p1 bool) (o0 *s3.DeleteObjectsResult, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"DeleteObjects",
+		file,
+		line,
+		[]interface{}{p0, p1})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockBucket.DeleteObjects: invalid return values: %v", retVals))
+	}
+
+	// o0 *s3.DeleteObjectsResult
+	if retVals[0] != nil {
+		o0 = retVals[0].(*s3.DeleteObjectsResult)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
 func (m *mockBucket) GetObject(p0 string) (o0 []uint8, o1 error) {
 	// Get a file name and line number for the caller.
 	_, file, line, _ := runtime.Caller(1)
@@ -94,6 +226,200 @@ func (m *mockBucket) GetObject(p0 string) (o0 []uint8, o1 error) {
 	return
 }
 
+func (m *mockBucket) GetObjectRange(p0 string, p1 int64, p2 int64) (o0 []uint8, o1 int64, o2 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"GetObjectRange",
+		file,
+		line,
+		[]interface{}{p0, p1, p2})
+
+	if len(retVals) != 3 {
+		panic(fmt.Sprintf("mockBucket.GetObjectRange: invalid return values: %v", retVals))
+	}
+
+	// o0 []uint8
+	if retVals[0] != nil {
+		o0 = retVals[0].([]uint8)
+	}
+
+	// o1 int64
+	if retVals[1] != nil {
+		o1 = retVals[1].(int64)
+	}
+
+	// o2 error
+	if retVals[2] != nil {
+		o2 = retVals[2].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) GetObjectRangeWithOptions(p0 string, p1 int64, p2 int64, p3 *s3.RangeOptions) (o0 []uint8, o1 int64, o2 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"GetObjectRangeWithOptions",
+		file,
+		line,
+		[]interface{}{p0, p1, p2, p3})
+
+	if len(retVals) != 3 {
+		panic(fmt.Sprintf("mockBucket.GetObjectRangeWithOptions: invalid return values: %v", retVals))
+	}
+
+	// o0 []uint8
+	if retVals[0] != nil {
+		o0 = retVals[0].([]uint8)
+	}
+
+	// o1 int64
+	if retVals[1] != nil {
+		o1 = retVals[1].(int64)
+	}
+
+	// o2 error
+	if retVals[2] != nil {
+		o2 = retVals[2].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) GetObjectReader(p0 string) (o0 io.ReadCloser, o1 int64, o2 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"GetObjectReader",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 3 {
+		panic(fmt.Sprintf("mockBucket.GetObjectReader: invalid return values: %v", retVals))
+	}
+
+	// o0 io.ReadCloser
+	if retVals[0] != nil {
+		o0 = retVals[0].(io.ReadCloser)
+	}
+
+	// o1 int64
+	if retVals[1] != nil {
+		o1 = retVals[1].(int64)
+	}
+
+	// o2 error
+	if retVals[2] != nil {
+		o2 = retVals[2].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) GetObjectWithOptions(p0 string, p1 *s3.GetOptions) (o0 io.ReadCloser, o1 *s3.ObjectInfo, o2 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"GetObjectWithOptions",
+		file,
+		line,
+		[]interface{}{p0, p1})
+
+	if len(retVals) != 3 {
+		panic(fmt.Sprintf("mockBucket.GetObjectWithOptions: invalid return values: %v", retVals))
+	}
+
+	// o0 io.ReadCloser
+	if retVals[0] != nil {
+		o0 = retVals[0].(io.ReadCloser)
+	}
+
+	// o1 *s3.ObjectInfo
+	if retVals[1] != nil {
+		o1 = retVals[1].(*s3.ObjectInfo)
+	}
+
+	// o2 error
+	if retVals[2] != nil {
+		o2 = retVals[2].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) GetVersioning() (o0 s3.VersioningStatus, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"GetVersioning",
+		file,
+		line,
+		[]interface{}{})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockBucket.GetVersioning: invalid return values: %v", retVals))
+	}
+
+	// o0 s3.VersioningStatus
+	if retVals[0] != nil {
+		o0 = retVals[0].(s3.VersioningStatus)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) InitiateMultipartUpload(p0 string) (o0 string, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"InitiateMultipartUpload",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockBucket.InitiateMultipartUpload: invalid return values: %v", retVals))
+	}
+
+	// o0 string
+	if retVals[0] != nil {
+		o0 = retVals[0].(string)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
 func (m *mockBucket) ListKeys(p0 string) (o0 []string, o1 error) {
 	// Get a file name and line number for the caller.
 	_, file, line, _ := runtime.Caller(1)
@@ -123,6 +449,175 @@ func (m *mockBucket) ListKeys(p0 string) (o0 []string, o1 error) {
 	return
 }
 
+func (m *mockBucket) ListObjectVersions(p0 s3.ListVersionsRequest) (o0 *s3.ListVersionsResult, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"ListObjectVersions",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockBucket.ListObjectVersions: invalid return values: %v", retVals))
+	}
+
+	// o0 *s3.ListVersionsResult
+	if retVals[0] != nil {
+		o0 = retVals[0].(*s3.ListVersionsResult)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) ListObjects(p0 s3.ListRequest) (o0 *s3.ListResult, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"ListObjects",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockBucket.ListObjects: invalid return values: %v", retVals))
+	}
+
+	// o0 *s3.ListResult
+	if retVals[0] != nil {
+		o0 = retVals[0].(*s3.ListResult)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) PresignUrl(p0 string, p1 *s3.PresignOptions) (o0 string, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"PresignUrl",
+		file,
+		line,
+		[]interface{}{p0, p1})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockBucket.PresignUrl: invalid return values: %v", retVals))
+	}
+
+	// o0 string
+	if retVals[0] != nil {
+		o0 = retVals[0].(string)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) SetVersioning(p0 s3.VersioningStatus) (o0 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"SetVersioning",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockBucket.SetVersioning: invalid return values: %v", retVals))
+	}
+
+	// o0 error
+	if retVals[0] != nil {
+		o0 = retVals[0].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) StatObject(p0 string) (o0 *s3.ObjectInfo, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"StatObject",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockBucket.StatObject: invalid return values: %v", retVals))
+	}
+
+	// o0 *s3.ObjectInfo
+	if retVals[0] != nil {
+		o0 = retVals[0].(*s3.ObjectInfo)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) StatObjectWithOptions(p0 string, p1 *s3.StatOptions) (o0 *s3.ObjectInfo, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"StatObjectWithOptions",
+		file,
+		line,
+		[]interface{}{p0, p1})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockBucket.StatObjectWithOptions: invalid return values: %v", retVals))
+	}
+
+	// o0 *s3.ObjectInfo
+	if retVals[0] != nil {
+		o0 = retVals[0].(*s3.ObjectInfo)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
 func (m *mockBucket) StoreObject(p0 string, p1 []uint8) (o0 error) {
 	// Get a file name and line number for the caller.
 	_, file, line, _ := runtime.Caller(1)
@@ -146,3 +641,109 @@ func (m *mockBucket) StoreObject(p0 string, p1 []uint8) (o0 error) {
 
 	return
 }
+
+func (m *mockBucket) StoreObjectFromReader(p0 string, p1 io.Reader, p2 int64) (o0 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"StoreObjectFromReader",
+		file,
+		line,
+		[]interface{}{p0, p1, p2})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockBucket.StoreObjectFromReader: invalid return values: %v", retVals))
+	}
+
+	// o0 error
+	if retVals[0] != nil {
+		o0 = retVals[0].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) StoreObjectWithOptions(p0 string, p1 io.Reader, p2 int64, p3 *s3.StoreOptions) (o0 *s3.StoreResult, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"StoreObjectWithOptions",
+		file,
+		line,
+		[]interface{}{p0, p1, p2, p3})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockBucket.StoreObjectWithOptions: invalid return values: %v", retVals))
+	}
+
+	// o0 *s3.StoreResult
+	if retVals[0] != nil {
+		o0 = retVals[0].(*s3.StoreResult)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) UploadPart(p0 string, p1 string, p2 int, p3 io.Reader, p4 int64) (o0 string, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"UploadPart",
+		file,
+		line,
+		[]interface{}{p0, p1, p2, p3, p4})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockBucket.UploadPart: invalid return values: %v", retVals))
+	}
+
+	// o0 string
+	if retVals[0] != nil {
+		o0 = retVals[0].(string)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
+func (m *mockBucket) WithContext(p0 context.Context) (o0 s3.Bucket) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"WithContext",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockBucket.WithContext: invalid return values: %v", retVals))
+	}
+
+	// o0 s3.Bucket
+	if retVals[0] != nil {
+		o0 = retVals[0].(s3.Bucket)
+	}
+
+	return
+}
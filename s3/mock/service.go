@@ -0,0 +1,173 @@
+// This file was auto-generated using createmock. See the following page for
+// more information:
+//
+//     https://github.com/jacobsa/oglemock
+//
+
+package mock_s3
+
+import (
+	context "context"
+	fmt "fmt"
+	s3 "github.com/jacobsa/aws/s3"
+	oglemock "github.com/jacobsa/oglemock"
+	runtime "runtime"
+	unsafe "unsafe"
+)
+
+type MockService interface {
+	s3.Service
+	oglemock.MockObject
+}
+
+type mockService struct {
+	controller  oglemock.Controller
+	description string
+}
+
+func NewMockService(
+	c oglemock.Controller,
+	desc string) MockService {
+	return &mockService{
+		controller:  c,
+		description: desc,
+	}
+}
+
+func (m *mockService) Oglemock_Id() uintptr {
+	return uintptr(unsafe.Pointer(m))
+}
+
+func (m *mockService) Oglemock_Description() string {
+	return m.description
+}
+
+func (m *mockService) CreateBucket(p0 string, p1 *s3.CreateBucketOptions) (o0 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"CreateBucket",
+		file,
+		line,
+		[]interface{}{p0, p1})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockService.CreateBucket: invalid return values: %v", retVals))
+	}
+
+	// o0 error
+	if retVals[0] != nil {
+		o0 = retVals[0].(error)
+	}
+
+	return
+}
+
+func (m *mockService) DeleteBucket(p0 string) (o0 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"DeleteBucket",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockService.DeleteBucket: invalid return values: %v", retVals))
+	}
+
+	// o0 error
+	if retVals[0] != nil {
+		o0 = retVals[0].(error)
+	}
+
+	return
+}
+
+func (m *mockService) GetBucketLocation(p0 string) (o0 string, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"GetBucketLocation",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockService.GetBucketLocation: invalid return values: %v", retVals))
+	}
+
+	// o0 string
+	if retVals[0] != nil {
+		o0 = retVals[0].(string)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
+func (m *mockService) ListBuckets() (o0 []s3.BucketInfo, o1 error) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"ListBuckets",
+		file,
+		line,
+		[]interface{}{})
+
+	if len(retVals) != 2 {
+		panic(fmt.Sprintf("mockService.ListBuckets: invalid return values: %v", retVals))
+	}
+
+	// o0 []s3.BucketInfo
+	if retVals[0] != nil {
+		o0 = retVals[0].([]s3.BucketInfo)
+	}
+
+	// o1 error
+	if retVals[1] != nil {
+		o1 = retVals[1].(error)
+	}
+
+	return
+}
+
+func (m *mockService) WithContext(p0 context.Context) (o0 s3.Service) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"WithContext",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockService.WithContext: invalid return values: %v", retVals))
+	}
+
+	// o0 s3.Service
+	if retVals[0] != nil {
+		o0 = retVals[0].(s3.Service)
+	}
+
+	return
+}
@@ -0,0 +1,325 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/jacobsa/aws/s3/http"
+	"io"
+	"strconv"
+	sys_time "time"
+)
+
+// A part of a multipart upload that has been successfully uploaded, for use
+// with CompleteMultipartUpload.
+type CompletedPart struct {
+	// The number of the part, in [1, 10000].
+	PartNumber int
+
+	// The ETag returned by UploadPart for the part.
+	ETag string
+}
+
+////////////////////////////////////////////////////////////////////////
+// InitiateMultipartUpload
+////////////////////////////////////////////////////////////////////////
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name
+	UploadId string
+}
+
+func (b *bucket) InitiateMultipartUpload(key string) (uploadId string, err error) {
+	// Validate the key.
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadInitiate.html
+	httpReq := &http.Request{
+		Verb:   "POST",
+		Path:   b.objectPath(key),
+		Bucket: b.hostBucket(),
+		Headers: map[string]string{
+			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+		Parameters: map[string]string{
+			"uploads": "",
+		},
+	}
+
+	// Sign the request.
+	if err := b.signer.Sign(httpReq); err != nil {
+		return "", fmt.Errorf("Sign: %v", err)
+	}
+
+	// Send the request.
+	httpResp, err := b.httpConn.SendRequest(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("SendRequest: %v", err)
+	}
+
+	// Check the response.
+	if httpResp.StatusCode != 200 {
+		return "", newError(httpResp.StatusCode, httpResp.Body)
+	}
+
+	// Attempt to parse the body.
+	result := initiateMultipartUploadResult{}
+	if err := xml.Unmarshal(httpResp.Body, &result); err != nil {
+		return "", fmt.Errorf(
+			"Invalid data from server (%s): %s",
+			err.Error(),
+			httpResp.Body)
+	}
+
+	if result.XMLName.Local != "InitiateMultipartUploadResult" || result.UploadId == "" {
+		return "", fmt.Errorf("Invalid data from server: %s", httpResp.Body)
+	}
+
+	return result.UploadId, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// UploadPart
+////////////////////////////////////////////////////////////////////////
+
+func (b *bucket) UploadPart(
+	key string,
+	uploadId string,
+	partNumber int,
+	r io.Reader,
+	size int64) (etag string, err error) {
+	// Validate the key and part number.
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+
+	if partNumber < 1 || partNumber > 10000 {
+		return "", fmt.Errorf("Invalid part number: %d", partNumber)
+	}
+
+	// Figure out the length and MD5 sum of the data, and obtain a stream that
+	// will yield the data again.
+	body, n, sum, cleanup, err := prepareBody(r, size)
+	if err != nil {
+		return "", err
+	}
+
+	defer cleanup()
+
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadUploadPart.html
+	httpReq := &http.Request{
+		Verb:          "PUT",
+		Path:          b.objectPath(key),
+		Bucket:        b.hostBucket(),
+		BodyReader:    body,
+		ContentLength: n,
+		Headers: map[string]string{
+			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+		Parameters: map[string]string{
+			"partNumber": strconv.Itoa(partNumber),
+			"uploadId":   uploadId,
+		},
+	}
+
+	// Add a Content-MD5 header, so that S3 can detect data corrupted in transit.
+	if err := addMd5HeaderForSum(httpReq, sum); err != nil {
+		return "", err
+	}
+
+	// Sign the request.
+	if err := b.signer.Sign(httpReq); err != nil {
+		return "", fmt.Errorf("Sign: %v", err)
+	}
+
+	// Send the request.
+	httpResp, err := b.httpConn.SendRequest(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("SendRequest: %v", err)
+	}
+
+	// Check the response.
+	if httpResp.StatusCode != 200 {
+		return "", newError(httpResp.StatusCode, httpResp.Body)
+	}
+
+	if etag = httpResp.Headers["Etag"]; etag == "" {
+		return "", fmt.Errorf("No ETag in response from server.")
+	}
+
+	return etag, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// CompleteMultipartUpload
+////////////////////////////////////////////////////////////////////////
+
+type completeMultipartUploadPart struct {
+	PartNumber int
+	ETag       string
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Part    []completeMultipartUploadPart
+}
+
+type completeMultipartUploadResult struct {
+	XMLName xml.Name
+}
+
+func (b *bucket) CompleteMultipartUpload(
+	key string,
+	uploadId string,
+	parts []CompletedPart) error {
+	// Validate the key and parts.
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	if len(parts) == 0 {
+		return fmt.Errorf("At least one part is required.")
+	}
+
+	// Build the request body.
+	reqBody := completeMultipartUpload{}
+	for i, p := range parts {
+		if i > 0 && p.PartNumber <= parts[i-1].PartNumber {
+			return fmt.Errorf("Parts must be in increasing order of part number.")
+		}
+
+		reqBody.Part = append(
+			reqBody.Part,
+			completeMultipartUploadPart{p.PartNumber, p.ETag})
+	}
+
+	body, err := xml.Marshal(reqBody)
+	if err != nil {
+		return fmt.Errorf("xml.Marshal: %v", err)
+	}
+
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadComplete.html
+	httpReq := &http.Request{
+		Verb:   "POST",
+		Path:   b.objectPath(key),
+		Bucket: b.hostBucket(),
+		Body:   body,
+		Headers: map[string]string{
+			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+		Parameters: map[string]string{
+			"uploadId": uploadId,
+		},
+	}
+
+	// Add a Content-MD5 header, as advised in the Amazon docs.
+	if err := addMd5Header(httpReq, httpReq.Body); err != nil {
+		return err
+	}
+
+	// Sign the request.
+	if err := b.signer.Sign(httpReq); err != nil {
+		return fmt.Errorf("Sign: %v", err)
+	}
+
+	// Send the request.
+	httpResp, err := b.httpConn.SendRequest(httpReq)
+	if err != nil {
+		return fmt.Errorf("SendRequest: %v", err)
+	}
+
+	// Check the response.
+	if httpResp.StatusCode != 200 {
+		return newError(httpResp.StatusCode, httpResp.Body)
+	}
+
+	// S3 may report an error after having already sent a 200 status code, so
+	// we must look at the body to find out whether we actually succeeded.
+	result := completeMultipartUploadResult{}
+	if err := xml.Unmarshal(httpResp.Body, &result); err != nil {
+		return fmt.Errorf(
+			"Invalid data from server (%s): %s",
+			err.Error(),
+			httpResp.Body)
+	}
+
+	switch result.XMLName.Local {
+	case "CompleteMultipartUploadResult":
+	case "Error":
+		return newError(httpResp.StatusCode, httpResp.Body)
+	default:
+		return fmt.Errorf("Invalid data from server: %s", httpResp.Body)
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// AbortMultipartUpload
+////////////////////////////////////////////////////////////////////////
+
+func (b *bucket) AbortMultipartUpload(key string, uploadId string) error {
+	// Validate the key.
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadAbort.html
+	httpReq := &http.Request{
+		Verb:   "DELETE",
+		Path:   b.objectPath(key),
+		Bucket: b.hostBucket(),
+		Headers: map[string]string{
+			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+		Parameters: map[string]string{
+			"uploadId": uploadId,
+		},
+	}
+
+	// Sign the request.
+	if err := b.signer.Sign(httpReq); err != nil {
+		return fmt.Errorf("Sign: %v", err)
+	}
+
+	// Send the request.
+	httpResp, err := b.httpConn.SendRequest(httpReq)
+	if err != nil {
+		return fmt.Errorf("SendRequest: %v", err)
+	}
+
+	// Check the response.
+	if httpResp.StatusCode != 204 {
+		return newError(httpResp.StatusCode, httpResp.Body)
+	}
+
+	return nil
+}
@@ -0,0 +1,554 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestMultipart(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// InitiateMultipartUpload
+////////////////////////////////////////////////////////////////////////
+
+type InitiateMultipartUploadTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&InitiateMultipartUploadTest{}) }
+
+func (t *InitiateMultipartUploadTest) KeyIsEmpty() {
+	// Call
+	_, err := t.bucket.InitiateMultipartUpload("")
+
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *InitiateMultipartUploadTest) CallsSigner() {
+	key := "foo/bar/baz"
+
+	// Clock
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+
+	// Signer
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	// Call
+	t.bucket.InitiateMultipartUpload(key)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("POST", httpReq.Verb)
+	ExpectEq("/some.bucket/foo/bar/baz", httpReq.Path)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", httpReq.Headers["Date"])
+	ExpectThat(httpReq.Parameters, DeepEquals(map[string]string{"uploads": ""}))
+}
+
+func (t *InitiateMultipartUploadTest) SignerReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(errors.New("taco")))
+
+	// Call
+	_, err := t.bucket.InitiateMultipartUpload("a")
+
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *InitiateMultipartUploadTest) ConnReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	_, err := t.bucket.InitiateMultipartUpload("a")
+
+	ExpectThat(err, ErrorThat(HasSubstr("SendRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *InitiateMultipartUploadTest) ServerReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 500,
+		Body:       []byte("taco"),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	_, err := t.bucket.InitiateMultipartUpload("a")
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("500")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *InitiateMultipartUploadTest) ResponseBodyIsJunk() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 200,
+		Body:       []byte("taco"),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	_, err := t.bucket.InitiateMultipartUpload("a")
+
+	ExpectThat(err, ErrorThat(HasSubstr("Invalid")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *InitiateMultipartUploadTest) ReturnsUploadId() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<InitiateMultipartUploadResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+				<Bucket>some.bucket</Bucket>
+				<Key>a</Key>
+				<UploadId>taco</UploadId>
+			</InitiateMultipartUploadResult>`),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	uploadId, err := t.bucket.InitiateMultipartUpload("a")
+	AssertEq(nil, err)
+
+	ExpectEq("taco", uploadId)
+}
+
+////////////////////////////////////////////////////////////////////////
+// UploadPart
+////////////////////////////////////////////////////////////////////////
+
+type UploadPartTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&UploadPartTest{}) }
+
+func (t *UploadPartTest) KeyIsEmpty() {
+	// Call
+	_, err := t.bucket.UploadPart("", "taco", 1, bytes.NewReader(nil), 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *UploadPartTest) PartNumberTooSmall() {
+	// Call
+	_, err := t.bucket.UploadPart("a", "taco", 0, bytes.NewReader(nil), 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("part number")))
+	ExpectThat(err, ErrorThat(HasSubstr("0")))
+}
+
+func (t *UploadPartTest) PartNumberTooLarge() {
+	// Call
+	_, err := t.bucket.UploadPart("a", "taco", 10001, bytes.NewReader(nil), 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("part number")))
+	ExpectThat(err, ErrorThat(HasSubstr("10001")))
+}
+
+func (t *UploadPartTest) CallsSigner() {
+	key := "foo/bar/baz"
+	data := []byte{0x00, 0xde, 0xad, 0xbe, 0xef}
+
+	// Clock
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+
+	// Signer
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	// Call
+	t.bucket.UploadPart(key, "taco", 17, bytes.NewReader(data), int64(len(data)))
+
+	AssertNe(nil, httpReq)
+	ExpectEq("PUT", httpReq.Verb)
+	ExpectEq("/some.bucket/foo/bar/baz", httpReq.Path)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", httpReq.Headers["Date"])
+	ExpectEq(computeBase64Md5(data), httpReq.Headers["Content-MD5"])
+	ExpectEq("17", httpReq.Parameters["partNumber"])
+	ExpectEq("taco", httpReq.Parameters["uploadId"])
+	ExpectEq(len(data), httpReq.ContentLength)
+
+	body, err := ioutil.ReadAll(httpReq.BodyReader)
+	AssertEq(nil, err)
+	ExpectThat(body, DeepEquals(data))
+}
+
+func (t *UploadPartTest) SignerReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(errors.New("taco")))
+
+	// Call
+	_, err := t.bucket.UploadPart("a", "b", 1, bytes.NewReader(nil), 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *UploadPartTest) ConnReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	_, err := t.bucket.UploadPart("a", "b", 1, bytes.NewReader(nil), 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("SendRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *UploadPartTest) ServerReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 500,
+		Body:       []byte("taco"),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	_, err := t.bucket.UploadPart("a", "b", 1, bytes.NewReader(nil), 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("500")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *UploadPartTest) ServerReturnsNoETag() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 200,
+		Headers:    map[string]string{},
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	_, err := t.bucket.UploadPart("a", "b", 1, bytes.NewReader(nil), 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("ETag")))
+}
+
+func (t *UploadPartTest) ReturnsETag() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Etag": `"taco"`,
+		},
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	etag, err := t.bucket.UploadPart("a", "b", 1, bytes.NewReader(nil), 0)
+	AssertEq(nil, err)
+
+	ExpectEq(`"taco"`, etag)
+}
+
+////////////////////////////////////////////////////////////////////////
+// CompleteMultipartUpload
+////////////////////////////////////////////////////////////////////////
+
+type CompleteMultipartUploadTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&CompleteMultipartUploadTest{}) }
+
+func (t *CompleteMultipartUploadTest) KeyIsEmpty() {
+	parts := []CompletedPart{{1, "taco"}}
+
+	// Call
+	err := t.bucket.CompleteMultipartUpload("", "b", parts)
+
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *CompleteMultipartUploadTest) NoParts() {
+	// Call
+	err := t.bucket.CompleteMultipartUpload("a", "b", []CompletedPart{})
+
+	ExpectThat(err, ErrorThat(HasSubstr("part")))
+}
+
+func (t *CompleteMultipartUploadTest) PartsOutOfOrder() {
+	parts := []CompletedPart{{2, "taco"}, {1, "burrito"}}
+
+	// Call
+	err := t.bucket.CompleteMultipartUpload("a", "b", parts)
+
+	ExpectThat(err, ErrorThat(HasSubstr("order")))
+}
+
+func (t *CompleteMultipartUploadTest) CallsSigner() {
+	parts := []CompletedPart{{1, `"taco"`}, {3, `"burrito"`}}
+
+	// Clock
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+
+	// Signer
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	// Call
+	t.bucket.CompleteMultipartUpload("foo/bar", "enchilada", parts)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("POST", httpReq.Verb)
+	ExpectEq("/some.bucket/foo/bar", httpReq.Path)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", httpReq.Headers["Date"])
+	ExpectEq(computeBase64Md5(httpReq.Body), httpReq.Headers["Content-MD5"])
+	ExpectThat(
+		httpReq.Parameters,
+		DeepEquals(map[string]string{"uploadId": "enchilada"}))
+
+	ExpectEq(
+		"<CompleteMultipartUpload>"+
+			"<Part><PartNumber>1</PartNumber><ETag>&#34;taco&#34;</ETag></Part>"+
+			"<Part><PartNumber>3</PartNumber><ETag>&#34;burrito&#34;</ETag></Part>"+
+			"</CompleteMultipartUpload>",
+		string(httpReq.Body))
+}
+
+func (t *CompleteMultipartUploadTest) ServerReturnsError() {
+	parts := []CompletedPart{{1, "taco"}}
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 400,
+		Body:       []byte("taco"),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	err := t.bucket.CompleteMultipartUpload("a", "b", parts)
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("400")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *CompleteMultipartUploadTest) ServerReturnsErrorWithOkayStatus() {
+	parts := []CompletedPart{{1, "taco"}}
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<Error>
+				<Code>InternalError</Code>
+				<Message>We encountered an internal error.</Message>
+			</Error>`),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	err := t.bucket.CompleteMultipartUpload("a", "b", parts)
+
+	ExpectThat(err, ErrorThat(HasSubstr("InternalError")))
+	ExpectThat(err, ErrorThat(HasSubstr("internal error")))
+}
+
+func (t *CompleteMultipartUploadTest) ServerSaysOkay() {
+	parts := []CompletedPart{{1, "taco"}}
+
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<?xml version="1.0" encoding="UTF-8"?>
+			<CompleteMultipartUploadResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+				<Key>a</Key>
+				<ETag>"taco-1"</ETag>
+			</CompleteMultipartUploadResult>`),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	err := t.bucket.CompleteMultipartUpload("a", "b", parts)
+
+	ExpectEq(nil, err)
+}
+
+////////////////////////////////////////////////////////////////////////
+// AbortMultipartUpload
+////////////////////////////////////////////////////////////////////////
+
+type AbortMultipartUploadTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&AbortMultipartUploadTest{}) }
+
+func (t *AbortMultipartUploadTest) KeyIsEmpty() {
+	// Call
+	err := t.bucket.AbortMultipartUpload("", "b")
+
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *AbortMultipartUploadTest) CallsSigner() {
+	// Clock
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+
+	// Signer
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	// Call
+	t.bucket.AbortMultipartUpload("foo/bar", "taco")
+
+	AssertNe(nil, httpReq)
+	ExpectEq("DELETE", httpReq.Verb)
+	ExpectEq("/some.bucket/foo/bar", httpReq.Path)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", httpReq.Headers["Date"])
+	ExpectThat(httpReq.Parameters, DeepEquals(map[string]string{"uploadId": "taco"}))
+}
+
+func (t *AbortMultipartUploadTest) ServerReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 404,
+		Body:       []byte("taco"),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	err := t.bucket.AbortMultipartUpload("a", "b")
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("404")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *AbortMultipartUploadTest) ServerReturnsNoContent() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 204,
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	err := t.bucket.AbortMultipartUpload("a", "b")
+
+	ExpectEq(nil, err)
+}
@@ -0,0 +1,104 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"net/http"
+)
+
+// An Option customizes a bucket opened with OpenBucket, or a service opened
+// with OpenService.
+type Option func(*bucketOptions)
+
+type bucketOptions struct {
+	signatureV4     bool
+	unsignedPayload bool
+	addressingStyle AddressingStyle
+	retryPolicy     *RetryPolicy
+	httpClient      *http.Client
+	signingRegion   string
+}
+
+func makeBucketOptions(opts []Option) *bucketOptions {
+	o := &bucketOptions{httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithSignatureV4 causes requests to be signed using AWS Signature Version 4
+// rather than the legacy scheme. Regions launched since 2014 accept only
+// Version 4. It also causes PresignUrl to always use Version 4.
+func WithSignatureV4() Option {
+	return func(o *bucketOptions) {
+		o.signatureV4 = true
+	}
+}
+
+// WithUnsignedPayload causes request payloads to be excluded from Signature
+// Version 4 signatures, saving the cost of hashing them. Payloads are still
+// protected in transit by their Content-MD5 headers. Has no effect without
+// WithSignatureV4.
+func WithUnsignedPayload() Option {
+	return func(o *bucketOptions) {
+		o.unsignedPayload = true
+	}
+}
+
+// WithAddressingStyle sets the style used to address the bucket. See
+// AddressingStyle.
+func WithAddressingStyle(style AddressingStyle) Option {
+	return func(o *bucketOptions) {
+		o.addressingStyle = style
+	}
+}
+
+// WithRetryPolicy causes requests that fail with a transient network error or
+// a server error status to be retried according to the supplied policy. Each
+// attempt is re-dated and re-signed. POST requests, such as those that start
+// or complete multipart uploads, are never retried. See RetryPolicy and
+// DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *bucketOptions) {
+		o.retryPolicy = &policy
+	}
+}
+
+// WithHttpClient causes requests to be sent using the supplied client rather
+// than http.DefaultClient, allowing control over connection pooling, proxies,
+// timeouts, TLS settings, and so on.
+func WithHttpClient(client *http.Client) Option {
+	return func(o *bucketOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithHttpTransport is like WithHttpClient, for a client that uses the
+// supplied transport and is otherwise the default.
+func WithHttpTransport(transport http.RoundTripper) Option {
+	return WithHttpClient(&http.Client{Transport: transport})
+}
+
+// WithSigningRegion sets the region name, e.g. "eu-west-1", used to scope
+// Signature Version 4 credentials. By default it is derived from the region
+// passed to OpenBucket, or is "us-east-1" for OpenBucketAtEndpoint.
+func WithSigningRegion(name string) Option {
+	return func(o *bucketOptions) {
+		o.signingRegion = name
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"github.com/jacobsa/aws"
+	. "github.com/jacobsa/ogletest"
+	"net/http"
+	"testing"
+)
+
+func TestOptions(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type OptionsTest struct {
+}
+
+func init() { RegisterTestSuite(&OptionsTest{}) }
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *OptionsTest) Defaults() {
+	o := makeBucketOptions(nil)
+
+	ExpectFalse(o.signatureV4)
+	ExpectFalse(o.unsignedPayload)
+	ExpectEq(PathStyle, o.addressingStyle)
+	ExpectEq(nil, o.retryPolicy)
+	ExpectEq(http.DefaultClient, o.httpClient)
+	ExpectEq("", o.signingRegion)
+}
+
+func (t *OptionsTest) SigningRegion() {
+	o := makeBucketOptions([]Option{WithSigningRegion("taco")})
+
+	ExpectEq("taco", o.signingRegion)
+}
+
+func (t *OptionsTest) HttpClient() {
+	client := &http.Client{}
+	o := makeBucketOptions([]Option{WithHttpClient(client)})
+
+	ExpectEq(client, o.httpClient)
+}
+
+func (t *OptionsTest) HttpTransport() {
+	transport := &http.Transport{}
+	o := makeBucketOptions([]Option{WithHttpTransport(transport)})
+
+	AssertNe(nil, o.httpClient)
+	ExpectEq(transport, o.httpClient.Transport)
+}
+
+func (t *OptionsTest) RetryPolicy() {
+	o := makeBucketOptions([]Option{WithRetryPolicy(DefaultRetryPolicy)})
+
+	AssertNe(nil, o.retryPolicy)
+	ExpectThat(*o.retryPolicy, DeepEquals(DefaultRetryPolicy))
+}
+
+func (t *OptionsTest) SignatureV4() {
+	o := makeBucketOptions([]Option{WithSignatureV4(), WithUnsignedPayload()})
+
+	ExpectTrue(o.signatureV4)
+	ExpectTrue(o.unsignedPayload)
+}
+
+func (t *OptionsTest) OpenBucketWithOptions() {
+	b, err := OpenBucket(
+		"some.bucket",
+		RegionEuIreland,
+		aws.AccessKey{},
+		WithSignatureV4())
+
+	AssertEq(nil, err)
+	ExpectNe(nil, b)
+}
+
+func (t *OptionsTest) VirtualHostedStyle() {
+	b, err := OpenBucket(
+		"some-bucket",
+		RegionEuIreland,
+		aws.AccessKey{},
+		WithAddressingStyle(VirtualHostedStyle))
+
+	AssertEq(nil, err)
+	ExpectTrue(b.(*bucket).virtualHosted)
+}
+
+func (t *OptionsTest) VirtualHostedStyleWithIncompatibleName() {
+	b, err := OpenBucket(
+		"some.bucket",
+		RegionEuIreland,
+		aws.AccessKey{},
+		WithAddressingStyle(VirtualHostedStyle))
+
+	AssertEq(nil, err)
+	ExpectFalse(b.(*bucket).virtualHosted)
+}
@@ -0,0 +1,114 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"fmt"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/aws/s3/internal/validate"
+	"net/url"
+	sys_time "time"
+)
+
+// Options controlling the URL returned by Bucket.PresignUrl.
+type PresignOptions struct {
+	// The HTTP verb with which the URL may be used: "GET" to download the
+	// object or "PUT" to upload it. If empty, "GET" is used.
+	Verb string
+
+	// How long the URL remains valid. Must be positive.
+	Expiry sys_time.Duration
+
+	// For a PUT, the Content-Type header that the uploader must send. If
+	// empty, the uploader must send no Content-Type header.
+	ContentType string
+
+	// If true, use AWS Signature Version 4 query parameters rather than the
+	// legacy Expires and Signature parameters. Some regions support only
+	// Version 4. Version 4 URLs may be valid for at most seven days.
+	//
+	// Version 4 is always used for buckets opened with WithSignatureV4.
+	UseSignatureV4 bool
+}
+
+func (b *bucket) PresignUrl(key string, opts *PresignOptions) (string, error) {
+	// Validate the arguments.
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+
+	if opts == nil {
+		opts = &PresignOptions{}
+	}
+
+	if err := validate.PresignOptions(opts.Verb, opts.Expiry, opts.ContentType); err != nil {
+		return "", err
+	}
+
+	verb := opts.Verb
+	if verb == "" {
+		verb = "GET"
+	}
+
+	// Build the request that the URL will authorize.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/dev/RESTAuthentication.html#RESTAuthenticationQueryStringAuth
+	now := b.clock.Now()
+	httpReq := &http.Request{
+		Verb:   verb,
+		Path:   b.objectPath(key),
+		Bucket: b.hostBucket(),
+		Headers: map[string]string{
+			"Date": now.UTC().Format(sys_time.RFC1123),
+		},
+		Parameters: map[string]string{},
+	}
+
+	if opts.ContentType != "" {
+		httpReq.Headers["Content-Type"] = opts.ContentType
+	}
+
+	// Presign it.
+	presigner := b.presigner
+	if opts.UseSignatureV4 {
+		presigner = b.v4Presigner
+	}
+
+	if err := presigner.Presign(httpReq, now.Add(opts.Expiry)); err != nil {
+		return "", fmt.Errorf("Presign: %v", err)
+	}
+
+	// Assemble the URL.
+	query := url.Values{}
+	for name, val := range httpReq.Parameters {
+		query.Set(name, val)
+	}
+
+	host := b.endpoint.Host
+	if httpReq.Bucket != "" {
+		host = httpReq.Bucket + "." + host
+	}
+
+	u := &url.URL{
+		Scheme:   b.endpoint.Scheme,
+		Host:     host,
+		Path:     httpReq.Path,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String(), nil
+}
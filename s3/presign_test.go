@@ -0,0 +1,154 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"testing"
+	"time"
+)
+
+func TestPresign(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type PresignUrlTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&PresignUrlTest{}) }
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *PresignUrlTest) KeyIsEmpty() {
+	// Call
+	_, err := t.bucket.PresignUrl("", &PresignOptions{Expiry: time.Minute})
+
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *PresignUrlTest) UnsupportedVerb() {
+	opts := &PresignOptions{Verb: "DELETE", Expiry: time.Minute}
+
+	// Call
+	_, err := t.bucket.PresignUrl("a", opts)
+
+	ExpectThat(err, ErrorThat(HasSubstr("verb")))
+	ExpectThat(err, ErrorThat(HasSubstr("DELETE")))
+}
+
+func (t *PresignUrlTest) NilOptions() {
+	// Call
+	_, err := t.bucket.PresignUrl("a", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Expiry")))
+}
+
+func (t *PresignUrlTest) ContentTypeForGet() {
+	opts := &PresignOptions{Expiry: time.Minute, ContentType: "text/plain"}
+
+	// Call
+	_, err := t.bucket.PresignUrl("a", opts)
+
+	ExpectThat(err, ErrorThat(HasSubstr("PUT")))
+}
+
+func (t *PresignUrlTest) CallsPresigner() {
+	// Clock
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+
+	// Presigner
+	var httpReq *http.Request
+	var expires time.Time
+	ExpectCall(t.presigner, "Presign")(Any(), Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request, e time.Time) error {
+			httpReq = r
+			expires = e
+			return errors.New("")
+		}))
+
+	// Call
+	opts := &PresignOptions{
+		Verb:        "PUT",
+		Expiry:      time.Hour,
+		ContentType: "text/plain",
+	}
+
+	t.bucket.PresignUrl("foo/bar", opts)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("PUT", httpReq.Verb)
+	ExpectEq("/some.bucket/foo/bar", httpReq.Path)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", httpReq.Headers["Date"])
+	ExpectEq("text/plain", httpReq.Headers["Content-Type"])
+	ExpectTrue(expires.Equal(t.clock.now.Add(time.Hour)), "%v", expires)
+}
+
+func (t *PresignUrlTest) CallsV4Presigner() {
+	// Presigner
+	var httpReq *http.Request
+	ExpectCall(t.v4Presigner, "Presign")(Any(), Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request, e time.Time) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	// Call
+	opts := &PresignOptions{Expiry: time.Hour, UseSignatureV4: true}
+	t.bucket.PresignUrl("a", opts)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("GET", httpReq.Verb)
+	ExpectEq("/some.bucket/a", httpReq.Path)
+}
+
+func (t *PresignUrlTest) PresignerReturnsError() {
+	// Presigner
+	ExpectCall(t.presigner, "Presign")(Any(), Any()).
+		WillOnce(oglemock.Return(errors.New("taco")))
+
+	// Call
+	_, err := t.bucket.PresignUrl("a", &PresignOptions{Expiry: time.Hour})
+
+	ExpectThat(err, ErrorThat(HasSubstr("Presign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *PresignUrlTest) ReturnsUrl() {
+	// Presigner
+	ExpectCall(t.presigner, "Presign")(Any(), Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request, e time.Time) error {
+			r.Parameters["Expires"] = "1175139620"
+			r.Parameters["Signature"] = "a+b/c="
+			return nil
+		}))
+
+	// Call
+	u, err := t.bucket.PresignUrl("타코 ?", &PresignOptions{Expiry: time.Hour})
+	AssertEq(nil, err)
+
+	ExpectEq(
+		"https://s3.example.com/some.bucket/%ED%83%80%EC%BD%94%20%3F"+
+			"?Expires=1175139620&Signature=a%2Bb%2Fc%3D",
+		u)
+}
@@ -0,0 +1,195 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Return the value of an HTTP Range header for the arguments to
+// GetObjectRange.
+//
+// Reference:
+//     http://www.w3.org/Protocols/rfc2616/rfc2616-sec14.html#sec14.35
+func makeRangeHeader(offset int64, length int64) (string, error) {
+	switch {
+	case offset < 0:
+		return fmt.Sprintf("bytes=-%d", -offset), nil
+
+	case length < 0:
+		return fmt.Sprintf("bytes=%d-", offset), nil
+
+	case length == 0:
+		return "", fmt.Errorf("Length must be non-zero.")
+
+	default:
+		return fmt.Sprintf("bytes=%d-%d", offset, offset+length-1), nil
+	}
+}
+
+// Parse the total object size from a Content-Range header of the form
+// "bytes 0-9/1234" or "bytes */1234", returning -1 if the size is given as
+// "*".
+func parseContentRangeSize(contentRange string) (int64, error) {
+	slash := strings.LastIndex(contentRange, "/")
+	if !strings.HasPrefix(contentRange, "bytes ") || slash < 0 {
+		return 0, fmt.Errorf("Invalid Content-Range: %q", contentRange)
+	}
+
+	sizeStr := contentRange[slash+1:]
+	if sizeStr == "*" {
+		return -1, nil
+	}
+
+	size, err := strconv.ParseInt(sizeStr, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("Invalid Content-Range: %q", contentRange)
+	}
+
+	return size, nil
+}
+
+type invalidRangeError struct {
+	ActualObjectSize int64
+}
+
+// Build an error for a 416 response, consuming and closing its body. S3
+// includes the object size in its XML error document; other servers may use a
+// Content-Range header of the form "bytes */1234" instead.
+func makeRangeNotSatisfiableError(
+	rangeHeader string,
+	headers map[string]string,
+	body io.ReadCloser) error {
+	defer body.Close()
+
+	err := &RangeNotSatisfiableError{Range: rangeHeader, ObjectSize: -1}
+
+	if contentRange, ok := headers["Content-Range"]; ok {
+		if size, parseErr := parseContentRangeSize(contentRange); parseErr == nil {
+			err.ObjectSize = size
+		}
+	}
+
+	if data, readErr := ioutil.ReadAll(body); readErr == nil {
+		parsed := invalidRangeError{ActualObjectSize: -1}
+		if xml.Unmarshal(data, &parsed) == nil && parsed.ActualObjectSize >= 0 {
+			err.ObjectSize = parsed.ActualObjectSize
+		}
+	}
+
+	return err
+}
+
+// Options for Bucket.GetObjectRangeWithOptions. The zero value is equivalent
+// to calling GetObjectRange.
+type RangeOptions struct {
+	// The key with which the object was encrypted, if it was stored with
+	// StoreOptions.CustomerKey. S3 refuses to return such objects without it.
+	CustomerKey []byte
+}
+
+func (b *bucket) GetObjectRange(
+	key string,
+	offset int64,
+	length int64) (data []byte, objectSize int64, err error) {
+	return b.GetObjectRangeWithOptions(key, offset, length, nil)
+}
+
+func (b *bucket) GetObjectRangeWithOptions(
+	key string,
+	offset int64,
+	length int64,
+	opts *RangeOptions) (data []byte, objectSize int64, err error) {
+	if opts == nil {
+		opts = &RangeOptions{}
+	}
+
+	// Figure out what to ask for.
+	rangeHeader, err := makeRangeHeader(offset, length)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	headers := map[string]string{"Range": rangeHeader}
+	if err := addCustomerKeyHeaders(headers, opts.CustomerKey); err != nil {
+		return nil, 0, err
+	}
+
+	// Send the request.
+	httpResp, err := b.sendGetRequest(key, headers, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Check the response.
+	switch httpResp.StatusCode {
+	case 206:
+		// The server honored the range.
+		defer httpResp.Body.Close()
+
+		contentRange := httpResp.Headers["Content-Range"]
+		if objectSize, err = parseContentRangeSize(contentRange); err != nil {
+			return nil, 0, err
+		}
+
+		if data, err = ioutil.ReadAll(httpResp.Body); err != nil {
+			return nil, 0, fmt.Errorf("ReadAll: %v", err)
+		}
+
+		return data, objectSize, nil
+
+	case 200:
+		// The server ignored the range and sent the whole object, so we must
+		// extract the range ourselves.
+		defer httpResp.Body.Close()
+
+		var all []byte
+		if all, err = ioutil.ReadAll(httpResp.Body); err != nil {
+			return nil, 0, fmt.Errorf("ReadAll: %v", err)
+		}
+
+		objectSize = int64(len(all))
+
+		start, end := offset, objectSize
+		if offset < 0 {
+			start = objectSize + offset
+			if start < 0 {
+				start = 0
+			}
+		} else if length >= 0 && offset+length < objectSize {
+			end = offset + length
+		}
+
+		if start >= objectSize && !(offset < 0 && objectSize == 0) {
+			err = &RangeNotSatisfiableError{Range: rangeHeader, ObjectSize: objectSize}
+			return nil, 0, err
+		}
+
+		return all[start:end], objectSize, nil
+
+	case 416:
+		err = makeRangeNotSatisfiableError(rangeHeader, httpResp.Headers, httpResp.Body)
+		return nil, 0, err
+
+	default:
+		return nil, 0, streamingResponseError(httpResp)
+	}
+}
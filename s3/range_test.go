@@ -0,0 +1,304 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"testing"
+	"time"
+)
+
+func TestRange(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type GetObjectRangeTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&GetObjectRangeTest{}) }
+
+// Call GetObjectRange with the supplied arguments, returning the request
+// passed to the signer.
+func (t *GetObjectRangeTest) captureRequest(offset, length int64) *http.Request {
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	t.bucket.GetObjectRange("a", offset, length)
+	return httpReq
+}
+
+// Set up the signer and conn to return the supplied response.
+func (t *GetObjectRangeTest) respondWith(resp *http.StreamingResponse) {
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *GetObjectRangeTest) KeyIsEmpty() {
+	// Call
+	_, _, err := t.bucket.GetObjectRange("", 0, 1)
+
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *GetObjectRangeTest) ZeroLength() {
+	// Call
+	_, _, err := t.bucket.GetObjectRange("a", 17, 0)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Length")))
+}
+
+func (t *GetObjectRangeTest) CallsSigner() {
+	// Clock
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+
+	// Call
+	httpReq := t.captureRequest(17, 10)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("GET", httpReq.Verb)
+	ExpectEq("/some.bucket/a", httpReq.Path)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", httpReq.Headers["Date"])
+	ExpectEq("bytes=17-26", httpReq.Headers["Range"])
+}
+
+func (t *GetObjectRangeTest) CustomerKey() {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	// Signer
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	// Call
+	t.bucket.GetObjectRangeWithOptions("a", 17, 10, &RangeOptions{CustomerKey: key})
+
+	AssertNe(nil, httpReq)
+	ExpectEq("bytes=17-26", httpReq.Headers["Range"])
+	ExpectEq("AES256", httpReq.Headers["x-amz-server-side-encryption-customer-algorithm"])
+	ExpectEq(
+		"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+		httpReq.Headers["x-amz-server-side-encryption-customer-key"])
+	ExpectEq(
+		computeBase64Md5(key),
+		httpReq.Headers["x-amz-server-side-encryption-customer-key-MD5"])
+}
+
+func (t *GetObjectRangeTest) CustomerKeyHasWrongLength() {
+	// Call
+	_, _, err := t.bucket.GetObjectRangeWithOptions(
+		"a",
+		17,
+		10,
+		&RangeOptions{CustomerKey: []byte("taco")})
+
+	ExpectThat(err, ErrorThat(HasSubstr("32 bytes long; got 4")))
+}
+
+func (t *GetObjectRangeTest) OpenEndedRange() {
+	httpReq := t.captureRequest(17, -1)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("bytes=17-", httpReq.Headers["Range"])
+}
+
+func (t *GetObjectRangeTest) SuffixRange() {
+	httpReq := t.captureRequest(-8, 100)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("bytes=-8", httpReq.Headers["Range"])
+}
+
+func (t *GetObjectRangeTest) ConnReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	ExpectCall(t.httpConn, "StreamRequest")(Any()).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	_, _, err := t.bucket.GetObjectRange("a", 0, 1)
+
+	ExpectThat(err, ErrorThat(HasSubstr("StreamRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *GetObjectRangeTest) ServerReturnsError() {
+	body := newFakeBody("taco")
+	t.respondWith(&http.StreamingResponse{StatusCode: 500, Body: body})
+
+	// Call
+	_, _, err := t.bucket.GetObjectRange("a", 0, 1)
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("500")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+	ExpectTrue(body.closed)
+}
+
+func (t *GetObjectRangeTest) ServerSaysRangeNotSatisfiable() {
+	body := newFakeBody(`
+		<?xml version="1.0" encoding="UTF-8"?>
+		<Error>
+			<Code>InvalidRange</Code>
+			<Message>The requested range is not satisfiable</Message>
+			<RangeRequested>bytes=100-109</RangeRequested>
+			<ActualObjectSize>17</ActualObjectSize>
+		</Error>`)
+
+	t.respondWith(&http.StreamingResponse{
+		StatusCode: 416,
+		Body:       body,
+		Headers:    map[string]string{},
+	})
+
+	// Call
+	_, _, err := t.bucket.GetObjectRange("a", 100, 10)
+
+	rangeErr, ok := err.(*RangeNotSatisfiableError)
+	AssertTrue(ok, "%v", err)
+	ExpectEq("bytes=100-109", rangeErr.Range)
+	ExpectEq(17, rangeErr.ObjectSize)
+	ExpectTrue(body.closed)
+}
+
+func (t *GetObjectRangeTest) ServerSaysRangeNotSatisfiableWithContentRange() {
+	t.respondWith(&http.StreamingResponse{
+		StatusCode: 416,
+		Body:       newFakeBody(""),
+		Headers: map[string]string{
+			"Content-Range": "bytes */23",
+		},
+	})
+
+	// Call
+	_, _, err := t.bucket.GetObjectRange("a", 100, 10)
+
+	rangeErr, ok := err.(*RangeNotSatisfiableError)
+	AssertTrue(ok, "%v", err)
+	ExpectEq(23, rangeErr.ObjectSize)
+}
+
+func (t *GetObjectRangeTest) ContentRangeMissing() {
+	t.respondWith(&http.StreamingResponse{
+		StatusCode: 206,
+		Body:       newFakeBody("taco"),
+		Headers:    map[string]string{},
+	})
+
+	// Call
+	_, _, err := t.bucket.GetObjectRange("a", 0, 4)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Content-Range")))
+}
+
+func (t *GetObjectRangeTest) ContentRangeIsJunk() {
+	t.respondWith(&http.StreamingResponse{
+		StatusCode: 206,
+		Body:       newFakeBody("taco"),
+		Headers: map[string]string{
+			"Content-Range": "bytes 0-3/burrito",
+		},
+	})
+
+	// Call
+	_, _, err := t.bucket.GetObjectRange("a", 0, 4)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Content-Range")))
+	ExpectThat(err, ErrorThat(HasSubstr("burrito")))
+}
+
+func (t *GetObjectRangeTest) ReturnsPartialContent() {
+	body := newFakeBody("taco")
+	t.respondWith(&http.StreamingResponse{
+		StatusCode: 206,
+		Body:       body,
+		Headers: map[string]string{
+			"Content-Range": "bytes 17-20/1234",
+		},
+	})
+
+	// Call
+	data, size, err := t.bucket.GetObjectRange("a", 17, 4)
+	AssertEq(nil, err)
+
+	ExpectEq("taco", string(data))
+	ExpectEq(1234, size)
+	ExpectTrue(body.closed)
+}
+
+func (t *GetObjectRangeTest) ServerIgnoresRange() {
+	t.respondWith(&http.StreamingResponse{
+		StatusCode: 200,
+		Body:       newFakeBody("tacoburrito"),
+	})
+
+	// Call
+	data, size, err := t.bucket.GetObjectRange("a", 4, 3)
+	AssertEq(nil, err)
+
+	ExpectEq("bur", string(data))
+	ExpectEq(11, size)
+}
+
+func (t *GetObjectRangeTest) ServerIgnoresSuffixRange() {
+	t.respondWith(&http.StreamingResponse{
+		StatusCode: 200,
+		Body:       newFakeBody("tacoburrito"),
+	})
+
+	// Call
+	data, size, err := t.bucket.GetObjectRange("a", -4, -1)
+	AssertEq(nil, err)
+
+	ExpectEq("rito", string(data))
+	ExpectEq(11, size)
+}
+
+func (t *GetObjectRangeTest) ServerIgnoresUnsatisfiableRange() {
+	t.respondWith(&http.StreamingResponse{
+		StatusCode: 200,
+		Body:       newFakeBody("taco"),
+	})
+
+	// Call
+	_, _, err := t.bucket.GetObjectRange("a", 4, 3)
+
+	rangeErr, ok := err.(*RangeNotSatisfiableError)
+	AssertTrue(ok, "%v", err)
+	ExpectEq(4, rangeErr.ObjectSize)
+}
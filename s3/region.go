@@ -15,6 +15,10 @@
 
 package s3
 
+import (
+	"strings"
+)
+
 // Region represents a regional endpoint to S3. Resources created within one
 // region are entirely independent of those created in others. You should use
 // one of the region constants defined by this package when referring to
@@ -36,3 +40,22 @@ const (
 	RegionApacTokyo            Region = "s3-ap-northeast-1.amazonaws.com"
 	RegionSouthAmericaSaoPaulo Region = "s3-sa-east-1.amazonaws.com"
 )
+
+// Return the name by which the region is identified in AWS Signature Version
+// 4 credential scopes, e.g. "us-west-2".
+func regionName(r Region) string {
+	host := strings.TrimSuffix(string(r), ".amazonaws.com")
+	switch {
+	case host == "s3" || host == "s3-external-1":
+		return "us-east-1"
+
+	case strings.HasPrefix(host, "s3-"):
+		return strings.TrimPrefix(host, "s3-")
+
+	case strings.HasPrefix(host, "s3."):
+		return strings.TrimPrefix(host, "s3.")
+	}
+
+	// We don't know; guess at the default region.
+	return "us-east-1"
+}
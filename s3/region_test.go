@@ -0,0 +1,55 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	. "github.com/jacobsa/ogletest"
+	"testing"
+)
+
+func TestRegion(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type RegionTest struct {
+}
+
+func init() { RegisterTestSuite(&RegionTest{}) }
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *RegionTest) KnownRegions() {
+	ExpectEq("us-east-1", regionName(RegionUsStandard))
+	ExpectEq("us-west-2", regionName(RegionUsWestOregon))
+	ExpectEq("us-west-1", regionName(RegionUsWestNorCal))
+	ExpectEq("eu-west-1", regionName(RegionEuIreland))
+	ExpectEq("ap-southeast-1", regionName(RegionApacSingapore))
+	ExpectEq("ap-southeast-2", regionName(RegionApacSydney))
+	ExpectEq("ap-northeast-1", regionName(RegionApacTokyo))
+	ExpectEq("sa-east-1", regionName(RegionSouthAmericaSaoPaulo))
+}
+
+func (t *RegionTest) DottedHostName() {
+	ExpectEq("eu-central-1", regionName(Region("s3.eu-central-1.amazonaws.com")))
+}
+
+func (t *RegionTest) UnknownHostName() {
+	ExpectEq("us-east-1", regionName(Region("localhost:9000")))
+}
@@ -0,0 +1,73 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"fmt"
+	"github.com/jacobsa/aws/s3/auth"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/aws/time"
+	"strings"
+	sys_time "time"
+)
+
+// RetryPolicy controls how a bucket opened with WithRetryPolicy retries
+// requests that fail with a 5xx status, such as a 503 "SlowDown" response, or
+// with a transient network error: a timeout, or a refused or reset connection
+// for a request that is safe to repeat.
+type RetryPolicy struct {
+	// The maximum number of times a request is sent, including the first.
+	MaxAttempts int
+
+	// The delay before the first retry. The delay doubles with each subsequent
+	// retry, up to MaxBackoff if it is positive. Each delay is jittered by
+	// choosing it at random from the upper half of this range.
+	InitialBackoff sys_time.Duration
+	MaxBackoff     sys_time.Duration
+}
+
+// A reasonable policy for batch jobs: five attempts spread over a few seconds.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 200 * sys_time.Millisecond,
+	MaxBackoff:     5 * sys_time.Second,
+}
+
+// Return a function that prepares an already-signed request to be sent again,
+// by updating its Date header and re-signing it. The signature covers the
+// Date header, and S3 rejects requests dated too far in the past.
+func resignRequest(
+	signer auth.Signer,
+	clock time.Clock) func(*http.Request) error {
+	return func(r *http.Request) error {
+		// Remove the fruits of the previous signature, which would otherwise be
+		// signed themselves or be taken as the new date.
+		for name := range r.Headers {
+			switch strings.ToLower(name) {
+			case "authorization", "x-amz-date":
+				delete(r.Headers, name)
+			}
+		}
+
+		r.Headers["Date"] = clock.Now().UTC().Format(sys_time.RFC1123)
+
+		if err := signer.Sign(r); err != nil {
+			return fmt.Errorf("Sign: %v", err)
+		}
+
+		return nil
+	}
+}
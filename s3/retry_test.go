@@ -0,0 +1,102 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"github.com/jacobsa/aws/s3/auth/mock"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type ResignRequestTest struct {
+	signer  mock_auth.MockSigner
+	clock   *fakeClock
+	resign  func(*http.Request) error
+	httpReq *http.Request
+}
+
+func init() { RegisterTestSuite(&ResignRequestTest{}) }
+
+func (t *ResignRequestTest) SetUp(i *TestInfo) {
+	t.signer = mock_auth.NewMockSigner(i.MockController, "signer")
+	t.clock = &fakeClock{}
+	t.resign = resignRequest(t.signer, t.clock)
+
+	// A request that has already been signed once.
+	t.httpReq = &http.Request{
+		Verb: "GET",
+		Path: "/foo",
+		Headers: map[string]string{
+			"Date":          "Mon, 18 Mar 1985 15:33:17 UTC",
+			"X-Amz-Date":    "19850318T153317Z",
+			"authorization": "taco",
+			"x-amz-meta-a":  "b",
+		},
+	}
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *ResignRequestTest) UpdatesDateAndSigns() {
+	t.clock.now = time.Date(1985, 3, 18, 15, 34, 17, 0, time.UTC)
+
+	// Signer
+	var headers map[string]string
+	ExpectCall(t.signer, "Sign")(t.httpReq).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			headers = make(map[string]string)
+			for k, v := range r.Headers {
+				headers[k] = v
+			}
+
+			return nil
+		}))
+
+	// Call
+	err := t.resign(t.httpReq)
+	AssertEq(nil, err)
+
+	ExpectThat(
+		headers,
+		DeepEquals(map[string]string{
+			"Date":         "Mon, 18 Mar 1985 15:34:17 UTC",
+			"x-amz-meta-a": "b",
+		}))
+}
+
+func (t *ResignRequestTest) SignerReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(errors.New("taco")))
+
+	// Call
+	err := t.resign(t.httpReq)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
@@ -0,0 +1,381 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3test
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/jacobsa/aws/s3"
+	"github.com/jacobsa/aws/s3/auth"
+	"github.com/jacobsa/aws/s3/http"
+	sys_http "net/http"
+	"strconv"
+	"strings"
+	sys_time "time"
+)
+
+// Parameters added by the presigners, which must be removed from a request
+// before presigning it again.
+var presignParams = []string{
+	"AWSAccessKeyId",
+	"Expires",
+	"Signature",
+	"X-Amz-Algorithm",
+	"X-Amz-Credential",
+	"X-Amz-Date",
+	"X-Amz-Expires",
+	"X-Amz-SignedHeaders",
+	"X-Amz-Signature",
+}
+
+const v4TimeFormat = "20060102T150405Z"
+
+func accessDenied(msg string) *s3.Error {
+	return &s3.Error{StatusCode: 403, Code: "AccessDenied", Message: msg}
+}
+
+func signatureDoesNotMatch() *s3.Error {
+	return &s3.Error{
+		StatusCode: 403,
+		Code:       "SignatureDoesNotMatch",
+		Message: "The request signature we calculated does not match the " +
+			"signature you provided.",
+	}
+}
+
+// Convert the supplied incoming request into the form the signers in package
+// auth accept, including only those headers for which include returns true.
+func convertRequest(
+	r *sys_http.Request,
+	body []byte,
+	include func(name string) bool) *http.Request {
+	req := &http.Request{
+		Verb:       r.Method,
+		Path:       r.URL.Path,
+		Body:       body,
+		Headers:    map[string]string{},
+		Parameters: map[string]string{},
+	}
+
+	for name, vals := range r.Header {
+		name = strings.ToLower(name)
+		if include(name) {
+			req.Headers[name] = strings.Join(vals, ",")
+		}
+	}
+
+	for name, vals := range r.URL.Query() {
+		req.Parameters[name] = vals[0]
+	}
+
+	for _, name := range presignParams {
+		delete(req.Parameters, name)
+	}
+
+	return req
+}
+
+// Check that the supplied request was signed with the server's access key,
+// either by auth.Signer or by auth.Presigner, returning an error response if
+// not.
+func (s *Server) authenticate(r *sys_http.Request, body []byte) *s3.Error {
+	query := r.URL.Query()
+	authorization := r.Header.Get("Authorization")
+
+	switch {
+	case strings.HasPrefix(authorization, "AWS "):
+		return s.checkV2(r, body, authorization)
+
+	case strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 "):
+		return s.checkV4(r, body, authorization)
+
+	case query.Get("Signature") != "":
+		return s.checkPresignedV2(r, query)
+
+	case query.Get("X-Amz-Signature") != "":
+		return s.checkPresignedV4(r, query)
+	}
+
+	return accessDenied("Anonymous access is not allowed.")
+}
+
+func (s *Server) checkKeyId(id string) *s3.Error {
+	if id != s.key.Id {
+		return &s3.Error{
+			StatusCode: 403,
+			Code:       "InvalidAccessKeyId",
+			Message:    "The access key ID you provided does not exist in our records.",
+		}
+	}
+
+	return nil
+}
+
+// Signature Version 2 signs the Content-MD5, Content-Type, and Date headers,
+// along with every x-amz-* header.
+func includeV2Header(name string) bool {
+	switch name {
+	case "content-md5", "content-type", "date":
+		return true
+	}
+
+	return strings.HasPrefix(name, "x-amz-")
+}
+
+// Fix up the names of the headers that the V2 string to sign looks up by
+// their canonical spelling.
+func canonicalizeV2Headers(req *http.Request) {
+	for lower, canonical := range map[string]string{
+		"content-md5":  "Content-MD5",
+		"content-type": "Content-Type",
+		"date":         "Date",
+	} {
+		if val, ok := req.Headers[lower]; ok {
+			delete(req.Headers, lower)
+			req.Headers[canonical] = val
+		}
+	}
+}
+
+func (s *Server) checkV2(
+	r *sys_http.Request,
+	body []byte,
+	authorization string) *s3.Error {
+	credential := strings.TrimPrefix(authorization, "AWS ")
+	colon := strings.LastIndex(credential, ":")
+	if colon < 0 {
+		return accessDenied("Malformed Authorization header.")
+	}
+
+	if errResp := s.checkKeyId(credential[:colon]); errResp != nil {
+		return errResp
+	}
+
+	req := convertRequest(r, body, includeV2Header)
+	canonicalizeV2Headers(req)
+
+	signer, err := auth.NewSigner(&s.key)
+	if err != nil {
+		return internalError(fmt.Errorf("NewSigner: %v", err))
+	}
+
+	if err := signer.Sign(req); err != nil {
+		return accessDenied(err.Error())
+	}
+
+	if !equalStrings(req.Headers["Authorization"], authorization) {
+		return signatureDoesNotMatch()
+	}
+
+	return nil
+}
+
+// The fields of a V4 Authorization header or the equivalent query
+// parameters.
+type v4Credential struct {
+	keyId         string
+	date          string
+	region        string
+	signedHeaders map[string]bool
+}
+
+// Parse a credential of the form "<key ID>/<date>/<region>/s3/aws4_request"
+// along with a semicolon-separated list of signed headers.
+func parseV4Credential(credential string, signedHeaders string) (*v4Credential, error) {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[3] != "s3" || parts[4] != "aws4_request" {
+		return nil, fmt.Errorf("Malformed credential: %q", credential)
+	}
+
+	c := &v4Credential{
+		keyId:         parts[0],
+		date:          parts[1],
+		region:        parts[2],
+		signedHeaders: map[string]bool{},
+	}
+
+	for _, name := range strings.Split(signedHeaders, ";") {
+		c.signedHeaders[name] = true
+	}
+
+	if !c.signedHeaders["host"] {
+		return nil, fmt.Errorf("The host header must be signed.")
+	}
+
+	return c, nil
+}
+
+func (s *Server) checkV4(
+	r *sys_http.Request,
+	body []byte,
+	authorization string) *s3.Error {
+	// Parse the header, which looks like:
+	//
+	//     AWS4-HMAC-SHA256 Credential=..., SignedHeaders=..., Signature=...
+	//
+	fields := map[string]string{}
+	for _, field := range strings.Split(strings.TrimPrefix(authorization, "AWS4-HMAC-SHA256 "), ",") {
+		field = strings.TrimSpace(field)
+		if eq := strings.Index(field, "="); eq >= 0 {
+			fields[field[:eq]] = field[eq+1:]
+		}
+	}
+
+	c, err := parseV4Credential(fields["Credential"], fields["SignedHeaders"])
+	if err != nil {
+		return accessDenied(err.Error())
+	}
+
+	if errResp := s.checkKeyId(c.keyId); errResp != nil {
+		return errResp
+	}
+
+	// The signer adds the host itself, and hashes the payload unless told that
+	// it is unsigned.
+	req := convertRequest(r, body, func(name string) bool {
+		return name != "host" && c.signedHeaders[name]
+	})
+
+	unsigned := r.Header.Get("x-amz-content-sha256") == "UNSIGNED-PAYLOAD"
+	signer, err := auth.NewV4Signer(&s.key, c.region, r.Host, unsigned)
+	if err != nil {
+		return internalError(fmt.Errorf("NewV4Signer: %v", err))
+	}
+
+	if err := signer.Sign(req); err != nil {
+		return accessDenied(err.Error())
+	}
+
+	if !equalStrings(req.Headers["Authorization"], authorization) {
+		return signatureDoesNotMatch()
+	}
+
+	return nil
+}
+
+func (s *Server) checkPresignedV2(
+	r *sys_http.Request,
+	query map[string][]string) *s3.Error {
+	get := func(name string) string {
+		if vals := query[name]; len(vals) > 0 {
+			return vals[0]
+		}
+
+		return ""
+	}
+
+	if errResp := s.checkKeyId(get("AWSAccessKeyId")); errResp != nil {
+		return errResp
+	}
+
+	expiresSecs, err := strconv.ParseInt(get("Expires"), 10, 64)
+	if err != nil {
+		return accessDenied(fmt.Sprintf("Invalid Expires parameter: %q", get("Expires")))
+	}
+
+	expires := sys_time.Unix(expiresSecs, 0)
+	if s.clock.Now().After(expires) {
+		return accessDenied("Request has expired.")
+	}
+
+	// The presigner substitutes the expiry time for the date.
+	req := convertRequest(r, nil, includeV2Header)
+	canonicalizeV2Headers(req)
+	req.Headers["Date"] = get("Expires")
+
+	presigner, err := auth.NewPresigner(&s.key)
+	if err != nil {
+		return internalError(fmt.Errorf("NewPresigner: %v", err))
+	}
+
+	if err := presigner.Presign(req, expires); err != nil {
+		return accessDenied(err.Error())
+	}
+
+	if !equalStrings(req.Parameters["Signature"], get("Signature")) {
+		return signatureDoesNotMatch()
+	}
+
+	return nil
+}
+
+func (s *Server) checkPresignedV4(
+	r *sys_http.Request,
+	query map[string][]string) *s3.Error {
+	get := func(name string) string {
+		if vals := query[name]; len(vals) > 0 {
+			return vals[0]
+		}
+
+		return ""
+	}
+
+	if get("X-Amz-Algorithm") != "AWS4-HMAC-SHA256" {
+		return accessDenied(fmt.Sprintf("Unsupported algorithm: %q", get("X-Amz-Algorithm")))
+	}
+
+	c, err := parseV4Credential(get("X-Amz-Credential"), get("X-Amz-SignedHeaders"))
+	if err != nil {
+		return accessDenied(err.Error())
+	}
+
+	if errResp := s.checkKeyId(c.keyId); errResp != nil {
+		return errResp
+	}
+
+	t, err := sys_time.Parse(v4TimeFormat, get("X-Amz-Date"))
+	if err != nil {
+		return accessDenied(fmt.Sprintf("Invalid X-Amz-Date parameter: %q", get("X-Amz-Date")))
+	}
+
+	expirySecs, err := strconv.ParseInt(get("X-Amz-Expires"), 10, 64)
+	if err != nil {
+		return accessDenied(fmt.Sprintf("Invalid X-Amz-Expires parameter: %q", get("X-Amz-Expires")))
+	}
+
+	expires := t.Add(sys_time.Duration(expirySecs) * sys_time.Second)
+	if s.clock.Now().After(expires) {
+		return accessDenied("Request has expired.")
+	}
+
+	// The presigner takes the signing time from the Date header, which it
+	// doesn't itself sign.
+	req := convertRequest(r, nil, func(name string) bool {
+		return name != "host" && name != "date" && c.signedHeaders[name]
+	})
+
+	req.Headers["Date"] = t.UTC().Format(sys_time.RFC1123)
+
+	presigner, err := auth.NewV4Presigner(&s.key, c.region, r.Host)
+	if err != nil {
+		return internalError(fmt.Errorf("NewV4Presigner: %v", err))
+	}
+
+	if err := presigner.Presign(req, expires); err != nil {
+		return accessDenied(err.Error())
+	}
+
+	if !equalStrings(req.Parameters["X-Amz-Signature"], get("X-Amz-Signature")) {
+		return signatureDoesNotMatch()
+	}
+
+	return nil
+}
+
+// Compare strings in constant time, as a real server would.
+func equalStrings(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,919 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"github.com/jacobsa/aws/s3"
+	"io"
+	sys_http "net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	sys_time "time"
+)
+
+////////////////////////////////////////////////////////////////////////
+// Service
+////////////////////////////////////////////////////////////////////////
+
+type listAllMyBucketsBucket struct {
+	Name         string
+	CreationDate string
+}
+
+type listAllMyBucketsResult struct {
+	XMLName xml.Name                 `xml:"ListAllMyBucketsResult"`
+	Buckets []listAllMyBucketsBucket `xml:"Buckets>Bucket"`
+}
+
+type createBucketConfiguration struct {
+	XMLName            xml.Name `xml:"CreateBucketConfiguration"`
+	LocationConstraint string
+}
+
+type locationConstraint struct {
+	XMLName  xml.Name `xml:"LocationConstraint"`
+	Location string   `xml:",chardata"`
+}
+
+func (s *Server) handleService(
+	w sys_http.ResponseWriter,
+	r *sys_http.Request) *s3.Error {
+	if r.Method != "GET" {
+		return methodNotAllowed()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.buckets))
+	for name := range s.buckets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	result := listAllMyBucketsResult{
+		Buckets: make([]listAllMyBucketsBucket, len(names)),
+	}
+
+	for i, name := range names {
+		result.Buckets[i] = listAllMyBucketsBucket{
+			Name:         name,
+			CreationDate: s.buckets[name].creationDate.Format(xmlTimeFormat),
+		}
+	}
+
+	writeXml(w, 200, &result)
+	return nil
+}
+
+func (s *Server) createBucket(
+	w sys_http.ResponseWriter,
+	body []byte,
+	bucketName string) *s3.Error {
+	// The body, if any, specifies the location of the bucket.
+	config := createBucketConfiguration{}
+	if len(body) != 0 {
+		if err := xml.Unmarshal(body, &config); err != nil {
+			return malformedXml(err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.buckets[bucketName]; ok {
+		return &s3.Error{
+			StatusCode: 409,
+			Code:       "BucketAlreadyOwnedByYou",
+			Message:    "Your previous request to create the named bucket succeeded and you already own it.",
+		}
+	}
+
+	s.buckets[bucketName] = s.newBucketEntry(bucketName, config.LocationConstraint)
+
+	w.WriteHeader(200)
+	return nil
+}
+
+func (s *Server) deleteBucket(
+	w sys_http.ResponseWriter,
+	r *sys_http.Request,
+	bucketName string) *s3.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.buckets[bucketName]
+	if !ok {
+		return noSuchBucket()
+	}
+
+	// Only empty buckets may be deleted.
+	result, err := entry.bucket.WithContext(r.Context()).ListObjects(
+		s3.ListRequest{MaxKeys: 1})
+
+	if err != nil {
+		return convertError(err, "")
+	}
+
+	if len(result.Keys) != 0 {
+		return &s3.Error{
+			StatusCode: 409,
+			Code:       "BucketNotEmpty",
+			Message:    "The bucket you tried to delete is not empty.",
+		}
+	}
+
+	delete(s.buckets, bucketName)
+
+	w.WriteHeader(204)
+	return nil
+}
+
+func (s *Server) getBucketLocation(
+	w sys_http.ResponseWriter,
+	bucketName string) *s3.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.buckets[bucketName]
+	if !ok {
+		return noSuchBucket()
+	}
+
+	writeXml(w, 200, &locationConstraint{Location: entry.location})
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// Buckets
+////////////////////////////////////////////////////////////////////////
+
+type listBucketContents struct {
+	Key string
+}
+
+type listBucketCommonPrefixes struct {
+	Prefix string
+}
+
+type listBucketResult struct {
+	XMLName        xml.Name `xml:"ListBucketResult"`
+	Name           string
+	Prefix         string
+	Marker         string
+	MaxKeys        int
+	Delimiter      string `xml:",omitempty"`
+	IsTruncated    bool
+	NextMarker     string `xml:",omitempty"`
+	Contents       []listBucketContents
+	CommonPrefixes []listBucketCommonPrefixes
+}
+
+type versioningConfiguration struct {
+	XMLName xml.Name            `xml:"VersioningConfiguration"`
+	Status  s3.VersioningStatus `xml:",omitempty"`
+}
+
+type listVersionsEntry struct {
+	XMLName      xml.Name
+	Key          string
+	VersionId    string
+	IsLatest     bool
+	LastModified string
+	ETag         string `xml:",omitempty"`
+	Size         *int64 `xml:",omitempty"`
+}
+
+type listVersionsResult struct {
+	XMLName             xml.Name `xml:"ListVersionsResult"`
+	Name                string
+	Prefix              string
+	KeyMarker           string
+	VersionIdMarker     string
+	MaxKeys             int
+	Delimiter           string `xml:",omitempty"`
+	IsTruncated         bool
+	NextKeyMarker       string `xml:",omitempty"`
+	NextVersionIdMarker string `xml:",omitempty"`
+	Entries             []listVersionsEntry
+	CommonPrefixes      []listBucketCommonPrefixes
+}
+
+type deleteRequestObject struct {
+	Key string
+}
+
+type deleteRequest struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool
+	Object  []deleteRequestObject
+}
+
+type deleteResultDeleted struct {
+	Key string
+}
+
+type deleteResult struct {
+	XMLName xml.Name `xml:"DeleteResult"`
+	Deleted []deleteResultDeleted
+	Error   []s3.DeleteObjectError
+}
+
+func (s *Server) handleBucket(
+	w sys_http.ResponseWriter,
+	r *sys_http.Request,
+	query url.Values,
+	body []byte,
+	bucket s3.Bucket,
+	bucketName string) *s3.Error {
+	switch {
+	case r.Method == "GET" && hasParam(query, "versioning"):
+		return getVersioning(w, bucket)
+
+	case r.Method == "PUT" && hasParam(query, "versioning"):
+		return setVersioning(w, body, bucket)
+
+	case r.Method == "GET" && hasParam(query, "versions"):
+		return listObjectVersions(w, query, bucket, bucketName)
+
+	case r.Method == "GET":
+		return listObjects(w, query, bucket, bucketName)
+
+	case r.Method == "POST" && hasParam(query, "delete"):
+		return deleteObjects(w, body, bucket)
+	}
+
+	return methodNotAllowed()
+}
+
+func hasParam(query url.Values, name string) bool {
+	_, ok := query[name]
+	return ok
+}
+
+func listObjects(
+	w sys_http.ResponseWriter,
+	query url.Values,
+	bucket s3.Bucket,
+	bucketName string) *s3.Error {
+	req := s3.ListRequest{
+		Prefix:    query.Get("prefix"),
+		Delimiter: query.Get("delimiter"),
+		Marker:    query.Get("marker"),
+	}
+
+	if maxKeys := query.Get("max-keys"); maxKeys != "" {
+		var err error
+		if req.MaxKeys, err = strconv.Atoi(maxKeys); err != nil {
+			return convertError(fmt.Errorf("Invalid max-keys: %q", maxKeys), "")
+		}
+	}
+
+	result, err := bucket.ListObjects(req)
+	if err != nil {
+		return convertError(err, "")
+	}
+
+	resp := listBucketResult{
+		Name:        bucketName,
+		Prefix:      req.Prefix,
+		Marker:      req.Marker,
+		MaxKeys:     req.MaxKeys,
+		Delimiter:   req.Delimiter,
+		IsTruncated: result.IsTruncated,
+		NextMarker:  result.NextMarker,
+	}
+
+	for _, key := range result.Keys {
+		resp.Contents = append(resp.Contents, listBucketContents{key})
+	}
+
+	for _, prefix := range result.CommonPrefixes {
+		resp.CommonPrefixes = append(resp.CommonPrefixes, listBucketCommonPrefixes{prefix})
+	}
+
+	writeXml(w, 200, &resp)
+	return nil
+}
+
+func getVersioning(w sys_http.ResponseWriter, bucket s3.Bucket) *s3.Error {
+	status, err := bucket.GetVersioning()
+	if err != nil {
+		return convertError(err, "")
+	}
+
+	writeXml(w, 200, &versioningConfiguration{Status: status})
+	return nil
+}
+
+func setVersioning(
+	w sys_http.ResponseWriter,
+	body []byte,
+	bucket s3.Bucket) *s3.Error {
+	config := versioningConfiguration{}
+	if err := xml.Unmarshal(body, &config); err != nil {
+		return malformedXml(err)
+	}
+
+	if err := bucket.SetVersioning(config.Status); err != nil {
+		return convertError(err, "")
+	}
+
+	w.WriteHeader(200)
+	return nil
+}
+
+func listObjectVersions(
+	w sys_http.ResponseWriter,
+	query url.Values,
+	bucket s3.Bucket,
+	bucketName string) *s3.Error {
+	req := s3.ListVersionsRequest{
+		Prefix:          query.Get("prefix"),
+		Delimiter:       query.Get("delimiter"),
+		KeyMarker:       query.Get("key-marker"),
+		VersionIdMarker: query.Get("version-id-marker"),
+	}
+
+	if maxKeys := query.Get("max-keys"); maxKeys != "" {
+		var err error
+		if req.MaxKeys, err = strconv.Atoi(maxKeys); err != nil {
+			return convertError(fmt.Errorf("Invalid max-keys: %q", maxKeys), "")
+		}
+	}
+
+	result, err := bucket.ListObjectVersions(req)
+	if err != nil {
+		return convertError(err, "")
+	}
+
+	resp := listVersionsResult{
+		Name:                bucketName,
+		Prefix:              req.Prefix,
+		KeyMarker:           req.KeyMarker,
+		VersionIdMarker:     req.VersionIdMarker,
+		MaxKeys:             req.MaxKeys,
+		Delimiter:           req.Delimiter,
+		IsTruncated:         result.IsTruncated,
+		NextKeyMarker:       result.NextKeyMarker,
+		NextVersionIdMarker: result.NextVersionIdMarker,
+	}
+
+	// Versions and delete markers are distinguished by element name.
+	for _, v := range result.Versions {
+		entry := listVersionsEntry{
+			XMLName:      xml.Name{Local: "Version"},
+			Key:          v.Key,
+			VersionId:    v.VersionId,
+			IsLatest:     v.IsLatest,
+			LastModified: v.LastModified.UTC().Format(xmlTimeFormat),
+		}
+
+		if v.IsDeleteMarker {
+			entry.XMLName.Local = "DeleteMarker"
+		} else {
+			size := v.Size
+			entry.ETag = v.ETag
+			entry.Size = &size
+		}
+
+		resp.Entries = append(resp.Entries, entry)
+	}
+
+	for _, prefix := range result.CommonPrefixes {
+		resp.CommonPrefixes = append(resp.CommonPrefixes, listBucketCommonPrefixes{prefix})
+	}
+
+	writeXml(w, 200, &resp)
+	return nil
+}
+
+func deleteObjects(
+	w sys_http.ResponseWriter,
+	body []byte,
+	bucket s3.Bucket) *s3.Error {
+	req := deleteRequest{}
+	if err := xml.Unmarshal(body, &req); err != nil {
+		return malformedXml(err)
+	}
+
+	keys := make([]string, len(req.Object))
+	for i, o := range req.Object {
+		keys[i] = o.Key
+	}
+
+	result, err := bucket.DeleteObjects(keys, req.Quiet)
+	if err != nil {
+		return convertError(err, "")
+	}
+
+	resp := deleteResult{Error: result.Errors}
+	for _, key := range result.Deleted {
+		resp.Deleted = append(resp.Deleted, deleteResultDeleted{key})
+	}
+
+	writeXml(w, 200, &resp)
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// Objects
+////////////////////////////////////////////////////////////////////////
+
+type copyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	LastModified string
+	ETag         string
+}
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Bucket   string
+	Key      string
+	UploadId string
+}
+
+type completeMultipartUploadPart struct {
+	PartNumber int
+	ETag       string
+}
+
+type completeMultipartUploadRequest struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Part    []completeMultipartUploadPart
+}
+
+type completeMultipartUploadResult struct {
+	XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	Bucket  string
+	Key     string
+	ETag    string
+}
+
+func (s *Server) handleObject(
+	w sys_http.ResponseWriter,
+	r *sys_http.Request,
+	query url.Values,
+	body []byte,
+	bucket s3.Bucket,
+	bucketName string,
+	key string) *s3.Error {
+	switch r.Method {
+	case "GET", "HEAD":
+		if r.Method == "GET" && r.Header.Get("Range") != "" {
+			return getObjectRange(w, r, bucket, key)
+		}
+
+		return getObject(w, r, bucket, key)
+
+	case "PUT":
+		switch {
+		case r.Header.Get("x-amz-copy-source") != "":
+			return copyObject(w, r, bucket, bucketName, key)
+
+		case hasParam(query, "uploadId"):
+			return uploadPart(w, query, body, bucket, key)
+		}
+
+		return storeObject(w, r, body, bucket, key)
+
+	case "POST":
+		switch {
+		case hasParam(query, "uploads"):
+			return initiateMultipartUpload(w, bucket, bucketName, key)
+
+		case hasParam(query, "uploadId"):
+			return completeMultipartUpload(w, query, body, bucket, bucketName, key)
+		}
+
+	case "DELETE":
+		if hasParam(query, "uploadId") {
+			if err := bucket.AbortMultipartUpload(key, query.Get("uploadId")); err != nil {
+				return convertError(err, key)
+			}
+		} else if hasParam(query, "versionId") {
+			if err := bucket.DeleteObjectVersion(key, query.Get("versionId")); err != nil {
+				return convertError(err, key)
+			}
+		} else if err := bucket.DeleteObject(key); err != nil {
+			return convertError(err, key)
+		}
+
+		w.WriteHeader(204)
+		return nil
+	}
+
+	return methodNotAllowed()
+}
+
+// Parse a time from a conditional request header, returning the zero time if
+// it is absent or invalid (in which case S3 ignores it).
+func parseTimeHeader(r *sys_http.Request, name string) sys_time.Time {
+	t, err := sys_http.ParseTime(r.Header.Get(name))
+	if err != nil {
+		return sys_time.Time{}
+	}
+
+	return t
+}
+
+// Parse the customer-provided encryption key supplied with a request, if any,
+// checking it against its MD5 sum as S3 does.
+func parseCustomerKey(r *sys_http.Request) ([]byte, *s3.Error) {
+	encoded := r.Header.Get("x-amz-server-side-encryption-customer-key")
+	if encoded == "" {
+		return nil, nil
+	}
+
+	invalid := func(msg string) *s3.Error {
+		return &s3.Error{
+			StatusCode: 400,
+			Code:       "InvalidArgument",
+			Message:    msg,
+		}
+	}
+
+	if r.Header.Get("x-amz-server-side-encryption-customer-algorithm") != "AES256" {
+		return nil, invalid("The encryption algorithm must be AES256.")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != s3.CustomerKeyLength {
+		return nil, invalid("The secret key was invalid for the specified algorithm.")
+	}
+
+	sum := md5.Sum(key)
+	if r.Header.Get("x-amz-server-side-encryption-customer-key-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+		return nil, invalid("The calculated MD5 hash of the key did not match the hash that was provided.")
+	}
+
+	return key, nil
+}
+
+// Set the headers describing the object to which the supplied info pertains.
+func setObjectHeaders(w sys_http.ResponseWriter, info *s3.ObjectInfo) {
+	h := w.Header()
+	h.Set("ETag", info.ETag)
+	h.Set("Last-Modified", info.LastModified.UTC().Format(sys_http.TimeFormat))
+	h.Set("Content-Type", info.ContentType)
+
+	if info.VersionId != "" {
+		h.Set("x-amz-version-id", info.VersionId)
+	}
+
+	if info.ServerSideEncryption != "" {
+		h.Set("x-amz-server-side-encryption", string(info.ServerSideEncryption))
+	}
+
+	if info.ContentEncoding != "" {
+		h.Set("Content-Encoding", info.ContentEncoding)
+	}
+
+	if info.CacheControl != "" {
+		h.Set("Cache-Control", info.CacheControl)
+	}
+
+	for name, val := range info.Metadata {
+		h.Set("x-amz-meta-"+name, val)
+	}
+}
+
+func getObject(
+	w sys_http.ResponseWriter,
+	r *sys_http.Request,
+	bucket s3.Bucket,
+	key string) *s3.Error {
+	customerKey, errResp := parseCustomerKey(r)
+	if errResp != nil {
+		return errResp
+	}
+
+	opts := &s3.GetOptions{
+		IfNoneMatch:       r.Header.Get("If-None-Match"),
+		IfModifiedSince:   parseTimeHeader(r, "If-Modified-Since"),
+		IfMatch:           r.Header.Get("If-Match"),
+		IfUnmodifiedSince: parseTimeHeader(r, "If-Unmodified-Since"),
+		VersionId:         r.URL.Query().Get("versionId"),
+		CustomerKey:       customerKey,
+	}
+
+	rc, info, err := bucket.GetObjectWithOptions(key, opts)
+	if err != nil {
+		return convertError(err, key)
+	}
+
+	defer rc.Close()
+
+	setObjectHeaders(w, info)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.WriteHeader(200)
+
+	if r.Method == "GET" {
+		io.Copy(w, rc)
+	}
+
+	return nil
+}
+
+// Parse the value of a Range header into arguments for GetObjectRange,
+// returning false if it is malformed (in which case S3 ignores it).
+func parseRange(rangeHeader string) (offset int64, length int64, ok bool) {
+	spec := strings.TrimPrefix(rangeHeader, "bytes=")
+	dash := strings.Index(spec, "-")
+	if spec == rangeHeader || dash < 0 || strings.Contains(spec, ",") {
+		return
+	}
+
+	first, last := spec[:dash], spec[dash+1:]
+	var err error
+
+	switch {
+	case first == "":
+		// A suffix range.
+		if offset, err = strconv.ParseInt(last, 10, 64); err != nil || offset <= 0 {
+			return
+		}
+
+		return -offset, 0, true
+
+	case last == "":
+		if offset, err = strconv.ParseInt(first, 10, 64); err != nil {
+			return
+		}
+
+		return offset, -1, true
+
+	default:
+		var end int64
+		if offset, err = strconv.ParseInt(first, 10, 64); err != nil {
+			return
+		}
+
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < offset {
+			return
+		}
+
+		return offset, end - offset + 1, true
+	}
+}
+
+func getObjectRange(
+	w sys_http.ResponseWriter,
+	r *sys_http.Request,
+	bucket s3.Bucket,
+	key string) *s3.Error {
+	offset, length, ok := parseRange(r.Header.Get("Range"))
+	if !ok {
+		return getObject(w, r, bucket, key)
+	}
+
+	customerKey, errResp := parseCustomerKey(r)
+	if errResp != nil {
+		return errResp
+	}
+
+	opts := &s3.RangeOptions{CustomerKey: customerKey}
+	data, size, err := bucket.GetObjectRangeWithOptions(key, offset, length, opts)
+	if rangeErr, ok := err.(*s3.RangeNotSatisfiableError); ok {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", rangeErr.ObjectSize))
+		writeErrorDocument(w, 416, &errorDocument{
+			Code:             "InvalidRange",
+			Message:          "The requested range is not satisfiable",
+			ActualObjectSize: &rangeErr.ObjectSize,
+		})
+
+		return nil
+	}
+
+	if err != nil {
+		return convertError(err, key)
+	}
+
+	// A suffix range of an empty object is the whole object.
+	if len(data) == 0 {
+		return getObject(w, r, bucket, key)
+	}
+
+	start := offset
+	if offset < 0 {
+		start = size - int64(len(data))
+	}
+
+	w.Header().Set(
+		"Content-Range",
+		fmt.Sprintf("bytes %d-%d/%d", start, start+int64(len(data))-1, size))
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(206)
+	w.Write(data)
+
+	return nil
+}
+
+// Return the user-defined metadata in the supplied request's headers.
+func metadataFromHeaders(r *sys_http.Request) map[string]string {
+	metadata := map[string]string{}
+	for name, vals := range r.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-meta-") {
+			metadata[strings.TrimPrefix(name, "x-amz-meta-")] = vals[0]
+		}
+	}
+
+	return metadata
+}
+
+func storeObject(
+	w sys_http.ResponseWriter,
+	r *sys_http.Request,
+	body []byte,
+	bucket s3.Bucket,
+	key string) *s3.Error {
+	customerKey, errResp := parseCustomerKey(r)
+	if errResp != nil {
+		return errResp
+	}
+
+	opts := &s3.StoreOptions{
+		ContentType:     r.Header.Get("Content-Type"),
+		ContentEncoding: r.Header.Get("Content-Encoding"),
+		CacheControl:    r.Header.Get("Cache-Control"),
+		Acl:             s3.CannedAcl(r.Header.Get("x-amz-acl")),
+		StorageClass:    s3.StorageClass(r.Header.Get("x-amz-storage-class")),
+		IfMatch:         r.Header.Get("If-Match"),
+		IfNoneMatch:     r.Header.Get("If-None-Match"),
+		Metadata:        metadataFromHeaders(r),
+
+		ServerSideEncryption: s3.ServerSideEncryption(r.Header.Get("x-amz-server-side-encryption")),
+		KmsKeyId:             r.Header.Get("x-amz-server-side-encryption-aws-kms-key-id"),
+		CustomerKey:          customerKey,
+	}
+
+	result, err := bucket.StoreObjectWithOptions(
+		key,
+		bytes.NewReader(body),
+		int64(len(body)),
+		opts)
+
+	if err != nil {
+		return convertError(err, key)
+	}
+
+	w.Header().Set("ETag", result.ETag)
+	if result.VersionId != "" {
+		w.Header().Set("x-amz-version-id", result.VersionId)
+	}
+
+	w.WriteHeader(200)
+	return nil
+}
+
+func copyObject(
+	w sys_http.ResponseWriter,
+	r *sys_http.Request,
+	bucket s3.Bucket,
+	bucketName string,
+	key string) *s3.Error {
+	// The source is a URL-encoded path of the form /bucket/key.
+	source, err := url.PathUnescape(r.Header.Get("x-amz-copy-source"))
+	if err != nil {
+		return convertError(fmt.Errorf("Invalid copy source: %v", err), key)
+	}
+
+	source = strings.TrimPrefix(source, "/")
+	slash := strings.Index(source, "/")
+	if slash < 0 {
+		return convertError(fmt.Errorf("Invalid copy source: %q", source), key)
+	}
+
+	if source[:slash] != bucketName {
+		return notImplemented()
+	}
+
+	opts := &s3.CopyOptions{
+		MetadataDirective: s3.MetadataDirective(r.Header.Get("x-amz-metadata-directive")),
+		Acl:               s3.CannedAcl(r.Header.Get("x-amz-acl")),
+		StorageClass:      s3.StorageClass(r.Header.Get("x-amz-storage-class")),
+	}
+
+	if opts.MetadataDirective == s3.MetadataReplace {
+		opts.ContentType = r.Header.Get("Content-Type")
+		opts.ContentEncoding = r.Header.Get("Content-Encoding")
+		opts.CacheControl = r.Header.Get("Cache-Control")
+		opts.Metadata = metadataFromHeaders(r)
+	}
+
+	result, err := bucket.CopyObject(source[slash+1:], key, opts)
+	if err != nil {
+		return convertError(err, source[slash+1:])
+	}
+
+	writeXml(w, 200, &copyObjectResult{
+		LastModified: result.LastModified.UTC().Format(xmlTimeFormat),
+		ETag:         result.ETag,
+	})
+
+	return nil
+}
+
+func initiateMultipartUpload(
+	w sys_http.ResponseWriter,
+	bucket s3.Bucket,
+	bucketName string,
+	key string) *s3.Error {
+	uploadId, err := bucket.InitiateMultipartUpload(key)
+	if err != nil {
+		return convertError(err, key)
+	}
+
+	writeXml(w, 200, &initiateMultipartUploadResult{
+		Bucket:   bucketName,
+		Key:      key,
+		UploadId: uploadId,
+	})
+
+	return nil
+}
+
+func uploadPart(
+	w sys_http.ResponseWriter,
+	query url.Values,
+	body []byte,
+	bucket s3.Bucket,
+	key string) *s3.Error {
+	partNumber, err := strconv.Atoi(query.Get("partNumber"))
+	if err != nil {
+		return convertError(fmt.Errorf("Invalid partNumber: %q", query.Get("partNumber")), key)
+	}
+
+	etag, err := bucket.UploadPart(
+		key,
+		query.Get("uploadId"),
+		partNumber,
+		bytes.NewReader(body),
+		int64(len(body)))
+
+	if err != nil {
+		return convertError(err, key)
+	}
+
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(200)
+	return nil
+}
+
+func completeMultipartUpload(
+	w sys_http.ResponseWriter,
+	query url.Values,
+	body []byte,
+	bucket s3.Bucket,
+	bucketName string,
+	key string) *s3.Error {
+	req := completeMultipartUploadRequest{}
+	if err := xml.Unmarshal(body, &req); err != nil {
+		return malformedXml(err)
+	}
+
+	parts := make([]s3.CompletedPart, len(req.Part))
+	for i, p := range req.Part {
+		parts[i] = s3.CompletedPart{PartNumber: p.PartNumber, ETag: p.ETag}
+	}
+
+	if err := bucket.CompleteMultipartUpload(key, query.Get("uploadId"), parts); err != nil {
+		return convertError(err, key)
+	}
+
+	info, err := bucket.StatObject(key)
+	if err != nil {
+		return convertError(err, key)
+	}
+
+	writeXml(w, 200, &completeMultipartUploadResult{
+		Bucket: bucketName,
+		Key:    key,
+		ETag:   info.ETag,
+	})
+
+	return nil
+}
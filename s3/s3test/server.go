@@ -0,0 +1,326 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package s3test contains an S3-compatible HTTP server that keeps objects in
+// memory, for use in tests of code that talks to S3 through package s3.
+package s3test
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"github.com/jacobsa/aws"
+	"github.com/jacobsa/aws/s3"
+	"github.com/jacobsa/aws/s3/fake"
+	"github.com/jacobsa/aws/time"
+	"io/ioutil"
+	sys_http "net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	sys_time "time"
+)
+
+// Server is an HTTPS server that speaks the subset of the S3 REST API used by
+// package s3, storing objects in memory using package fake. Buckets may be
+// created and deleted through s3.Service as well as supplied to NewServer. It
+// accepts only requests signed with the access key it was created with, by any
+// of the signers and presigners in package s3/auth. Buckets must be addressed
+// using path-style addressing.
+//
+// Use the Client method of the embedded httptest.Server to obtain an HTTP
+// client that trusts the server's certificate, and pass it to s3.OpenBucket or
+// s3.OpenBucketAtEndpoint using s3.WithHttpClient. For example:
+//
+//     server := s3test.NewServer(key, "some-bucket")
+//     defer server.Close()
+//
+//     bucket, err := s3.OpenBucket(
+//         "some-bucket",
+//         server.Region(),
+//         key,
+//         s3.WithHttpClient(server.Client()))
+//
+type Server struct {
+	*httptest.Server
+
+	key   aws.AccessKey
+	clock time.Clock
+
+	mu sync.Mutex
+
+	// GUARDED_BY(mu)
+	buckets map[string]*bucketEntry
+}
+
+// A bucket known to the server, along with the information S3 keeps about
+// the bucket itself.
+type bucketEntry struct {
+	bucket       s3.Bucket
+	creationDate sys_time.Time
+
+	// The location constraint with which the bucket was created, empty for
+	// us-east-1.
+	location string
+}
+
+// NewServer starts a server that accepts requests signed with the supplied
+// key, containing empty buckets with the supplied names. The caller must call
+// Close when done with it.
+func NewServer(key aws.AccessKey, buckets ...string) *Server {
+	return newServer(key, time.RealClock(), buckets)
+}
+
+func newServer(key aws.AccessKey, clock time.Clock, buckets []string) *Server {
+	s := &Server{
+		key:     key,
+		clock:   clock,
+		buckets: map[string]*bucketEntry{},
+	}
+
+	for _, name := range buckets {
+		s.buckets[name] = s.newBucketEntry(name, "")
+	}
+
+	s.Server = httptest.NewTLSServer(sys_http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Endpoint returns the URL at which the server listens, for use with
+// s3.OpenBucketAtEndpoint.
+func (s *Server) Endpoint() *url.URL {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		panic(fmt.Sprintf("url.Parse: %v", err))
+	}
+
+	return u
+}
+
+// Region returns a region whose endpoint is the server, for use with
+// s3.OpenBucket.
+func (s *Server) Region() s3.Region {
+	return s3.Region(s.Endpoint().Host)
+}
+
+// Bucket returns the contents of the bucket with the given name, or nil if
+// there is no such bucket. Changes made through the result are visible to
+// clients of the server, and vice versa.
+func (s *Server) Bucket(name string) s3.Bucket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.buckets[name]; ok {
+		return entry.bucket
+	}
+
+	return nil
+}
+
+func (s *Server) newBucketEntry(name string, location string) *bucketEntry {
+	return &bucketEntry{
+		bucket:       fake.NewBucket(name),
+		creationDate: s.clock.Now().UTC().Truncate(sys_time.Millisecond),
+		location:     location,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////
+// Responses
+////////////////////////////////////////////////////////////////////////
+
+// The format of times in XML response bodies.
+const xmlTimeFormat = "2006-01-02T15:04:05.000Z"
+
+type errorDocument struct {
+	XMLName          xml.Name `xml:"Error"`
+	Code             string
+	Message          string
+	Key              string `xml:",omitempty"`
+	ActualObjectSize *int64 `xml:",omitempty"`
+}
+
+func internalError(err error) *s3.Error {
+	return &s3.Error{
+		StatusCode: 500,
+		Code:       "InternalError",
+		Message:    err.Error(),
+	}
+}
+
+func notImplemented() *s3.Error {
+	return &s3.Error{
+		StatusCode: 501,
+		Code:       "NotImplemented",
+		Message:    "A header or parameter you provided implies functionality that is not implemented.",
+	}
+}
+
+func methodNotAllowed() *s3.Error {
+	return &s3.Error{
+		StatusCode: 405,
+		Code:       "MethodNotAllowed",
+		Message:    "The specified method is not allowed against this resource.",
+	}
+}
+
+func noSuchBucket() *s3.Error {
+	return &s3.Error{
+		StatusCode: 404,
+		Code:       "NoSuchBucket",
+		Message:    "The specified bucket does not exist.",
+	}
+}
+
+func malformedXml(err error) *s3.Error {
+	return &s3.Error{
+		StatusCode: 400,
+		Code:       "MalformedXML",
+		Message:    fmt.Sprintf("The XML you provided was not well-formed: %v", err),
+	}
+}
+
+// Convert an error returned by a fake bucket into the response S3 would give.
+func convertError(err error, key string) *s3.Error {
+	switch typed := err.(type) {
+	case *s3.Error:
+		return typed
+
+	case *s3.NotModifiedError:
+		return &s3.Error{StatusCode: 304}
+
+	case *s3.PreconditionFailedError:
+		return &s3.Error{
+			StatusCode: 412,
+			Code:       "PreconditionFailed",
+			Message:    "At least one of the preconditions you specified did not hold.",
+			Key:        key,
+		}
+	}
+
+	return &s3.Error{
+		StatusCode: 400,
+		Code:       "InvalidArgument",
+		Message:    err.Error(),
+		Key:        key,
+	}
+}
+
+func writeError(w sys_http.ResponseWriter, e *s3.Error) {
+	writeErrorDocument(w, e.StatusCode, &errorDocument{
+		Code:    e.Code,
+		Message: e.Message,
+		Key:     e.Key,
+	})
+}
+
+func writeErrorDocument(w sys_http.ResponseWriter, status int, doc *errorDocument) {
+	// Some responses have no body.
+	if status == 304 {
+		w.WriteHeader(status)
+		return
+	}
+
+	writeXml(w, status, doc)
+}
+
+func writeXml(w sys_http.ResponseWriter, status int, v interface{}) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		sys_http.Error(w, fmt.Sprintf("xml.Marshal: %v", err), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+}
+
+////////////////////////////////////////////////////////////////////////
+// Routing
+////////////////////////////////////////////////////////////////////////
+
+func (s *Server) serveHTTP(w sys_http.ResponseWriter, r *sys_http.Request) {
+	if errResp := s.handle(w, r); errResp != nil {
+		writeError(w, errResp)
+	}
+}
+
+// Handle the supplied request, returning an error response to be written if
+// the handler hasn't already written a response.
+func (s *Server) handle(w sys_http.ResponseWriter, r *sys_http.Request) *s3.Error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return internalError(fmt.Errorf("ReadAll: %v", err))
+	}
+
+	// Check the signature and the integrity of the body.
+	if errResp := s.authenticate(r, body); errResp != nil {
+		return errResp
+	}
+
+	if contentMd5 := r.Header.Get("Content-MD5"); contentMd5 != "" {
+		sum := md5.Sum(body)
+		if contentMd5 != base64.StdEncoding.EncodeToString(sum[:]) {
+			return &s3.Error{
+				StatusCode: 400,
+				Code:       "BadDigest",
+				Message:    "The Content-MD5 you specified did not match what we received.",
+			}
+		}
+	}
+
+	// Find the bucket and key, in that order, in the path.
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucketName, key := path, ""
+	if slash := strings.Index(path, "/"); slash >= 0 {
+		bucketName, key = path[:slash], path[slash+1:]
+	}
+
+	query := r.URL.Query()
+
+	if bucketName == "" {
+		return s.handleService(w, r)
+	}
+
+	if key == "" {
+		switch {
+		case r.Method == "PUT" && len(query) == 0:
+			return s.createBucket(w, body, bucketName)
+
+		case r.Method == "DELETE" && len(query) == 0:
+			return s.deleteBucket(w, r, bucketName)
+
+		case r.Method == "GET" && hasParam(query, "location"):
+			return s.getBucketLocation(w, bucketName)
+		}
+	}
+
+	bucket := s.Bucket(bucketName)
+	if bucket == nil {
+		return noSuchBucket()
+	}
+
+	bucket = bucket.WithContext(r.Context())
+
+	if key == "" {
+		return s.handleBucket(w, r, query, body, bucket, bucketName)
+	}
+
+	return s.handleObject(w, r, query, body, bucket, bucketName, key)
+}
@@ -0,0 +1,377 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3test
+
+import (
+	"fmt"
+	"github.com/jacobsa/aws"
+	"github.com/jacobsa/aws/s3"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+	"io/ioutil"
+	sys_http "net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+type ServerTest struct {
+	key    aws.AccessKey
+	clock  fakeClock
+	server *Server
+}
+
+func init() { RegisterTestSuite(&ServerTest{}) }
+
+func (t *ServerTest) SetUp(i *TestInfo) {
+	t.key = aws.AccessKey{Id: "some_id", Secret: "some_secret"}
+	t.clock.now = time.Now()
+	t.server = newServer(t.key, &t.clock, []string{"some-bucket"})
+}
+
+func (t *ServerTest) TearDown() {
+	t.server.Close()
+}
+
+func (t *ServerTest) openBucket(key aws.AccessKey, opts ...s3.Option) s3.Bucket {
+	opts = append(opts, s3.WithHttpClient(t.server.Client()))
+	bucket, err := s3.OpenBucket("some-bucket", t.server.Region(), key, opts...)
+	AssertEq(nil, err)
+
+	return bucket
+}
+
+// Send an unsigned request to the server, returning the response status and
+// body.
+func (t *ServerTest) sendRaw(
+	method string,
+	url string,
+	body string) (status int, respBody string) {
+	req, err := sys_http.NewRequest(method, url, strings.NewReader(body))
+	AssertEq(nil, err)
+
+	resp, err := t.server.Client().Do(req)
+	AssertEq(nil, err)
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	AssertEq(nil, err)
+
+	return resp.StatusCode, string(data)
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *ServerTest) StoreThenGet() {
+	for _, opts := range [][]s3.Option{
+		{},
+		{s3.WithSignatureV4()},
+		{s3.WithSignatureV4(), s3.WithUnsignedPayload()},
+	} {
+		bucket := t.openBucket(t.key, opts...)
+
+		AssertEq(nil, bucket.StoreObject("foo/bar", []byte("taco")))
+
+		data, err := bucket.GetObject("foo/bar")
+		AssertEq(nil, err)
+		ExpectEq("taco", string(data))
+	}
+}
+
+func (t *ServerTest) ContentsVisibleThroughBucket() {
+	bucket := t.openBucket(t.key)
+	AssertEq(nil, bucket.StoreObject("a", []byte("taco")))
+
+	data, err := t.server.Bucket("some-bucket").GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	ExpectEq(nil, t.server.Bucket("other-bucket"))
+}
+
+func (t *ServerTest) OpenBucketAtEndpoint() {
+	bucket, err := s3.OpenBucketAtEndpoint(
+		"some-bucket",
+		t.server.Endpoint(),
+		s3.PathStyle,
+		t.key,
+		s3.WithHttpClient(t.server.Client()))
+
+	AssertEq(nil, err)
+
+	keys, err := bucket.ListKeys("")
+	AssertEq(nil, err)
+	ExpectThat(keys, ElementsAre())
+}
+
+func (t *ServerTest) UnsignedRequest() {
+	status, body := t.sendRaw("GET", t.server.URL+"/some-bucket/a", "")
+
+	ExpectEq(403, status)
+	ExpectThat(body, HasSubstr("AccessDenied"))
+}
+
+func (t *ServerTest) UnknownKeyId() {
+	key := t.key
+	key.Id = "taco"
+
+	_, err := t.openBucket(key).GetObject("a")
+
+	ExpectThat(err, Error(HasSubstr("InvalidAccessKeyId")))
+}
+
+func (t *ServerTest) WrongSecret() {
+	for _, opts := range [][]s3.Option{{}, {s3.WithSignatureV4()}} {
+		key := t.key
+		key.Secret = "taco"
+
+		err := t.openBucket(key, opts...).StoreObject("a", []byte{})
+		ExpectThat(err, Error(HasSubstr("SignatureDoesNotMatch")))
+	}
+}
+
+func (t *ServerTest) NoSuchBucket() {
+	bucket, err := s3.OpenBucket(
+		"other-bucket",
+		t.server.Region(),
+		t.key,
+		s3.WithHttpClient(t.server.Client()))
+
+	AssertEq(nil, err)
+
+	_, err = bucket.GetObject("a")
+	ExpectTrue(s3.IsNotFound(err))
+	ExpectThat(err, Error(HasSubstr("NoSuchBucket")))
+}
+
+func (t *ServerTest) CreateListAndDeleteBuckets() {
+	for _, opts := range [][]s3.Option{{}, {s3.WithSignatureV4()}} {
+		opts = append(opts, s3.WithHttpClient(t.server.Client()))
+		service, err := s3.OpenServiceAtEndpoint(t.server.Endpoint(), t.key, opts...)
+		AssertEq(nil, err)
+
+		// Create
+		err = service.CreateBucket(
+			"burrito",
+			&s3.CreateBucketOptions{LocationConstraint: "eu-west-1"})
+
+		AssertEq(nil, err)
+		ExpectNe(nil, t.server.Bucket("burrito"))
+
+		err = service.CreateBucket("burrito", nil)
+		ExpectThat(err, Error(HasSubstr("BucketAlreadyOwnedByYou")))
+
+		// Location
+		location, err := service.GetBucketLocation("burrito")
+		AssertEq(nil, err)
+		ExpectEq("eu-west-1", location)
+
+		location, err = service.GetBucketLocation("some-bucket")
+		AssertEq(nil, err)
+		ExpectEq("", location)
+
+		// List
+		buckets, err := service.ListBuckets()
+		AssertEq(nil, err)
+
+		AssertEq(2, len(buckets))
+		ExpectEq("burrito", buckets[0].Name)
+		ExpectEq("some-bucket", buckets[1].Name)
+		ExpectTrue(
+			buckets[0].CreationDate.Equal(t.clock.now.Truncate(time.Millisecond)),
+			"%v",
+			buckets[0].CreationDate)
+
+		// Delete
+		AssertEq(nil, t.server.Bucket("burrito").StoreObject("a", []byte{}))
+
+		err = service.DeleteBucket("burrito")
+		ExpectThat(err, Error(HasSubstr("BucketNotEmpty")))
+
+		AssertEq(nil, t.server.Bucket("burrito").DeleteObject("a"))
+		AssertEq(nil, service.DeleteBucket("burrito"))
+		ExpectEq(nil, t.server.Bucket("burrito"))
+
+		err = service.DeleteBucket("burrito")
+		ExpectThat(err, Error(HasSubstr("NoSuchBucket")))
+	}
+}
+
+func (t *ServerTest) Versioning() {
+	for i, opts := range [][]s3.Option{{}, {s3.WithSignatureV4()}} {
+		bucket := t.openBucket(t.key, opts...)
+		key := fmt.Sprintf("foo%d", i)
+		AssertEq(nil, bucket.SetVersioning(s3.VersioningEnabled))
+
+		status, err := bucket.GetVersioning()
+		AssertEq(nil, err)
+		ExpectEq(s3.VersioningEnabled, status)
+
+		// Store two versions, then delete the object.
+		result, err := bucket.StoreObjectWithOptions(key, strings.NewReader("taco"), 4, nil)
+		AssertEq(nil, err)
+		AssertNe("", result.VersionId)
+		oldVersion := result.VersionId
+
+		AssertEq(nil, bucket.StoreObject(key, []byte("burrito")))
+		AssertEq(nil, bucket.DeleteObject(key))
+
+		// List the versions.
+		listing, err := bucket.ListObjectVersions(s3.ListVersionsRequest{Prefix: key})
+		AssertEq(nil, err)
+		AssertEq(3, len(listing.Versions))
+
+		ExpectTrue(listing.Versions[0].IsDeleteMarker)
+		ExpectTrue(listing.Versions[0].IsLatest)
+		ExpectFalse(listing.Versions[1].IsDeleteMarker)
+		ExpectEq(7, listing.Versions[1].Size)
+		ExpectEq(oldVersion, listing.Versions[2].VersionId)
+		ExpectTrue(
+			listing.Versions[2].LastModified.Equal(t.clock.now.Truncate(time.Second)),
+			"%v",
+			listing.Versions[2].LastModified)
+
+		// Read the old version.
+		r, info, err := bucket.GetObjectWithOptions(key, &s3.GetOptions{VersionId: oldVersion})
+		AssertEq(nil, err)
+		ExpectEq(oldVersion, info.VersionId)
+
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		AssertEq(nil, err)
+		ExpectEq("taco", string(data))
+
+		// Remove the delete marker.
+		AssertEq(nil, bucket.DeleteObjectVersion(key, listing.Versions[0].VersionId))
+
+		data, err = bucket.GetObject(key)
+		AssertEq(nil, err)
+		ExpectEq("burrito", string(data))
+	}
+}
+
+func (t *ServerTest) ServerSideEncryption() {
+	for _, opts := range [][]s3.Option{{}, {s3.WithSignatureV4()}} {
+		bucket := t.openBucket(t.key, opts...)
+
+		// SSE-KMS
+		storeOpts := &s3.StoreOptions{ServerSideEncryption: s3.SseKms, KmsKeyId: "taco"}
+		_, err := bucket.StoreObjectWithOptions("a", strings.NewReader("burrito"), -1, storeOpts)
+		AssertEq(nil, err)
+
+		info, err := bucket.StatObject("a")
+		AssertEq(nil, err)
+		ExpectEq(s3.SseKms, info.ServerSideEncryption)
+
+		// SSE-C
+		key := []byte(strings.Repeat("k", s3.CustomerKeyLength))
+		storeOpts = &s3.StoreOptions{CustomerKey: key}
+		_, err = bucket.StoreObjectWithOptions("b", strings.NewReader("enchilada"), -1, storeOpts)
+		AssertEq(nil, err)
+
+		r, _, err := bucket.GetObjectWithOptions("b", &s3.GetOptions{CustomerKey: key})
+		AssertEq(nil, err)
+
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		AssertEq(nil, err)
+		ExpectEq("enchilada", string(data))
+
+		_, err = bucket.GetObject("b")
+		ExpectThat(err, Error(HasSubstr("InvalidRequest")))
+
+		wrongKey := []byte(strings.Repeat("w", s3.CustomerKeyLength))
+		_, _, err = bucket.GetObjectWithOptions("b", &s3.GetOptions{CustomerKey: wrongKey})
+		ExpectThat(err, Error(HasSubstr("AccessDenied")))
+	}
+}
+
+func (t *ServerTest) PresignedUrls() {
+	bucket := t.openBucket(t.key)
+	AssertEq(nil, bucket.StoreObject("a", []byte("taco")))
+
+	for _, v4 := range []bool{false, true} {
+		url, err := bucket.PresignUrl(
+			"a",
+			&s3.PresignOptions{Expiry: time.Minute, UseSignatureV4: v4})
+
+		AssertEq(nil, err)
+
+		// Valid now.
+		status, body := t.sendRaw("GET", url, "")
+		ExpectEq(200, status, "v4: %v", v4)
+		ExpectEq("taco", body, "v4: %v", v4)
+
+		// Tampered with.
+		status, body = t.sendRaw("GET", strings.Replace(url, "/a?", "/b?", 1), "")
+		ExpectEq(403, status, "v4: %v", v4)
+		ExpectThat(body, HasSubstr("SignatureDoesNotMatch"), "v4: %v", v4)
+
+		// Expired.
+		t.clock.now = t.clock.now.Add(2 * time.Minute)
+		status, body = t.sendRaw("GET", url, "")
+		ExpectEq(403, status, "v4: %v", v4)
+		ExpectThat(body, HasSubstr("expired"), "v4: %v", v4)
+
+		t.clock.now = t.clock.now.Add(-2 * time.Minute)
+	}
+}
+
+func (t *ServerTest) ParseRange() {
+	type testCase struct {
+		header string
+		offset int64
+		length int64
+		ok     bool
+	}
+
+	cases := []testCase{
+		{"bytes=0-0", 0, 1, true},
+		{"bytes=17-26", 17, 10, true},
+		{"bytes=17-", 17, -1, true},
+		{"bytes=-8", -8, 0, true},
+		{"bytes=26-17", 0, 0, false},
+		{"bytes=0-1,3-4", 0, 0, false},
+		{"bytes=-0", 0, 0, false},
+		{"items=0-1", 0, 0, false},
+		{"bytes=taco", 0, 0, false},
+	}
+
+	for _, c := range cases {
+		offset, length, ok := parseRange(c.header)
+		AssertEq(c.ok, ok, "Header: %s", c.header)
+		if ok {
+			ExpectEq(c.offset, offset, "Header: %s", c.header)
+			ExpectEq(c.length, length, "Header: %s", c.header)
+		}
+	}
+}
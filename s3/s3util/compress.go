@@ -0,0 +1,405 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"github.com/jacobsa/aws/s3"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+const (
+	// The names of the user-defined metadata with which a compressing bucket
+	// marks the objects it compressed, recording the encoding used and the
+	// size of the uncompressed data.
+	compressionMetadata      = "compression"
+	uncompressedSizeMetadata = "uncompressed-size"
+)
+
+// A compression format for use with NewCompressingBucket.
+type Codec interface {
+	// The name of the format, recorded as the Content-Encoding of objects
+	// compressed with it, e.g. "gzip" or "zstd".
+	Encoding() string
+
+	// Return a writer that compresses the data written to it into w. Closing
+	// the writer must flush any buffered data to w, but not close w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+
+	// Return a reader that decompresses the data read from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// A Codec for the gzip format, from the standard library.
+var Gzip Codec = gzipCodec{}
+
+// A Codec for the zstd format, from github.com/klauspost/compress.
+var Zstd Codec = zstdCodec{}
+
+type gzipCodec struct{}
+
+func (c gzipCodec) Encoding() string {
+	return "gzip"
+}
+
+func (c gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (c gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type zstdCodec struct{}
+
+func (c zstdCodec) Encoding() string {
+	return "zstd"
+}
+
+func (c zstdCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (c zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.IOReadCloser(), nil
+}
+
+// NewCompressingBucket returns a bucket that compresses objects with the
+// supplied codec before storing them in the wrapped bucket, and decompresses
+// them when they are read, reducing storage and transfer costs.
+//
+// Compressed objects are stored with a Content-Encoding naming the codec, and
+// marked with user-defined metadata. Only objects so marked are decompressed
+// when read; others, including objects stored by callers that set
+// StoreOptions.ContentEncoding themselves, are stored and returned as-is.
+// Sizes and ObjectInfo returned for compressed objects describe the
+// uncompressed data.
+//
+// Objects are compressed in memory when stored, and GetObjectRange reads
+// objects from their start, since ranges refer to uncompressed data. Copies
+// that replace metadata are not supported. Multipart uploads and presigned
+// URLs bypass compression.
+//
+// To combine compression with NewEncryptingBucket, wrap the encrypting
+// bucket, since encrypted data doesn't compress.
+func NewCompressingBucket(bucket s3.Bucket, codec Codec) s3.Bucket {
+	return &compressingBucket{bucket, codec}
+}
+
+type compressingBucket struct {
+	// Operations that don't involve object data, such as listing and deleting,
+	// are passed through unchanged.
+	s3.Bucket
+
+	codec Codec
+}
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+func isCompressionMetadata(name string) bool {
+	switch strings.ToLower(name) {
+	case compressionMetadata, uncompressedSizeMetadata:
+		return true
+	}
+
+	return false
+}
+
+// Return whether the object described by the supplied info was compressed by
+// a compressing bucket, and if so, check that it used our codec.
+func (b *compressingBucket) isCompressed(info *s3.ObjectInfo) (bool, error) {
+	encoding, ok := info.Metadata[compressionMetadata]
+	switch {
+	case !ok:
+		return false, nil
+
+	case encoding != b.codec.Encoding():
+		return false, fmt.Errorf(
+			"Object %s is compressed with %s, not %s.",
+			info.Key,
+			encoding,
+			b.codec.Encoding())
+	}
+
+	return true, nil
+}
+
+// Return a copy of the supplied object info for a compressed object,
+// describing the uncompressed data.
+func uncompressedInfo(info *s3.ObjectInfo) *s3.ObjectInfo {
+	result := *info
+	result.ContentEncoding = ""
+	result.Size = -1
+	result.Metadata = map[string]string{}
+
+	for name, val := range info.Metadata {
+		if !isCompressionMetadata(name) {
+			result.Metadata[name] = val
+		}
+	}
+
+	sizeStr := info.Metadata[uncompressedSizeMetadata]
+	if size, err := strconv.ParseInt(sizeStr, 10, 64); err == nil && size >= 0 {
+		result.Size = size
+	}
+
+	return &result
+}
+
+// A reader that decompresses the body of an object, closing both the
+// decompressor and the body when closed.
+type decompressingReader struct {
+	io.ReadCloser
+	body io.ReadCloser
+}
+
+func (r *decompressingReader) Close() error {
+	err := r.ReadCloser.Close()
+	if bodyErr := r.body.Close(); err == nil {
+		err = bodyErr
+	}
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////
+// Reading
+////////////////////////////////////////////////////////////////////////
+
+func (b *compressingBucket) GetObject(key string) (data []byte, err error) {
+	r, _, err := b.GetObjectWithOptions(key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	if data, err = ioutil.ReadAll(r); err != nil {
+		return nil, fmt.Errorf("Decompressing %s: %v", key, err)
+	}
+
+	return data, nil
+}
+
+func (b *compressingBucket) GetObjectReader(
+	key string) (r io.ReadCloser, size int64, err error) {
+	r, info, err := b.GetObjectWithOptions(key, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r, info.Size, nil
+}
+
+func (b *compressingBucket) GetObjectWithOptions(
+	key string,
+	opts *s3.GetOptions) (r io.ReadCloser, info *s3.ObjectInfo, err error) {
+	body, info, err := b.Bucket.GetObjectWithOptions(key, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compressed, err := b.isCompressed(info)
+	if err != nil {
+		body.Close()
+		return nil, nil, err
+	}
+
+	if !compressed {
+		return body, info, nil
+	}
+
+	decompressor, err := b.codec.NewReader(body)
+	if err != nil {
+		body.Close()
+		return nil, nil, fmt.Errorf("Decompressing %s: %v", key, err)
+	}
+
+	r = &decompressingReader{decompressor, body}
+	return r, uncompressedInfo(info), nil
+}
+
+func (b *compressingBucket) GetObjectRange(
+	key string,
+	offset int64,
+	length int64) (data []byte, objectSize int64, err error) {
+	return b.GetObjectRangeWithOptions(key, offset, length, nil)
+}
+
+func (b *compressingBucket) GetObjectRangeWithOptions(
+	key string,
+	offset int64,
+	length int64,
+	opts *s3.RangeOptions) (data []byte, objectSize int64, err error) {
+	if length == 0 && offset >= 0 {
+		return nil, 0, fmt.Errorf("Length must be non-zero.")
+	}
+
+	if opts == nil {
+		opts = &s3.RangeOptions{}
+	}
+
+	// The range refers to the uncompressed data, so compressed objects must be
+	// read from the start. Whether the object is compressed is known only once
+	// the response arrives, so other objects are read the same way, though no
+	// further than the end of the range.
+	r, info, err := b.GetObjectWithOptions(key, &s3.GetOptions{CustomerKey: opts.CustomerKey})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer r.Close()
+
+	if data, objectSize, err = extractRange(r, info.Size, offset, length); err != nil {
+		if _, ok := err.(*s3.RangeNotSatisfiableError); ok {
+			return nil, 0, err
+		}
+
+		return nil, 0, fmt.Errorf("Reading %s: %v", key, err)
+	}
+
+	return data, objectSize, nil
+}
+
+func (b *compressingBucket) StatObject(key string) (info *s3.ObjectInfo, err error) {
+	return b.StatObjectWithOptions(key, nil)
+}
+
+func (b *compressingBucket) StatObjectWithOptions(
+	key string,
+	opts *s3.StatOptions) (info *s3.ObjectInfo, err error) {
+	if info, err = b.Bucket.StatObjectWithOptions(key, opts); err != nil {
+		return nil, err
+	}
+
+	compressed, err := b.isCompressed(info)
+	if err != nil {
+		return nil, err
+	}
+
+	if compressed {
+		info = uncompressedInfo(info)
+	}
+
+	return info, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// Writing
+////////////////////////////////////////////////////////////////////////
+
+func (b *compressingBucket) StoreObject(key string, data []byte) error {
+	_, err := b.StoreObjectWithOptions(key, bytes.NewReader(data), int64(len(data)), nil)
+	return err
+}
+
+func (b *compressingBucket) StoreObjectFromReader(
+	key string,
+	r io.Reader,
+	size int64) error {
+	_, err := b.StoreObjectWithOptions(key, r, size, nil)
+	return err
+}
+
+func (b *compressingBucket) StoreObjectWithOptions(
+	key string,
+	r io.Reader,
+	size int64,
+	opts *s3.StoreOptions) (result *s3.StoreResult, err error) {
+	var optsCopy s3.StoreOptions
+	if opts != nil {
+		optsCopy = *opts
+	}
+
+	// Make sure the caller's metadata doesn't collide with our own.
+	metadata := map[string]string{}
+	for name, val := range optsCopy.Metadata {
+		if isCompressionMetadata(name) {
+			return nil, fmt.Errorf("Metadata name %s is reserved for compression.", name)
+		}
+
+		metadata[name] = val
+	}
+
+	// Data that the caller has already encoded is stored as-is.
+	if optsCopy.ContentEncoding != "" {
+		return b.Bucket.StoreObjectWithOptions(key, r, size, opts)
+	}
+
+	// Compress the data.
+	var buf bytes.Buffer
+	w, err := b.codec.NewWriter(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("Compressing: %v", err)
+	}
+
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return nil, fmt.Errorf("Compressing: %v", err)
+	}
+
+	if err = w.Close(); err != nil {
+		return nil, fmt.Errorf("Compressing: %v", err)
+	}
+
+	if size >= 0 && n != size {
+		return nil, fmt.Errorf("Read %d bytes; expected %d.", n, size)
+	}
+
+	// Store it, marking it as compressed.
+	metadata[compressionMetadata] = b.codec.Encoding()
+	metadata[uncompressedSizeMetadata] = strconv.FormatInt(n, 10)
+
+	optsCopy.ContentEncoding = b.codec.Encoding()
+	optsCopy.Metadata = metadata
+
+	return b.Bucket.StoreObjectWithOptions(
+		key,
+		bytes.NewReader(buf.Bytes()),
+		int64(buf.Len()),
+		&optsCopy)
+}
+
+func (b *compressingBucket) CopyObject(
+	srcKey string,
+	dstKey string,
+	opts *s3.CopyOptions) (result *s3.CopyObjectResult, err error) {
+	// Replacing the metadata would lose track of whether the object is
+	// compressed.
+	if opts != nil && opts.MetadataDirective == s3.MetadataReplace {
+		return nil, fmt.Errorf("Compressed objects can't be copied with MetadataReplace.")
+	}
+
+	return b.Bucket.CopyObject(srcKey, dstKey, opts)
+}
+
+func (b *compressingBucket) WithContext(ctx context.Context) s3.Bucket {
+	return &compressingBucket{b.Bucket.WithContext(ctx), b.codec}
+}
@@ -0,0 +1,485 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3util_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"github.com/jacobsa/aws/s3"
+	"github.com/jacobsa/aws/s3/fake"
+	"github.com/jacobsa/aws/s3/mock"
+	"github.com/jacobsa/aws/s3/s3util"
+	. "github.com/jacobsa/oglematchers"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+// A codec that leaves data unchanged, standing in for a format other than
+// gzip.
+type nopCodec struct{}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (w nopWriteCloser) Close() error { return nil }
+
+func (c nopCodec) Encoding() string { return "nop" }
+
+func (c nopCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+func (c nopCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(r), nil
+}
+
+func gunzip(data []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	AssertEq(nil, err)
+
+	result, err := ioutil.ReadAll(r)
+	AssertEq(nil, err)
+
+	return string(result)
+}
+
+func unzstd(data []byte) string {
+	r, err := zstd.NewReader(bytes.NewReader(data))
+	AssertEq(nil, err)
+	defer r.Close()
+
+	result, err := ioutil.ReadAll(r)
+	AssertEq(nil, err)
+
+	return string(result)
+}
+
+type CompressingBucketTest struct {
+	wrapped s3.Bucket
+	bucket  s3.Bucket
+}
+
+func init() { RegisterTestSuite(&CompressingBucketTest{}) }
+
+func (t *CompressingBucketTest) SetUp(i *TestInfo) {
+	t.wrapped = fake.NewBucket("bucket")
+	t.bucket = s3util.NewCompressingBucket(t.wrapped, s3util.Gzip)
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *CompressingBucketTest) StoreThenGet() {
+	contents := strings.Repeat(`{"taco": "burrito"}`, 100)
+	AssertEq(nil, t.bucket.StoreObject("a", []byte(contents)))
+
+	data, err := t.bucket.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq(contents, string(data))
+
+	// The wrapped bucket sees compressed data.
+	data, err = t.wrapped.GetObject("a")
+	AssertEq(nil, err)
+	ExpectLt(len(data), len(contents)/10)
+	ExpectEq(contents, gunzip(data))
+
+	info, err := t.wrapped.StatObject("a")
+	AssertEq(nil, err)
+	ExpectEq("gzip", info.ContentEncoding)
+	ExpectEq("gzip", info.Metadata["compression"])
+	ExpectEq("1900", info.Metadata["uncompressed-size"])
+}
+
+func (t *CompressingBucketTest) StoreWithOptions() {
+	opts := &s3.StoreOptions{
+		ContentType: "application/json",
+		Metadata:    map[string]string{"color": "red"},
+	}
+
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("taco"), -1, opts)
+	AssertEq(nil, err)
+
+	// The caller's options are left alone.
+	ExpectEq("", opts.ContentEncoding)
+	ExpectThat(opts.Metadata, DeepEquals(map[string]string{"color": "red"}))
+
+	// GetObjectWithOptions
+	r, info, err := t.bucket.GetObjectWithOptions("a", nil)
+	AssertEq(nil, err)
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	ExpectEq(4, info.Size)
+	ExpectEq("application/json", info.ContentType)
+	ExpectEq("", info.ContentEncoding)
+	ExpectThat(info.Metadata, DeepEquals(map[string]string{"color": "red"}))
+
+	// StatObjectWithOptions
+	info, err = t.bucket.StatObject("a")
+	AssertEq(nil, err)
+
+	ExpectEq(4, info.Size)
+	ExpectEq("", info.ContentEncoding)
+	ExpectThat(info.Metadata, DeepEquals(map[string]string{"color": "red"}))
+}
+
+func (t *CompressingBucketTest) ReservedMetadataName() {
+	opts := &s3.StoreOptions{
+		Metadata: map[string]string{"Compression": "taco"},
+	}
+
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("taco"), -1, opts)
+	ExpectThat(err, Error(HasSubstr("reserved")))
+}
+
+func (t *CompressingBucketTest) StoreFromReaderWithWrongSize() {
+	err := t.bucket.StoreObjectFromReader("a", strings.NewReader("taco"), 5)
+	ExpectThat(err, Error(HasSubstr("expected 5")))
+}
+
+func (t *CompressingBucketTest) GetObjectReader() {
+	err := t.bucket.StoreObjectFromReader("a", strings.NewReader("burrito"), 7)
+	AssertEq(nil, err)
+
+	r, size, err := t.bucket.GetObjectReader("a")
+	AssertEq(nil, err)
+	defer r.Close()
+
+	ExpectEq(7, size)
+
+	data, err := ioutil.ReadAll(r)
+	AssertEq(nil, err)
+	ExpectEq("burrito", string(data))
+}
+
+func (t *CompressingBucketTest) GetObjectRange() {
+	AssertEq(nil, t.bucket.StoreObject("a", []byte("0123456789")))
+
+	data, size, err := t.bucket.GetObjectRange("a", 2, 3)
+	AssertEq(nil, err)
+	ExpectEq("234", string(data))
+	ExpectEq(10, size)
+
+	data, size, err = t.bucket.GetObjectRange("a", -4, 0)
+	AssertEq(nil, err)
+	ExpectEq("6789", string(data))
+
+	_, _, err = t.bucket.GetObjectRange("a", 10, -1)
+	rangeErr, ok := err.(*s3.RangeNotSatisfiableError)
+	AssertTrue(ok, "%v", err)
+	ExpectEq("bytes=10-", rangeErr.Range)
+	ExpectEq(10, rangeErr.ObjectSize)
+}
+
+func (t *CompressingBucketTest) GetObjectRangeOfOtherObject() {
+	opts := &s3.StoreOptions{ContentEncoding: "gzip"}
+	_, err := t.wrapped.StoreObjectWithOptions("a", strings.NewReader("0123456789"), -1, opts)
+	AssertEq(nil, err)
+
+	data, size, err := t.bucket.GetObjectRange("a", 2, 3)
+	AssertEq(nil, err)
+	ExpectEq("234", string(data))
+	ExpectEq(10, size)
+}
+
+func (t *CompressingBucketTest) GetObjectRangeOfMissingObject() {
+	_, _, err := t.bucket.GetObjectRange("a", 2, 3)
+	ExpectThat(err, Error(HasSubstr("NoSuchKey")))
+}
+
+func (t *CompressingBucketTest) CustomerKey() {
+	key := []byte(strings.Repeat("k", s3.CustomerKeyLength))
+	opts := &s3.StoreOptions{CustomerKey: key}
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("0123456789"), -1, opts)
+	AssertEq(nil, err)
+
+	info, err := t.bucket.StatObjectWithOptions("a", &s3.StatOptions{CustomerKey: key})
+	AssertEq(nil, err)
+	ExpectEq(10, info.Size)
+
+	data, size, err := t.bucket.GetObjectRangeWithOptions(
+		"a", 2, 3, &s3.RangeOptions{CustomerKey: key})
+
+	AssertEq(nil, err)
+	ExpectEq("234", string(data))
+	ExpectEq(10, size)
+
+	// Without the key.
+	_, _, err = t.bucket.GetObjectRange("a", 2, 3)
+	ExpectThat(err, Error(HasSubstr("InvalidRequest")))
+}
+
+func (t *CompressingBucketTest) OtherObjectsReturnedAsIs() {
+	// An object that merely has a Content-Encoding of gzip is not ours to
+	// decompress.
+	opts := &s3.StoreOptions{ContentEncoding: "gzip"}
+	_, err := t.wrapped.StoreObjectWithOptions("a", strings.NewReader("taco"), -1, opts)
+	AssertEq(nil, err)
+
+	AssertEq(nil, t.wrapped.StoreObject("b", []byte("burrito")))
+
+	data, err := t.bucket.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	info, err := t.bucket.StatObject("a")
+	AssertEq(nil, err)
+	ExpectEq("gzip", info.ContentEncoding)
+	ExpectEq(4, info.Size)
+
+	data, err = t.bucket.GetObject("b")
+	AssertEq(nil, err)
+	ExpectEq("burrito", string(data))
+}
+
+func (t *CompressingBucketTest) CallerEncodedDataStoredAsIs() {
+	opts := &s3.StoreOptions{ContentEncoding: "br"}
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("taco"), -1, opts)
+	AssertEq(nil, err)
+
+	data, err := t.wrapped.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	info, err := t.wrapped.StatObject("a")
+	AssertEq(nil, err)
+	ExpectEq("br", info.ContentEncoding)
+	ExpectThat(info.Metadata, DeepEquals(map[string]string{}))
+}
+
+func (t *CompressingBucketTest) OtherCodec() {
+	other := s3util.NewCompressingBucket(t.wrapped, nopCodec{})
+	AssertEq(nil, other.StoreObject("a", []byte("taco")))
+
+	info, err := t.wrapped.StatObject("a")
+	AssertEq(nil, err)
+	ExpectEq("nop", info.ContentEncoding)
+
+	data, err := other.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	// A bucket using gzip refuses to return the object.
+	_, err = t.bucket.GetObject("a")
+	ExpectThat(err, Error(HasSubstr("compressed with nop, not gzip")))
+
+	_, err = t.bucket.StatObject("a")
+	ExpectThat(err, Error(HasSubstr("compressed with nop, not gzip")))
+}
+
+func (t *CompressingBucketTest) Zstd() {
+	other := s3util.NewCompressingBucket(t.wrapped, s3util.Zstd)
+
+	contents := strings.Repeat(`{"taco": "burrito"}`, 100)
+	AssertEq(nil, other.StoreObject("a", []byte(contents)))
+
+	data, err := other.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq(contents, string(data))
+
+	// The wrapped bucket sees zstd data.
+	data, err = t.wrapped.GetObject("a")
+	AssertEq(nil, err)
+	ExpectLt(len(data), len(contents)/10)
+	ExpectEq(contents, unzstd(data))
+
+	info, err := t.wrapped.StatObject("a")
+	AssertEq(nil, err)
+	ExpectEq("zstd", info.ContentEncoding)
+	ExpectEq("zstd", info.Metadata["compression"])
+	ExpectEq("1900", info.Metadata["uncompressed-size"])
+
+	// A bucket using gzip refuses to return the object.
+	_, err = t.bucket.GetObject("a")
+	ExpectThat(err, Error(HasSubstr("compressed with zstd, not gzip")))
+}
+
+func (t *CompressingBucketTest) CorruptZstdData() {
+	opts := &s3.StoreOptions{
+		ContentEncoding: "zstd",
+		Metadata:        map[string]string{"compression": "zstd"},
+	}
+
+	_, err := t.wrapped.StoreObjectWithOptions("a", strings.NewReader("taco"), -1, opts)
+	AssertEq(nil, err)
+
+	other := s3util.NewCompressingBucket(t.wrapped, s3util.Zstd)
+	_, err = other.GetObject("a")
+	ExpectThat(err, Error(HasSubstr("Decompressing a")))
+}
+
+func (t *CompressingBucketTest) CorruptData() {
+	opts := &s3.StoreOptions{
+		ContentEncoding: "gzip",
+		Metadata:        map[string]string{"compression": "gzip"},
+	}
+
+	_, err := t.wrapped.StoreObjectWithOptions("a", strings.NewReader("taco"), -1, opts)
+	AssertEq(nil, err)
+
+	_, err = t.bucket.GetObject("a")
+	ExpectThat(err, Error(HasSubstr("Decompressing a")))
+}
+
+func (t *CompressingBucketTest) CopyObject() {
+	AssertEq(nil, t.bucket.StoreObject("a", []byte("taco")))
+
+	// A copy keeps the metadata, so it is decompressed.
+	_, err := t.bucket.CopyObject("a", "b", nil)
+	AssertEq(nil, err)
+
+	data, err := t.bucket.GetObject("b")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	// Replacing the metadata would lose it.
+	opts := &s3.CopyOptions{MetadataDirective: s3.MetadataReplace}
+	_, err = t.bucket.CopyObject("a", "c", opts)
+	ExpectThat(err, Error(HasSubstr("MetadataReplace")))
+}
+
+func (t *CompressingBucketTest) WithContext() {
+	bound := t.bucket.WithContext(context.Background())
+	AssertEq(nil, bound.StoreObject("a", []byte("taco")))
+
+	data, err := t.wrapped.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("taco", gunzip(data))
+}
+
+func (t *CompressingBucketTest) WrappingEncryptingBucket() {
+	encrypting := s3util.NewEncryptingBucket(t.wrapped, wrapKey, unwrapKey)
+	t.bucket = s3util.NewCompressingBucket(encrypting, s3util.Gzip)
+
+	contents := strings.Repeat("taco", 100)
+	AssertEq(nil, t.bucket.StoreObject("a", []byte(contents)))
+
+	data, err := t.bucket.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq(contents, string(data))
+
+	info, err := t.bucket.StatObject("a")
+	AssertEq(nil, err)
+	ExpectEq(len(contents), info.Size)
+	ExpectThat(info.Metadata, DeepEquals(map[string]string{}))
+}
+
+////////////////////////////////////////////////////////////////////////
+// GetObjectRange
+////////////////////////////////////////////////////////////////////////
+
+type CompressingBucketRangeTest struct {
+	wrapped mock_s3.MockBucket
+	bucket  s3.Bucket
+}
+
+func init() { RegisterTestSuite(&CompressingBucketRangeTest{}) }
+
+func (t *CompressingBucketRangeTest) SetUp(i *TestInfo) {
+	t.wrapped = mock_s3.NewMockBucket(i.MockController, "bucket")
+	t.bucket = s3util.NewCompressingBucket(t.wrapped, s3util.Gzip)
+}
+
+func (t *CompressingBucketRangeTest) GetObjectReturnsError() {
+	// GetObjectWithOptions
+	ExpectCall(t.wrapped, "GetObjectWithOptions")("a", Any()).
+		WillOnce(oglemock.Return(nil, nil, errors.New("taco")))
+
+	// Call
+	_, _, err := t.bucket.GetObjectRange("a", 2, 3)
+
+	ExpectThat(err, Error(HasSubstr("taco")))
+}
+
+func (t *CompressingBucketRangeTest) OtherObjectReadToEndOfRange() {
+	info := &s3.ObjectInfo{Key: "a", Size: 10, Metadata: map[string]string{}}
+
+	// GetObjectWithOptions
+	//
+	// The body fails if read past the end of the range.
+	body := ioutil.NopCloser(&failingReader{
+		strings.NewReader("01234"),
+		errors.New("taco"),
+	})
+
+	ExpectCall(t.wrapped, "GetObjectWithOptions")("a", Any()).
+		WillOnce(oglemock.Return(body, info, nil))
+
+	// Call
+	data, size, err := t.bucket.GetObjectRange("a", 2, 3)
+
+	AssertEq(nil, err)
+	ExpectEq("234", string(data))
+	ExpectEq(10, size)
+}
+
+func (t *CompressingBucketRangeTest) ShortBody() {
+	info := &s3.ObjectInfo{Key: "a", Size: 10, Metadata: map[string]string{}}
+
+	// GetObjectWithOptions
+	body := ioutil.NopCloser(strings.NewReader("0123"))
+	ExpectCall(t.wrapped, "GetObjectWithOptions")("a", Any()).
+		WillOnce(oglemock.Return(body, info, nil))
+
+	// Call
+	_, _, err := t.bucket.GetObjectRange("a", 2, 3)
+
+	ExpectThat(err, Error(HasSubstr("shorter")))
+}
+
+func (t *CompressingBucketRangeTest) CompressedObjectDecompressed() {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write([]byte("0123456789"))
+	AssertEq(nil, w.Close())
+
+	info := &s3.ObjectInfo{
+		Key:             "a",
+		ContentEncoding: "gzip",
+		Metadata: map[string]string{
+			"compression":       "gzip",
+			"uncompressed-size": "10",
+		},
+	}
+
+	// GetObjectWithOptions
+	body := ioutil.NopCloser(&compressed)
+	ExpectCall(t.wrapped, "GetObjectWithOptions")("a", Any()).
+		WillOnce(oglemock.Return(body, info, nil))
+
+	// Call
+	data, size, err := t.bucket.GetObjectRange("a", 2, 3)
+
+	AssertEq(nil, err)
+	ExpectEq("234", string(data))
+	ExpectEq(10, size)
+}
@@ -0,0 +1,68 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3util
+
+import (
+	"fmt"
+	"github.com/jacobsa/aws/s3"
+)
+
+// Delete every key in the bucket that begins with the supplied prefix, using
+// batch deletes of up to s3.MaxDeleteObjectsKeys keys at a time. If the prefix
+// is empty, every key in the bucket is deleted.
+//
+// Keys created concurrently with the call may or may not be deleted.
+func DeleteAllKeysWithPrefix(bucket s3.Bucket, prefix string) (err error) {
+	req := s3.ListRequest{
+		Prefix:  prefix,
+		MaxKeys: s3.MaxDeleteObjectsKeys,
+	}
+
+	for {
+		// Find the next batch of keys.
+		var listing *s3.ListResult
+		if listing, err = bucket.ListObjects(req); err != nil {
+			err = fmt.Errorf("ListObjects: %w", err)
+			return
+		}
+
+		// Delete them.
+		if len(listing.Keys) > 0 {
+			var result *s3.DeleteObjectsResult
+			if result, err = bucket.DeleteObjects(listing.Keys, true); err != nil {
+				err = fmt.Errorf("DeleteObjects: %w", err)
+				return
+			}
+
+			if len(result.Errors) > 0 {
+				err = fmt.Errorf(
+					"Failed to delete %d keys, including: %v",
+					len(result.Errors),
+					&result.Errors[0])
+
+				return
+			}
+		}
+
+		if !listing.IsTruncated {
+			break
+		}
+
+		req.Marker = listing.NextMarker
+	}
+
+	return
+}
@@ -0,0 +1,154 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3util_test
+
+import (
+	"errors"
+	"github.com/jacobsa/aws/s3"
+	"github.com/jacobsa/aws/s3/mock"
+	"github.com/jacobsa/aws/s3/s3util"
+	. "github.com/jacobsa/oglematchers"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"testing"
+)
+
+func TestDelete(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type DeleteAllKeysWithPrefixTest struct {
+	bucket mock_s3.MockBucket
+
+	err error
+}
+
+func init() { RegisterTestSuite(&DeleteAllKeysWithPrefixTest{}) }
+
+func (t *DeleteAllKeysWithPrefixTest) SetUp(i *TestInfo) {
+	t.bucket = mock_s3.NewMockBucket(i.MockController, "bucket")
+}
+
+func (t *DeleteAllKeysWithPrefixTest) call() {
+	t.err = s3util.DeleteAllKeysWithPrefix(t.bucket, "foo/")
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *DeleteAllKeysWithPrefixTest) ListObjectsReturnsError() {
+	// ListObjects
+	expectedReq := s3.ListRequest{Prefix: "foo/", MaxKeys: 1000}
+	ExpectCall(t.bucket, "ListObjects")(DeepEquals(expectedReq)).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	t.call()
+
+	ExpectThat(t.err, Error(HasSubstr("ListObjects")))
+	ExpectThat(t.err, Error(HasSubstr("taco")))
+}
+
+func (t *DeleteAllKeysWithPrefixTest) NoKeys() {
+	// ListObjects
+	ExpectCall(t.bucket, "ListObjects")(Any()).
+		WillOnce(oglemock.Return(&s3.ListResult{}, nil))
+
+	// Call
+	t.call()
+
+	ExpectEq(nil, t.err)
+}
+
+func (t *DeleteAllKeysWithPrefixTest) DeleteObjectsReturnsError() {
+	// ListObjects
+	listing := &s3.ListResult{Keys: []string{"foo/a"}}
+	ExpectCall(t.bucket, "ListObjects")(Any()).
+		WillOnce(oglemock.Return(listing, nil))
+
+	// DeleteObjects
+	ExpectCall(t.bucket, "DeleteObjects")(Any(), Any()).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	t.call()
+
+	ExpectThat(t.err, Error(HasSubstr("DeleteObjects")))
+	ExpectThat(t.err, Error(HasSubstr("taco")))
+}
+
+func (t *DeleteAllKeysWithPrefixTest) SomeKeysNotDeleted() {
+	// ListObjects
+	listing := &s3.ListResult{Keys: []string{"foo/a", "foo/b", "foo/c"}}
+	ExpectCall(t.bucket, "ListObjects")(Any()).
+		WillOnce(oglemock.Return(listing, nil))
+
+	// DeleteObjects
+	result := &s3.DeleteObjectsResult{
+		Errors: []s3.DeleteObjectError{
+			{Key: "foo/b", Code: "AccessDenied", Message: "taco"},
+			{Key: "foo/c", Code: "AccessDenied", Message: "burrito"},
+		},
+	}
+
+	ExpectCall(t.bucket, "DeleteObjects")(Any(), Any()).
+		WillOnce(oglemock.Return(result, nil))
+
+	// Call
+	t.call()
+
+	ExpectThat(t.err, Error(HasSubstr("2 keys")))
+	ExpectThat(t.err, Error(HasSubstr("foo/b")))
+	ExpectThat(t.err, Error(HasSubstr("AccessDenied")))
+	ExpectThat(t.err, Error(HasSubstr("taco")))
+}
+
+func (t *DeleteAllKeysWithPrefixTest) DeletesEachBatch() {
+	// ListObjects
+	listing0 := &s3.ListResult{
+		Keys:        []string{"foo/a", "foo/b"},
+		IsTruncated: true,
+		NextMarker:  "foo/b",
+	}
+
+	listing1 := &s3.ListResult{
+		Keys: []string{"foo/c"},
+	}
+
+	expectedReq0 := s3.ListRequest{Prefix: "foo/", MaxKeys: 1000}
+	expectedReq1 := s3.ListRequest{Prefix: "foo/", MaxKeys: 1000, Marker: "foo/b"}
+
+	ExpectCall(t.bucket, "ListObjects")(DeepEquals(expectedReq0)).
+		WillOnce(oglemock.Return(listing0, nil))
+
+	ExpectCall(t.bucket, "ListObjects")(DeepEquals(expectedReq1)).
+		WillOnce(oglemock.Return(listing1, nil))
+
+	// DeleteObjects
+	ExpectCall(t.bucket, "DeleteObjects")(ElementsAre("foo/a", "foo/b"), true).
+		WillOnce(oglemock.Return(&s3.DeleteObjectsResult{}, nil))
+
+	ExpectCall(t.bucket, "DeleteObjects")(ElementsAre("foo/c"), true).
+		WillOnce(oglemock.Return(&s3.DeleteObjectsResult{}, nil))
+
+	// Call
+	t.call()
+
+	ExpectEq(nil, t.err)
+}
@@ -0,0 +1,469 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3util
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/jacobsa/aws/s3"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	// The length in bytes of the data key generated for each object stored by
+	// an encrypting bucket, which selects AES-256.
+	DataKeyLength = 32
+
+	// The names of the user-defined metadata with which an encrypting bucket
+	// records how an object was encrypted.
+	envelopeAlgMetadata   = "envelope-alg"
+	envelopeKeyMetadata   = "envelope-key"
+	envelopeNonceMetadata = "envelope-nonce"
+
+	envelopeAlg = "AES256-GCM"
+
+	// The size of the authentication tag that GCM appends to the plaintext.
+	gcmTagSize = 16
+)
+
+// A function that encrypts (wraps) or decrypts (unwraps) the data key for an
+// object under a key-encryption key held by the caller, e.g. by calling a key
+// management service.
+type KeyWrapFunc func(key []byte) ([]byte, error)
+
+// NewEncryptingBucket returns a bucket that encrypts objects on the client
+// before storing them in the wrapped bucket, so that S3 never sees their
+// plaintext, and decrypts them when they are read.
+//
+// Each object is sealed with AES-GCM under a fresh data key of DataKeyLength
+// bytes. The data key, encrypted with wrapKey, and the nonce are stored
+// alongside the ciphertext as user-defined metadata; reading the object calls
+// unwrapKey to recover the data key. The object's key and the wrapped data key
+// are authenticated along with the ciphertext, so reading an object that
+// wasn't stored through an encrypting bucket under the same key, or whose
+// contents or wrapped data key have been tampered with, results in an error.
+//
+// Because the entire object must be authenticated before any of it is
+// returned, objects are held in memory when stored and read, and
+// GetObjectRange reads the whole object. Sizes returned by StatObject and
+// GetObjectWithOptions are those of the plaintext. Since objects are bound to
+// their keys, CopyObject decrypts the source object and encrypts it afresh
+// under the destination key rather than having S3 copy it, and copies from
+// other buckets are not supported. Multipart uploads and presigned URLs are
+// not supported either.
+func NewEncryptingBucket(
+	bucket s3.Bucket,
+	wrapKey KeyWrapFunc,
+	unwrapKey KeyWrapFunc) s3.Bucket {
+	return &encryptingBucket{bucket, wrapKey, unwrapKey}
+}
+
+type encryptingBucket struct {
+	// Operations that don't involve object data, such as listing and deleting,
+	// are passed through unchanged.
+	s3.Bucket
+
+	wrapKey   KeyWrapFunc
+	unwrapKey KeyWrapFunc
+}
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+func isEnvelopeMetadata(name string) bool {
+	switch strings.ToLower(name) {
+	case envelopeAlgMetadata, envelopeKeyMetadata, envelopeNonceMetadata:
+		return true
+	}
+
+	return false
+}
+
+// Return the additional data authenticated along with the contents of the
+// object with the given key, binding the ciphertext to the key and to the
+// wrapped data key. Keys can't contain U+0000, so the two can't be confused.
+func additionalData(key string, wrappedKey []byte) []byte {
+	result := append([]byte(key), 0)
+	return append(result, wrappedKey...)
+}
+
+// Encrypt the supplied data, to be stored with the given key, under a new data
+// key, returning the ciphertext and the metadata needed to decrypt it.
+func (b *encryptingBucket) seal(
+	key string,
+	plaintext []byte) (ciphertext []byte, metadata map[string]string, err error) {
+	dataKey := make([]byte, DataKeyLength)
+	if _, err = io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, nil, fmt.Errorf("Generating data key: %v", err)
+	}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("aes.NewCipher: %v", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cipher.NewGCM: %v", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, fmt.Errorf("Generating nonce: %v", err)
+	}
+
+	wrappedKey, err := b.wrapKey(dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Wrapping data key: %v", err)
+	}
+
+	ciphertext = gcm.Seal(nil, nonce, plaintext, additionalData(key, wrappedKey))
+	metadata = map[string]string{
+		envelopeAlgMetadata:   envelopeAlg,
+		envelopeKeyMetadata:   base64.StdEncoding.EncodeToString(wrappedKey),
+		envelopeNonceMetadata: base64.StdEncoding.EncodeToString(nonce),
+	}
+
+	return ciphertext, metadata, nil
+}
+
+// Decrypt the contents of the object with the given key using the metadata
+// stored with it.
+func (b *encryptingBucket) open(
+	key string,
+	ciphertext []byte,
+	metadata map[string]string) (plaintext []byte, err error) {
+	if metadata[envelopeAlgMetadata] != envelopeAlg {
+		return nil, fmt.Errorf("Object %s is not encrypted.", key)
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(metadata[envelopeKeyMetadata])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid wrapped key for %s: %v", key, err)
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(metadata[envelopeNonceMetadata])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid nonce for %s: %v", key, err)
+	}
+
+	dataKey, err := b.unwrapKey(wrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("Unwrapping data key for %s: %v", key, err)
+	}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("aes.NewCipher: %v", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cipher.NewGCM: %v", err)
+	}
+
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("Invalid nonce for %s: length %d", key, len(nonce))
+	}
+
+	aad := additionalData(key, wrappedKey)
+	if plaintext, err = gcm.Open(nil, nonce, ciphertext, aad); err != nil {
+		return nil, fmt.Errorf("Decrypting %s: %v", key, err)
+	}
+
+	return plaintext, nil
+}
+
+// Return a copy of the supplied object info describing the plaintext rather
+// than the ciphertext.
+func plaintextInfo(info *s3.ObjectInfo, size int64) *s3.ObjectInfo {
+	result := *info
+	result.Size = size
+	result.Metadata = map[string]string{}
+
+	for name, val := range info.Metadata {
+		if !isEnvelopeMetadata(name) {
+			result.Metadata[name] = val
+		}
+	}
+
+	return &result
+}
+
+////////////////////////////////////////////////////////////////////////
+// Reading
+////////////////////////////////////////////////////////////////////////
+
+func (b *encryptingBucket) GetObject(key string) (data []byte, err error) {
+	r, _, err := b.GetObjectWithOptions(key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (b *encryptingBucket) GetObjectReader(
+	key string) (r io.ReadCloser, size int64, err error) {
+	r, info, err := b.GetObjectWithOptions(key, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r, info.Size, nil
+}
+
+func (b *encryptingBucket) GetObjectWithOptions(
+	key string,
+	opts *s3.GetOptions) (r io.ReadCloser, info *s3.ObjectInfo, err error) {
+	r, info, err = b.Bucket.GetObjectWithOptions(key, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer r.Close()
+
+	ciphertext, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadAll: %v", err)
+	}
+
+	plaintext, err := b.open(key, ciphertext, info.Metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info = plaintextInfo(info, int64(len(plaintext)))
+	return ioutil.NopCloser(bytes.NewReader(plaintext)), info, nil
+}
+
+func (b *encryptingBucket) GetObjectRange(
+	key string,
+	offset int64,
+	length int64) (data []byte, objectSize int64, err error) {
+	return b.GetObjectRangeWithOptions(key, offset, length, nil)
+}
+
+func (b *encryptingBucket) GetObjectRangeWithOptions(
+	key string,
+	offset int64,
+	length int64,
+	opts *s3.RangeOptions) (data []byte, objectSize int64, err error) {
+	if length == 0 && offset >= 0 {
+		return nil, 0, fmt.Errorf("Length must be non-zero.")
+	}
+
+	if opts == nil {
+		opts = &s3.RangeOptions{}
+	}
+
+	r, info, err := b.GetObjectWithOptions(key, &s3.GetOptions{CustomerKey: opts.CustomerKey})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer r.Close()
+
+	return extractRange(r, info.Size, offset, length)
+}
+
+func (b *encryptingBucket) StatObject(key string) (info *s3.ObjectInfo, err error) {
+	return b.StatObjectWithOptions(key, nil)
+}
+
+func (b *encryptingBucket) StatObjectWithOptions(
+	key string,
+	opts *s3.StatOptions) (info *s3.ObjectInfo, err error) {
+	if info, err = b.Bucket.StatObjectWithOptions(key, opts); err != nil {
+		return nil, err
+	}
+
+	if info.Metadata[envelopeAlgMetadata] != envelopeAlg {
+		return nil, fmt.Errorf("Object %s is not encrypted.", key)
+	}
+
+	size := info.Size
+	if size >= gcmTagSize {
+		size -= gcmTagSize
+	}
+
+	return plaintextInfo(info, size), nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// Writing
+////////////////////////////////////////////////////////////////////////
+
+func (b *encryptingBucket) StoreObject(key string, data []byte) error {
+	_, err := b.StoreObjectWithOptions(key, bytes.NewReader(data), int64(len(data)), nil)
+	return err
+}
+
+func (b *encryptingBucket) StoreObjectFromReader(
+	key string,
+	r io.Reader,
+	size int64) error {
+	_, err := b.StoreObjectWithOptions(key, r, size, nil)
+	return err
+}
+
+func (b *encryptingBucket) StoreObjectWithOptions(
+	key string,
+	r io.Reader,
+	size int64,
+	opts *s3.StoreOptions) (result *s3.StoreResult, err error) {
+	var optsCopy s3.StoreOptions
+	if opts != nil {
+		optsCopy = *opts
+	}
+
+	// Make sure the caller's metadata doesn't collide with our own.
+	metadata := map[string]string{}
+	for name, val := range optsCopy.Metadata {
+		if isEnvelopeMetadata(name) {
+			return nil, fmt.Errorf("Metadata name %s is reserved for encryption.", name)
+		}
+
+		metadata[name] = val
+	}
+
+	// Read and encrypt the data.
+	plaintext, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("Reading: %v", err)
+	}
+
+	if size >= 0 && int64(len(plaintext)) != size {
+		return nil, fmt.Errorf("Read %d bytes; expected %d.", len(plaintext), size)
+	}
+
+	ciphertext, envelope, err := b.seal(key, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, val := range envelope {
+		metadata[name] = val
+	}
+
+	optsCopy.Metadata = metadata
+
+	return b.Bucket.StoreObjectWithOptions(
+		key,
+		bytes.NewReader(ciphertext),
+		int64(len(ciphertext)),
+		&optsCopy)
+}
+
+func (b *encryptingBucket) CopyObject(
+	srcKey string,
+	dstKey string,
+	opts *s3.CopyOptions) (result *s3.CopyObjectResult, err error) {
+	if opts == nil {
+		opts = &s3.CopyOptions{}
+	}
+
+	// Objects are bound to their keys, so a copy made by S3 couldn't be
+	// decrypted. Instead we read the object and store it again.
+	if opts.SourceBucket != "" {
+		return nil, fmt.Errorf("Encrypted objects can't be copied from other buckets.")
+	}
+
+	r, info, err := b.GetObjectWithOptions(srcKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	storeOpts := &s3.StoreOptions{
+		ContentType:     info.ContentType,
+		ContentEncoding: info.ContentEncoding,
+		CacheControl:    info.CacheControl,
+		Metadata:        info.Metadata,
+		Acl:             opts.Acl,
+		StorageClass:    opts.StorageClass,
+	}
+
+	if opts.MetadataDirective == s3.MetadataReplace {
+		storeOpts.ContentType = opts.ContentType
+		storeOpts.ContentEncoding = opts.ContentEncoding
+		storeOpts.CacheControl = opts.CacheControl
+		storeOpts.Metadata = opts.Metadata
+	}
+
+	stored, err := b.StoreObjectWithOptions(dstKey, r, info.Size, storeOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The store doesn't tell us when the new object was last modified.
+	dstInfo, err := b.Bucket.StatObject(dstKey)
+	if err != nil {
+		return nil, err
+	}
+
+	result = &s3.CopyObjectResult{
+		ETag:         stored.ETag,
+		LastModified: dstInfo.LastModified,
+	}
+
+	return result, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// Unsupported operations
+////////////////////////////////////////////////////////////////////////
+
+func (b *encryptingBucket) InitiateMultipartUpload(
+	key string) (uploadId string, err error) {
+	return "", fmt.Errorf("Multipart uploads are not supported with encryption.")
+}
+
+func (b *encryptingBucket) UploadPart(
+	key string,
+	uploadId string,
+	partNumber int,
+	r io.Reader,
+	size int64) (etag string, err error) {
+	return "", fmt.Errorf("Multipart uploads are not supported with encryption.")
+}
+
+func (b *encryptingBucket) CompleteMultipartUpload(
+	key string,
+	uploadId string,
+	parts []s3.CompletedPart) error {
+	return fmt.Errorf("Multipart uploads are not supported with encryption.")
+}
+
+func (b *encryptingBucket) PresignUrl(
+	key string,
+	opts *s3.PresignOptions) (url string, err error) {
+	return "", fmt.Errorf("Presigned URLs are not supported with encryption.")
+}
+
+func (b *encryptingBucket) WithContext(ctx context.Context) s3.Bucket {
+	return &encryptingBucket{b.Bucket.WithContext(ctx), b.wrapKey, b.unwrapKey}
+}
@@ -0,0 +1,378 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3util_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/jacobsa/aws/s3"
+	"github.com/jacobsa/aws/s3/fake"
+	"github.com/jacobsa/aws/s3/s3util"
+	. "github.com/jacobsa/oglematchers"
+	. "github.com/jacobsa/ogletest"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestEncrypt(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+const wrappedPrefix = "wrapped:"
+
+// A stand-in for a key management service, which "wraps" keys by prefixing
+// them.
+func wrapKey(key []byte) ([]byte, error) {
+	return append([]byte(wrappedPrefix), key...), nil
+}
+
+func unwrapKey(wrapped []byte) ([]byte, error) {
+	if !bytes.HasPrefix(wrapped, []byte(wrappedPrefix)) {
+		return nil, errors.New("not wrapped")
+	}
+
+	return wrapped[len(wrappedPrefix):], nil
+}
+
+type EncryptingBucketTest struct {
+	wrapped s3.Bucket
+	bucket  s3.Bucket
+}
+
+func init() { RegisterTestSuite(&EncryptingBucketTest{}) }
+
+func (t *EncryptingBucketTest) SetUp(i *TestInfo) {
+	t.wrapped = fake.NewBucket("bucket")
+	t.bucket = s3util.NewEncryptingBucket(t.wrapped, wrapKey, unwrapKey)
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *EncryptingBucketTest) StoreThenGet() {
+	AssertEq(nil, t.bucket.StoreObject("a", []byte("taco")))
+
+	data, err := t.bucket.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	// The wrapped bucket sees only ciphertext.
+	data, err = t.wrapped.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq(len("taco")+16, len(data))
+	ExpectFalse(bytes.Contains(data, []byte("taco")))
+
+	info, err := t.wrapped.StatObject("a")
+	AssertEq(nil, err)
+	ExpectEq("AES256-GCM", info.Metadata["envelope-alg"])
+	ExpectNe("", info.Metadata["envelope-key"])
+	ExpectNe("", info.Metadata["envelope-nonce"])
+}
+
+func (t *EncryptingBucketTest) EachObjectHasItsOwnKey() {
+	AssertEq(nil, t.bucket.StoreObject("a", []byte("taco")))
+	AssertEq(nil, t.bucket.StoreObject("b", []byte("taco")))
+
+	infoA, err := t.wrapped.StatObject("a")
+	AssertEq(nil, err)
+
+	infoB, err := t.wrapped.StatObject("b")
+	AssertEq(nil, err)
+
+	ExpectNe(infoA.Metadata["envelope-key"], infoB.Metadata["envelope-key"])
+	ExpectNe(infoA.Metadata["envelope-nonce"], infoB.Metadata["envelope-nonce"])
+	ExpectNe(infoA.ETag, infoB.ETag)
+}
+
+func (t *EncryptingBucketTest) StoreWithOptions() {
+	opts := &s3.StoreOptions{
+		ContentType: "text/plain",
+		Metadata:    map[string]string{"color": "red"},
+	}
+
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("taco"), -1, opts)
+	AssertEq(nil, err)
+
+	// The caller's options are left alone.
+	ExpectThat(opts.Metadata, DeepEquals(map[string]string{"color": "red"}))
+
+	// GetObjectWithOptions
+	r, info, err := t.bucket.GetObjectWithOptions("a", nil)
+	AssertEq(nil, err)
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	ExpectEq(4, info.Size)
+	ExpectEq("text/plain", info.ContentType)
+	ExpectThat(info.Metadata, DeepEquals(map[string]string{"color": "red"}))
+
+	// StatObject
+	info, err = t.bucket.StatObject("a")
+	AssertEq(nil, err)
+
+	ExpectEq(4, info.Size)
+	ExpectThat(info.Metadata, DeepEquals(map[string]string{"color": "red"}))
+}
+
+func (t *EncryptingBucketTest) ReservedMetadataName() {
+	opts := &s3.StoreOptions{
+		Metadata: map[string]string{"Envelope-Key": "taco"},
+	}
+
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("taco"), -1, opts)
+	ExpectThat(err, Error(HasSubstr("reserved")))
+}
+
+func (t *EncryptingBucketTest) StoreFromReaderWithWrongSize() {
+	err := t.bucket.StoreObjectFromReader("a", strings.NewReader("taco"), 5)
+	ExpectThat(err, Error(HasSubstr("expected 5")))
+}
+
+func (t *EncryptingBucketTest) GetObjectReader() {
+	err := t.bucket.StoreObjectFromReader("a", strings.NewReader("burrito"), 7)
+	AssertEq(nil, err)
+
+	r, size, err := t.bucket.GetObjectReader("a")
+	AssertEq(nil, err)
+	defer r.Close()
+
+	ExpectEq(7, size)
+
+	data, err := ioutil.ReadAll(r)
+	AssertEq(nil, err)
+	ExpectEq("burrito", string(data))
+}
+
+func (t *EncryptingBucketTest) GetObjectRange() {
+	AssertEq(nil, t.bucket.StoreObject("a", []byte("0123456789")))
+
+	data, size, err := t.bucket.GetObjectRange("a", 2, 3)
+	AssertEq(nil, err)
+	ExpectEq("234", string(data))
+	ExpectEq(10, size)
+
+	data, size, err = t.bucket.GetObjectRange("a", 7, -1)
+	AssertEq(nil, err)
+	ExpectEq("789", string(data))
+
+	data, size, err = t.bucket.GetObjectRange("a", -4, 0)
+	AssertEq(nil, err)
+	ExpectEq("6789", string(data))
+
+	_, _, err = t.bucket.GetObjectRange("a", 10, 1)
+	rangeErr, ok := err.(*s3.RangeNotSatisfiableError)
+	AssertTrue(ok, "%v", err)
+	ExpectEq("bytes=10-10", rangeErr.Range)
+	ExpectEq(10, rangeErr.ObjectSize)
+}
+
+func (t *EncryptingBucketTest) CustomerKey() {
+	key := []byte(strings.Repeat("k", s3.CustomerKeyLength))
+	opts := &s3.StoreOptions{CustomerKey: key}
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("0123456789"), -1, opts)
+	AssertEq(nil, err)
+
+	info, err := t.bucket.StatObjectWithOptions("a", &s3.StatOptions{CustomerKey: key})
+	AssertEq(nil, err)
+	ExpectEq(10, info.Size)
+
+	data, size, err := t.bucket.GetObjectRangeWithOptions(
+		"a", 2, 3, &s3.RangeOptions{CustomerKey: key})
+
+	AssertEq(nil, err)
+	ExpectEq("234", string(data))
+	ExpectEq(10, size)
+
+	// Without the key.
+	_, _, err = t.bucket.GetObjectRange("a", 2, 3)
+	ExpectThat(err, Error(HasSubstr("InvalidRequest")))
+}
+
+func (t *EncryptingBucketTest) ObjectNotEncrypted() {
+	AssertEq(nil, t.wrapped.StoreObject("a", []byte("taco")))
+
+	_, err := t.bucket.GetObject("a")
+	ExpectThat(err, Error(HasSubstr("not encrypted")))
+
+	_, err = t.bucket.StatObject("a")
+	ExpectThat(err, Error(HasSubstr("not encrypted")))
+}
+
+func (t *EncryptingBucketTest) ObjectDoesNotExist() {
+	_, err := t.bucket.GetObject("a")
+	ExpectThat(err, Error(HasSubstr("NoSuchKey")))
+
+	_, err = t.bucket.StatObject("a")
+	ExpectTrue(s3.IsNotFound(err), "%v", err)
+}
+
+func (t *EncryptingBucketTest) CiphertextTamperedWith() {
+	AssertEq(nil, t.bucket.StoreObject("a", []byte("taco")))
+
+	// Flip a bit of the ciphertext, keeping the metadata.
+	r, info, err := t.wrapped.GetObjectWithOptions("a", nil)
+	AssertEq(nil, err)
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	AssertEq(nil, err)
+
+	data[0] ^= 1
+	opts := &s3.StoreOptions{Metadata: info.Metadata}
+	_, err = t.wrapped.StoreObjectWithOptions("a", bytes.NewReader(data), -1, opts)
+	AssertEq(nil, err)
+
+	// Read
+	_, err = t.bucket.GetObject("a")
+	ExpectThat(err, Error(HasSubstr("Decrypting a")))
+}
+
+func (t *EncryptingBucketTest) WrapKeyReturnsError() {
+	wrap := func([]byte) ([]byte, error) { return nil, errors.New("taco") }
+	t.bucket = s3util.NewEncryptingBucket(t.wrapped, wrap, unwrapKey)
+
+	err := t.bucket.StoreObject("a", []byte("burrito"))
+	ExpectThat(err, Error(HasSubstr("Wrapping data key")))
+	ExpectThat(err, Error(HasSubstr("taco")))
+
+	_, err = t.wrapped.StatObject("a")
+	ExpectNe(nil, err)
+}
+
+func (t *EncryptingBucketTest) UnwrapKeyReturnsError() {
+	AssertEq(nil, t.bucket.StoreObject("a", []byte("burrito")))
+
+	unwrap := func([]byte) ([]byte, error) { return nil, errors.New("taco") }
+	t.bucket = s3util.NewEncryptingBucket(t.wrapped, wrapKey, unwrap)
+
+	_, err := t.bucket.GetObject("a")
+	ExpectThat(err, Error(HasSubstr("Unwrapping data key")))
+	ExpectThat(err, Error(HasSubstr("taco")))
+}
+
+func (t *EncryptingBucketTest) CopyObject() {
+	opts := &s3.StoreOptions{
+		ContentType: "text/plain",
+		Metadata:    map[string]string{"color": "red"},
+	}
+
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader("taco"), -1, opts)
+	AssertEq(nil, err)
+
+	// The copy is encrypted afresh under its own key.
+	result, err := t.bucket.CopyObject("a", "b", nil)
+	AssertEq(nil, err)
+
+	data, err := t.bucket.GetObject("b")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	infoA, err := t.wrapped.StatObject("a")
+	AssertEq(nil, err)
+
+	infoB, err := t.wrapped.StatObject("b")
+	AssertEq(nil, err)
+
+	ExpectEq(infoB.ETag, result.ETag)
+	ExpectTrue(infoB.LastModified.Equal(result.LastModified))
+	ExpectNe(infoA.Metadata["envelope-key"], infoB.Metadata["envelope-key"])
+	ExpectEq("text/plain", infoB.ContentType)
+	ExpectEq("red", infoB.Metadata["color"])
+}
+
+func (t *EncryptingBucketTest) CopyObjectReplacingMetadata() {
+	AssertEq(nil, t.bucket.StoreObject("a", []byte("taco")))
+
+	opts := &s3.CopyOptions{
+		MetadataDirective: s3.MetadataReplace,
+		ContentType:       "text/plain",
+		Metadata:          map[string]string{"color": "blue"},
+	}
+
+	_, err := t.bucket.CopyObject("a", "b", opts)
+	AssertEq(nil, err)
+
+	data, err := t.bucket.GetObject("b")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	info, err := t.bucket.StatObject("b")
+	AssertEq(nil, err)
+	ExpectEq("text/plain", info.ContentType)
+	ExpectThat(info.Metadata, DeepEquals(map[string]string{"color": "blue"}))
+}
+
+func (t *EncryptingBucketTest) CopyObjectFromOtherBucket() {
+	opts := &s3.CopyOptions{SourceBucket: "other"}
+	_, err := t.bucket.CopyObject("a", "b", opts)
+	ExpectThat(err, Error(HasSubstr("other buckets")))
+}
+
+func (t *EncryptingBucketTest) CiphertextMovedToOtherKey() {
+	AssertEq(nil, t.bucket.StoreObject("a", []byte("taco")))
+
+	// Copy the ciphertext and its metadata verbatim.
+	_, err := t.wrapped.CopyObject("a", "b", nil)
+	AssertEq(nil, err)
+
+	// Read
+	_, err = t.bucket.GetObject("b")
+	ExpectThat(err, Error(HasSubstr("Decrypting b")))
+}
+
+func (t *EncryptingBucketTest) UnsupportedOperations() {
+	_, err := t.bucket.InitiateMultipartUpload("a")
+	ExpectThat(err, Error(HasSubstr("not supported")))
+
+	_, err = t.bucket.PresignUrl("a", nil)
+	ExpectThat(err, Error(HasSubstr("not supported")))
+}
+
+func (t *EncryptingBucketTest) PassesThroughOtherOperations() {
+	AssertEq(nil, t.bucket.StoreObject("a", []byte("taco")))
+	AssertEq(nil, t.bucket.StoreObject("b", []byte("burrito")))
+
+	keys, err := s3util.ListAllKeys(t.bucket)
+	AssertEq(nil, err)
+	ExpectThat(keys, ElementsAre("a", "b"))
+
+	AssertEq(nil, t.bucket.DeleteObject("a"))
+
+	keys, err = s3util.ListAllKeys(t.wrapped)
+	AssertEq(nil, err)
+	ExpectThat(keys, ElementsAre("b"))
+}
+
+func (t *EncryptingBucketTest) WithContext() {
+	bound := t.bucket.WithContext(context.Background())
+	AssertEq(nil, bound.StoreObject("a", []byte("taco")))
+
+	data, err := t.bucket.GetObject("a")
+	AssertEq(nil, err)
+	ExpectEq("taco", string(data))
+
+	// The bound bucket encrypts too.
+	data, err = t.wrapped.GetObject("a")
+	AssertEq(nil, err)
+	ExpectNe("taco", string(data))
+}
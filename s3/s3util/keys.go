@@ -31,7 +31,7 @@ func ListAllKeys(bucket s3.Bucket) (keys []string, err error) {
 		var partialKeys []string
 		partialKeys, err = bucket.ListKeys(prevKey)
 		if err != nil {
-			err = fmt.Errorf("ListKeys: %v", err)
+			err = fmt.Errorf("ListKeys: %w", err)
 			return
 		}
 
@@ -44,3 +44,29 @@ func ListAllKeys(bucket s3.Bucket) (keys []string, err error) {
 
 	return
 }
+
+// List all keys currently contained by the bucket that begin with the
+// supplied prefix.
+func ListAllKeysWithPrefix(
+	bucket s3.Bucket,
+	prefix string) (keys []string, err error) {
+	req := s3.ListRequest{Prefix: prefix}
+	for {
+		var result *s3.ListResult
+		result, err = bucket.ListObjects(req)
+		if err != nil {
+			err = fmt.Errorf("ListObjects: %w", err)
+			return
+		}
+
+		keys = append(keys, result.Keys...)
+
+		if !result.IsTruncated {
+			break
+		}
+
+		req.Marker = result.NextMarker
+	}
+
+	return
+}
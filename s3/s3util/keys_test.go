@@ -17,6 +17,7 @@ package s3util_test
 
 import (
 	"errors"
+	"github.com/jacobsa/aws/s3"
 	"github.com/jacobsa/aws/s3/mock"
 	"github.com/jacobsa/aws/s3/s3util"
 	. "github.com/jacobsa/oglematchers"
@@ -118,3 +119,94 @@ func (t *ListAllKeysTest) ListKeyReturnsSomeKeys() {
 		),
 	)
 }
+
+////////////////////////////////////////////////////////////////////////
+// ListAllKeysWithPrefix
+////////////////////////////////////////////////////////////////////////
+
+type ListAllKeysWithPrefixTest struct {
+	bucket mock_s3.MockBucket
+
+	keys []string
+	err  error
+}
+
+func init() { RegisterTestSuite(&ListAllKeysWithPrefixTest{}) }
+
+func (t *ListAllKeysWithPrefixTest) SetUp(i *TestInfo) {
+	t.bucket = mock_s3.NewMockBucket(i.MockController, "bucket")
+}
+
+func (t *ListAllKeysWithPrefixTest) call() {
+	t.keys, t.err = s3util.ListAllKeysWithPrefix(t.bucket, "foo/")
+}
+
+func (t *ListAllKeysWithPrefixTest) CallsListObjectsRepeatedly() {
+	// ListObjects (call 0)
+	result0 := &s3.ListResult{
+		Keys:        []string{"foo/burrito", "foo/enchilada"},
+		IsTruncated: true,
+		NextMarker:  "foo/enchilada",
+	}
+
+	ExpectCall(t.bucket, "ListObjects")(DeepEquals(s3.ListRequest{Prefix: "foo/"})).
+		WillOnce(oglemock.Return(result0, nil))
+
+	// ListObjects (call 1)
+	expectedReq := s3.ListRequest{Prefix: "foo/", Marker: "foo/enchilada"}
+	ExpectCall(t.bucket, "ListObjects")(DeepEquals(expectedReq)).
+		WillOnce(oglemock.Return(nil, errors.New("")))
+
+	// Call
+	t.call()
+}
+
+func (t *ListAllKeysWithPrefixTest) ListObjectsReturnsError() {
+	// ListObjects
+	result0 := &s3.ListResult{
+		Keys:        []string{"foo/a"},
+		IsTruncated: true,
+		NextMarker:  "foo/a",
+	}
+
+	ExpectCall(t.bucket, "ListObjects")(Any()).
+		WillOnce(oglemock.Return(result0, nil)).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	t.call()
+
+	ExpectThat(t.err, Error(HasSubstr("ListObjects")))
+	ExpectThat(t.err, Error(HasSubstr("taco")))
+}
+
+func (t *ListAllKeysWithPrefixTest) StopsWhenNotTruncated() {
+	// ListObjects
+	result0 := &s3.ListResult{
+		Keys:        []string{"foo/burrito", "foo/enchilada"},
+		IsTruncated: true,
+		NextMarker:  "foo/enchilada",
+	}
+
+	result1 := &s3.ListResult{
+		Keys:        []string{"foo/taco"},
+		IsTruncated: false,
+	}
+
+	ExpectCall(t.bucket, "ListObjects")(Any()).
+		WillOnce(oglemock.Return(result0, nil)).
+		WillOnce(oglemock.Return(result1, nil))
+
+	// Call
+	t.call()
+	AssertEq(nil, t.err)
+
+	ExpectThat(
+		t.keys,
+		ElementsAre(
+			"foo/burrito",
+			"foo/enchilada",
+			"foo/taco",
+		),
+	)
+}
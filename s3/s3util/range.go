@@ -0,0 +1,79 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3util
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jacobsa/aws/s3"
+	"io"
+	"io/ioutil"
+)
+
+// Read from r, the contents of an object of the supplied size, the range
+// requested by the arguments to Bucket.GetObjectRange, for wrappers that
+// can't ask the server for the range directly. Nothing past the end of the
+// range is read. If the size is unknown (negative) the whole object is read.
+func extractRange(
+	r io.Reader,
+	size int64,
+	offset int64,
+	length int64) (data []byte, objectSize int64, err error) {
+	if size < 0 {
+		var all []byte
+		if all, err = ioutil.ReadAll(r); err != nil {
+			return nil, 0, err
+		}
+
+		r = bytes.NewReader(all)
+		size = int64(len(all))
+	}
+
+	start, end := offset, size
+	switch {
+	case offset < 0:
+		// A suffix range covers the whole object if the object is too short.
+		start = size + offset
+		if start < 0 {
+			start = 0
+		}
+
+	case offset >= size:
+		rangeHeader := fmt.Sprintf("bytes=%d-", offset)
+		if length > 0 {
+			rangeHeader += fmt.Sprintf("%d", offset+length-1)
+		}
+
+		return nil, 0, &s3.RangeNotSatisfiableError{Range: rangeHeader, ObjectSize: size}
+
+	case length > 0 && offset+length < size:
+		end = offset + length
+	}
+
+	if _, err = io.CopyN(ioutil.Discard, r, start); err != nil {
+		return nil, 0, err
+	}
+
+	if data, err = ioutil.ReadAll(io.LimitReader(r, end-start)); err != nil {
+		return nil, 0, err
+	}
+
+	if int64(len(data)) != end-start {
+		return nil, 0, fmt.Errorf("Object is shorter than its size of %d.", size)
+	}
+
+	return data, size, nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3util
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jacobsa/aws/s3"
+	"io"
+	"sync"
+)
+
+const (
+	// The smallest part size that S3 allows for any but the last part of a
+	// multipart upload.
+	MinPartSize = 5 << 20
+
+	// The part size used by Uploader if none is specified.
+	DefaultPartSize = 16 << 20
+
+	// The number of parts uploaded concurrently by Uploader if no parallelism
+	// is specified.
+	DefaultParallelism = 4
+
+	// The largest number of parts that S3 allows in a multipart upload.
+	maxParts = 10000
+)
+
+// An Uploader stores objects using S3's multipart upload API, splitting its
+// input into parts and uploading several of them concurrently. This allows
+// storing objects too large for a single request, and limits the amount of
+// work lost to a transient failure.
+//
+// The input is read sequentially, and at most Parallelism parts are held in
+// memory at once.
+type Uploader struct {
+	Bucket s3.Bucket
+
+	// The size in bytes of every part except the last. If zero,
+	// DefaultPartSize is used. Otherwise it must be at least MinPartSize.
+	PartSize int64
+
+	// The maximum number of parts to upload at once. If zero,
+	// DefaultParallelism is used.
+	Parallelism int
+}
+
+// Upload the contents of r to the object with the given key, overwriting any
+// previous version. If any part fails to upload, the multipart upload is
+// aborted and an error returned.
+func (u *Uploader) Upload(key string, r io.Reader) (err error) {
+	// Figure out our configuration.
+	partSize := u.PartSize
+	if partSize == 0 {
+		partSize = DefaultPartSize
+	}
+
+	if partSize < MinPartSize {
+		err = fmt.Errorf("Part size must be at least %d bytes.", MinPartSize)
+		return
+	}
+
+	parallelism := u.Parallelism
+	if parallelism == 0 {
+		parallelism = DefaultParallelism
+	}
+
+	if parallelism < 0 {
+		err = fmt.Errorf("Invalid parallelism: %d", parallelism)
+		return
+	}
+
+	// Begin the upload.
+	uploadId, err := u.Bucket.InitiateMultipartUpload(key)
+	if err != nil {
+		err = fmt.Errorf("InitiateMultipartUpload: %w", err)
+		return
+	}
+
+	// Abort the upload if we don't succeed, so that S3 doesn't hang on to the
+	// parts we've uploaded.
+	defer func() {
+		if err != nil {
+			if abortErr := u.Bucket.AbortMultipartUpload(key, uploadId); abortErr != nil {
+				err = fmt.Errorf("%v (AbortMultipartUpload: %v)", err, abortErr)
+			}
+		}
+	}()
+
+	// State shared with the goroutines uploading parts.
+	var mutex sync.Mutex
+	var firstErr error
+	etags := make(map[int]string)
+
+	recordError := func(e error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if firstErr == nil {
+			firstErr = e
+		}
+	}
+
+	failed := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return firstErr != nil
+	}
+
+	// Read parts and upload them in the background, using a semaphore to limit
+	// the number in flight (and therefore the amount of memory used).
+	var wg sync.WaitGroup
+	sem := make(chan bool, parallelism)
+	numParts := 0
+
+	for {
+		// Wait for a slot, then make sure no other part has failed before doing
+		// more work.
+		sem <- true
+		if failed() {
+			<-sem
+			break
+		}
+
+		// Read the next part. Every upload has at least one part, even if the
+		// input is empty.
+		buf := make([]byte, partSize)
+		n, readErr := io.ReadFull(r, buf)
+
+		if readErr == io.EOF && numParts > 0 {
+			<-sem
+			break
+		}
+
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			<-sem
+			recordError(fmt.Errorf("Reading: %v", readErr))
+			break
+		}
+
+		if numParts == maxParts {
+			<-sem
+			recordError(fmt.Errorf("Input requires more than %d parts.", maxParts))
+			break
+		}
+
+		// Start uploading it.
+		numParts++
+
+		wg.Add(1)
+		go func(partNumber int, data []byte) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			etag, err := u.Bucket.UploadPart(
+				key,
+				uploadId,
+				partNumber,
+				bytes.NewReader(data),
+				int64(len(data)))
+
+			if err != nil {
+				recordError(fmt.Errorf("UploadPart(%d): %w", partNumber, err))
+				return
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			etags[partNumber] = etag
+		}(numParts, buf[:n])
+
+		// A short read means we've reached the end of the input.
+		if readErr != nil {
+			break
+		}
+	}
+
+	// Wait for all uploads to finish, then check for errors.
+	wg.Wait()
+
+	if err = firstErr; err != nil {
+		return
+	}
+
+	// Assemble the parts.
+	parts := make([]s3.CompletedPart, numParts)
+	for i := range parts {
+		parts[i] = s3.CompletedPart{PartNumber: i + 1, ETag: etags[i+1]}
+	}
+
+	if err = u.Bucket.CompleteMultipartUpload(key, uploadId, parts); err != nil {
+		err = fmt.Errorf("CompleteMultipartUpload: %w", err)
+		return
+	}
+
+	return
+}
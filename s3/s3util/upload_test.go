@@ -0,0 +1,271 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3util_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/jacobsa/aws/s3"
+	"github.com/jacobsa/aws/s3/mock"
+	"github.com/jacobsa/aws/s3/s3util"
+	. "github.com/jacobsa/oglematchers"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+func TestUpload(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+const partSize = s3util.MinPartSize
+
+// A reader that returns an error after yielding some data.
+type failingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (n int, err error) {
+	n, err = r.r.Read(p)
+	if err == io.EOF {
+		err = r.err
+	}
+
+	return
+}
+
+type UploaderTest struct {
+	bucket   mock_s3.MockBucket
+	uploader s3util.Uploader
+}
+
+func init() { RegisterTestSuite(&UploaderTest{}) }
+
+func (t *UploaderTest) SetUp(i *TestInfo) {
+	t.bucket = mock_s3.NewMockBucket(i.MockController, "bucket")
+	t.uploader = s3util.Uploader{
+		Bucket:      t.bucket,
+		PartSize:    partSize,
+		Parallelism: 1,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *UploaderTest) PartSizeTooSmall() {
+	t.uploader.PartSize = partSize - 1
+
+	// Call
+	err := t.uploader.Upload("taco", bytes.NewReader(nil))
+
+	ExpectThat(err, Error(HasSubstr("Part size")))
+}
+
+func (t *UploaderTest) InitiateReturnsError() {
+	// Bucket
+	ExpectCall(t.bucket, "InitiateMultipartUpload")("taco").
+		WillOnce(oglemock.Return("", errors.New("burrito")))
+
+	// Call
+	err := t.uploader.Upload("taco", bytes.NewReader(nil))
+
+	ExpectThat(err, Error(HasSubstr("InitiateMultipartUpload")))
+	ExpectThat(err, Error(HasSubstr("burrito")))
+}
+
+func (t *UploaderTest) EmptyInput() {
+	// Bucket
+	ExpectCall(t.bucket, "InitiateMultipartUpload")("taco").
+		WillOnce(oglemock.Return("upload_id", nil))
+
+	ExpectCall(t.bucket, "UploadPart")("taco", "upload_id", 1, Any(), 0).
+		WillOnce(oglemock.Return("etag_1", nil))
+
+	parts := []s3.CompletedPart{{PartNumber: 1, ETag: "etag_1"}}
+	ExpectCall(t.bucket, "CompleteMultipartUpload")("taco", "upload_id", DeepEquals(parts)).
+		WillOnce(oglemock.Return(nil))
+
+	// Call
+	err := t.uploader.Upload("taco", bytes.NewReader(nil))
+
+	ExpectEq(nil, err)
+}
+
+func (t *UploaderTest) InputIsMultipleOfPartSize() {
+	data := bytes.Repeat([]byte{0x17}, 2*partSize)
+
+	// Bucket
+	ExpectCall(t.bucket, "InitiateMultipartUpload")(Any()).
+		WillOnce(oglemock.Return("upload_id", nil))
+
+	ExpectCall(t.bucket, "UploadPart")("taco", "upload_id", 1, Any(), partSize).
+		WillOnce(oglemock.Return("etag_1", nil))
+
+	ExpectCall(t.bucket, "UploadPart")("taco", "upload_id", 2, Any(), partSize).
+		WillOnce(oglemock.Return("etag_2", nil))
+
+	parts := []s3.CompletedPart{
+		{PartNumber: 1, ETag: "etag_1"},
+		{PartNumber: 2, ETag: "etag_2"},
+	}
+
+	ExpectCall(t.bucket, "CompleteMultipartUpload")("taco", "upload_id", DeepEquals(parts)).
+		WillOnce(oglemock.Return(nil))
+
+	// Call
+	err := t.uploader.Upload("taco", bytes.NewReader(data))
+
+	ExpectEq(nil, err)
+}
+
+func (t *UploaderTest) UploadsPartsConcurrently() {
+	t.uploader.Parallelism = 3
+
+	data := make([]byte, 4*partSize+17)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// Bucket
+	ExpectCall(t.bucket, "InitiateMultipartUpload")(Any()).
+		WillOnce(oglemock.Return("upload_id", nil))
+
+	var mutex sync.Mutex
+	uploaded := make([][]byte, 5)
+
+	ExpectCall(t.bucket, "UploadPart")("taco", "upload_id", Any(), Any(), Any()).
+		Times(5).
+		WillRepeatedly(oglemock.Invoke(func(
+			key string,
+			uploadId string,
+			partNumber int,
+			r io.Reader,
+			size int64) (string, error) {
+			partData, err := ioutil.ReadAll(r)
+			if err != nil || int64(len(partData)) != size {
+				return "", errors.New("bad part data")
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			uploaded[partNumber-1] = partData
+
+			return string(rune('a' + partNumber)), nil
+		}))
+
+	parts := []s3.CompletedPart{
+		{PartNumber: 1, ETag: "b"},
+		{PartNumber: 2, ETag: "c"},
+		{PartNumber: 3, ETag: "d"},
+		{PartNumber: 4, ETag: "e"},
+		{PartNumber: 5, ETag: "f"},
+	}
+
+	ExpectCall(t.bucket, "CompleteMultipartUpload")("taco", "upload_id", DeepEquals(parts)).
+		WillOnce(oglemock.Return(nil))
+
+	// Call
+	err := t.uploader.Upload("taco", bytes.NewReader(data))
+	AssertEq(nil, err)
+
+	ExpectThat(bytes.Join(uploaded, nil), DeepEquals(data))
+	ExpectEq(17, len(uploaded[4]))
+}
+
+func (t *UploaderTest) UploadPartReturnsError() {
+	data := bytes.Repeat([]byte{0x17}, partSize+1)
+
+	// Bucket
+	ExpectCall(t.bucket, "InitiateMultipartUpload")(Any()).
+		WillOnce(oglemock.Return("upload_id", nil))
+
+	ExpectCall(t.bucket, "UploadPart")(Any(), Any(), 1, Any(), Any()).
+		WillOnce(oglemock.Return("", errors.New("burrito")))
+
+	ExpectCall(t.bucket, "AbortMultipartUpload")("taco", "upload_id").
+		WillOnce(oglemock.Return(nil))
+
+	// Call
+	err := t.uploader.Upload("taco", bytes.NewReader(data))
+
+	ExpectThat(err, Error(HasSubstr("UploadPart(1)")))
+	ExpectThat(err, Error(HasSubstr("burrito")))
+}
+
+func (t *UploaderTest) ReaderReturnsError() {
+	r := &failingReader{bytes.NewReader([]byte("taco")), errors.New("burrito")}
+
+	// Bucket
+	ExpectCall(t.bucket, "InitiateMultipartUpload")(Any()).
+		WillOnce(oglemock.Return("upload_id", nil))
+
+	ExpectCall(t.bucket, "AbortMultipartUpload")("taco", "upload_id").
+		WillOnce(oglemock.Return(nil))
+
+	// Call
+	err := t.uploader.Upload("taco", r)
+
+	ExpectThat(err, Error(HasSubstr("Reading")))
+	ExpectThat(err, Error(HasSubstr("burrito")))
+}
+
+func (t *UploaderTest) CompleteReturnsError() {
+	// Bucket
+	ExpectCall(t.bucket, "InitiateMultipartUpload")(Any()).
+		WillOnce(oglemock.Return("upload_id", nil))
+
+	ExpectCall(t.bucket, "UploadPart")(Any(), Any(), Any(), Any(), Any()).
+		WillOnce(oglemock.Return("etag", nil))
+
+	ExpectCall(t.bucket, "CompleteMultipartUpload")(Any(), Any(), Any()).
+		WillOnce(oglemock.Return(errors.New("burrito")))
+
+	ExpectCall(t.bucket, "AbortMultipartUpload")("taco", "upload_id").
+		WillOnce(oglemock.Return(nil))
+
+	// Call
+	err := t.uploader.Upload("taco", bytes.NewReader([]byte("enchilada")))
+
+	ExpectThat(err, Error(HasSubstr("CompleteMultipartUpload")))
+	ExpectThat(err, Error(HasSubstr("burrito")))
+}
+
+func (t *UploaderTest) AbortReturnsError() {
+	// Bucket
+	ExpectCall(t.bucket, "InitiateMultipartUpload")(Any()).
+		WillOnce(oglemock.Return("upload_id", nil))
+
+	ExpectCall(t.bucket, "UploadPart")(Any(), Any(), Any(), Any(), Any()).
+		WillOnce(oglemock.Return("", errors.New("burrito")))
+
+	ExpectCall(t.bucket, "AbortMultipartUpload")(Any(), Any()).
+		WillOnce(oglemock.Return(errors.New("queso")))
+
+	// Call
+	err := t.uploader.Upload("taco", bytes.NewReader(nil))
+
+	ExpectThat(err, Error(HasSubstr("burrito")))
+	ExpectThat(err, Error(HasSubstr("AbortMultipartUpload")))
+	ExpectThat(err, Error(HasSubstr("queso")))
+}
@@ -0,0 +1,391 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/jacobsa/aws"
+	"github.com/jacobsa/aws/s3/auth"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/aws/time"
+	"net/url"
+	"strings"
+	sys_time "time"
+)
+
+// Service represents S3 as a whole from the point of view of a particular
+// account, allowing the account's buckets to be created, deleted, and listed.
+// Use OpenBucket to work with the contents of a bucket.
+type Service interface {
+	// Create a bucket with the given name, which must not already be in use by
+	// any account. opts may be nil, in which case defaults are used.
+	CreateBucket(name string, opts *CreateBucketOptions) error
+
+	// Delete the bucket with the given name, which must be empty.
+	DeleteBucket(name string) error
+
+	// Return the buckets owned by the account, in order of name.
+	ListBuckets() (buckets []BucketInfo, err error)
+
+	// Return the location constraint with which the bucket with the given name
+	// was created, e.g. "eu-west-1". The empty string means us-east-1.
+	GetBucketLocation(name string) (location string, err error)
+
+	// Return a view of the service whose operations are bound to the supplied
+	// context, in the same manner as Bucket.WithContext.
+	WithContext(ctx context.Context) Service
+}
+
+// Options controlling Service.CreateBucket. The zero value creates a private
+// bucket in us-east-1.
+type CreateBucketOptions struct {
+	// The region in which to create the bucket, e.g. "eu-west-1". If empty,
+	// the bucket is created in us-east-1. S3 requires this to match the region
+	// to which the request is sent, except that any region may be given when
+	// sending to us-east-1.
+	LocationConstraint string
+
+	// The canned ACL to apply to the bucket. If empty, it is private.
+	Acl CannedAcl
+}
+
+// BucketInfo describes a bucket, as returned by Service.ListBuckets.
+type BucketInfo struct {
+	// The bucket's name.
+	Name string
+
+	// The time at which the bucket was created.
+	CreationDate sys_time.Time
+}
+
+// OpenService returns a Service that sends requests to the given region
+// using the supplied access key. The options have the same meanings as for
+// OpenBucket.
+func OpenService(
+	region Region,
+	key aws.AccessKey,
+	opts ...Option) (Service, error) {
+	endpoint := &url.URL{Scheme: "https", Host: string(region)}
+	return openServiceAtEndpoint(
+		endpoint,
+		regionName(region),
+		key,
+		makeBucketOptions(opts))
+}
+
+// OpenServiceAtEndpoint is like OpenService, but sends requests to an
+// arbitrary endpoint in the same manner as OpenBucketAtEndpoint. Buckets are
+// addressed using the style set with WithAddressingStyle.
+func OpenServiceAtEndpoint(
+	endpoint *url.URL,
+	key aws.AccessKey,
+	opts ...Option) (Service, error) {
+	if endpoint.Host == "" {
+		return nil, fmt.Errorf("Endpoint has no host: %s", endpoint)
+	}
+
+	if endpoint.RawQuery != "" || endpoint.Fragment != "" {
+		return nil, fmt.Errorf("Endpoint may not have a query or fragment: %s", endpoint)
+	}
+
+	return openServiceAtEndpoint(endpoint, "us-east-1", key, makeBucketOptions(opts))
+}
+
+func openServiceAtEndpoint(
+	endpoint *url.URL,
+	region string,
+	key aws.AccessKey,
+	o *bucketOptions) (Service, error) {
+	if o.signingRegion != "" {
+		region = o.signingRegion
+	}
+
+	clock := time.RealClock()
+	httpConn, signer, err := makeConnAndSigner(endpoint, region, key, o, clock)
+	if err != nil {
+		return nil, err
+	}
+
+	return openService(endpoint, o.addressingStyle, httpConn, signer, clock)
+}
+
+// A version of OpenService with the ability to inject dependencies, for
+// testability.
+func openService(
+	endpoint *url.URL,
+	style AddressingStyle,
+	httpConn http.Conn,
+	signer auth.Signer,
+	clock time.Clock) (Service, error) {
+	s := &service{
+		pathPrefix: strings.TrimSuffix(endpoint.Path, "/"),
+		style:      style,
+		httpConn:   httpConn,
+		signer:     signer,
+		clock:      clock,
+	}
+
+	return s, nil
+}
+
+type service struct {
+	pathPrefix string
+	style      AddressingStyle
+	httpConn   http.Conn
+	signer     auth.Signer
+	clock      time.Clock
+}
+
+func (s *service) WithContext(ctx context.Context) Service {
+	bound := *s
+	bound.httpConn = bindContext(s.httpConn, ctx)
+	return &bound
+}
+
+// Bucket names are restricted by S3. We apply the legacy rules, which are a
+// superset of the current ones, so as not to rule out existing buckets.
+//
+// Reference:
+//     http://docs.aws.amazon.com/AmazonS3/latest/dev/BucketRestrictions.html
+func validateBucketName(name string) error {
+	if len(name) < 3 || len(name) > 255 {
+		return fmt.Errorf("Bucket names must be between 3 and 255 bytes long.")
+	}
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '.', c == '-', c == '_':
+		default:
+			return fmt.Errorf("Invalid bucket name: %q", name)
+		}
+	}
+
+	return nil
+}
+
+// Build a request addressing the bucket with the given name.
+func (s *service) newBucketRequest(verb string, name string) *http.Request {
+	r := &http.Request{
+		Verb: verb,
+		Path: fmt.Sprintf("%s/%s", s.pathPrefix, name),
+		Headers: map[string]string{
+			"Date": s.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+		Parameters: map[string]string{},
+	}
+
+	if s.style == VirtualHostedStyle && isDnsCompatible(name) {
+		r.Path = s.pathPrefix + "/"
+		r.Bucket = name
+	}
+
+	return r
+}
+
+// Sign and send the supplied request, returning an error if the response
+// doesn't have the expected status code.
+func (s *service) sendRequest(
+	r *http.Request,
+	expectedStatus int) (*http.Response, error) {
+	// Sign the request.
+	if err := s.signer.Sign(r); err != nil {
+		return nil, fmt.Errorf("Sign: %v", err)
+	}
+
+	// Send the request.
+	httpResp, err := s.httpConn.SendRequest(r)
+	if err != nil {
+		return nil, fmt.Errorf("SendRequest: %v", err)
+	}
+
+	// Check the response.
+	if httpResp.StatusCode != expectedStatus {
+		return nil, newError(httpResp.StatusCode, httpResp.Body)
+	}
+
+	return httpResp, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// CreateBucket
+////////////////////////////////////////////////////////////////////////
+
+type createBucketConfiguration struct {
+	XMLName            xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CreateBucketConfiguration"`
+	LocationConstraint string
+}
+
+func (s *service) CreateBucket(name string, opts *CreateBucketOptions) error {
+	// Validate the name.
+	if err := validateBucketName(name); err != nil {
+		return err
+	}
+
+	if opts == nil {
+		opts = &CreateBucketOptions{}
+	}
+
+	// Build an appropriate HTTP request. The location constraint is sent in the
+	// body, if any.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUT.html
+	httpReq := s.newBucketRequest("PUT", name)
+
+	if opts.Acl != "" {
+		httpReq.Headers["x-amz-acl"] = string(opts.Acl)
+	}
+
+	if opts.LocationConstraint != "" {
+		body, err := xml.Marshal(createBucketConfiguration{
+			LocationConstraint: opts.LocationConstraint,
+		})
+
+		if err != nil {
+			return fmt.Errorf("xml.Marshal: %v", err)
+		}
+
+		httpReq.Body = body
+	}
+
+	// Send it.
+	if _, err := s.sendRequest(httpReq, 200); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// DeleteBucket
+////////////////////////////////////////////////////////////////////////
+
+func (s *service) DeleteBucket(name string) error {
+	// Validate the name.
+	if err := validateBucketName(name); err != nil {
+		return err
+	}
+
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketDELETE.html
+	httpReq := s.newBucketRequest("DELETE", name)
+	if _, err := s.sendRequest(httpReq, 204); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// ListBuckets
+////////////////////////////////////////////////////////////////////////
+
+type listedBucket struct {
+	Name         string
+	CreationDate string
+}
+
+type listAllMyBucketsResult struct {
+	XMLName xml.Name
+	Buckets []listedBucket `xml:"Buckets>Bucket"`
+}
+
+func (s *service) ListBuckets() (buckets []BucketInfo, err error) {
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/RESTServiceGET.html
+	httpReq := &http.Request{
+		Verb: "GET",
+		Path: s.pathPrefix + "/",
+		Headers: map[string]string{
+			"Date": s.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+	}
+
+	httpResp, err := s.sendRequest(httpReq, 200)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attempt to parse the body.
+	parsed := listAllMyBucketsResult{}
+	if err := xml.Unmarshal(httpResp.Body, &parsed); err != nil {
+		return nil, fmt.Errorf(
+			"Invalid data from server (%s): %s",
+			err.Error(),
+			httpResp.Body)
+	}
+
+	if parsed.XMLName.Local != "ListAllMyBucketsResult" {
+		return nil, fmt.Errorf("Invalid data from server: %s", httpResp.Body)
+	}
+
+	buckets = make([]BucketInfo, len(parsed.Buckets))
+	for i, b := range parsed.Buckets {
+		buckets[i].Name = b.Name
+		if buckets[i].CreationDate, err = sys_time.Parse(sys_time.RFC3339, b.CreationDate); err != nil {
+			return nil, fmt.Errorf("Invalid CreationDate from server: %s", b.CreationDate)
+		}
+	}
+
+	return buckets, nil
+}
+
+////////////////////////////////////////////////////////////////////////
+// GetBucketLocation
+////////////////////////////////////////////////////////////////////////
+
+type locationConstraint struct {
+	XMLName  xml.Name
+	Location string `xml:",chardata"`
+}
+
+func (s *service) GetBucketLocation(name string) (location string, err error) {
+	// Validate the name.
+	if err := validateBucketName(name); err != nil {
+		return "", err
+	}
+
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketGETlocation.html
+	httpReq := s.newBucketRequest("GET", name)
+	httpReq.Parameters["location"] = ""
+
+	httpResp, err := s.sendRequest(httpReq, 200)
+	if err != nil {
+		return "", err
+	}
+
+	// Attempt to parse the body.
+	parsed := locationConstraint{}
+	if err := xml.Unmarshal(httpResp.Body, &parsed); err != nil {
+		return "", fmt.Errorf(
+			"Invalid data from server (%s): %s",
+			err.Error(),
+			httpResp.Body)
+	}
+
+	if parsed.XMLName.Local != "LocationConstraint" {
+		return "", fmt.Errorf("Invalid data from server: %s", httpResp.Body)
+	}
+
+	return parsed.Location, nil
+}
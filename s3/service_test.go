@@ -0,0 +1,406 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"context"
+	"errors"
+	"github.com/jacobsa/aws/s3/auth/mock"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/aws/s3/http/mock"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestService(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type ServiceTest struct {
+	httpConn mock_http.MockConn
+	signer   mock_auth.MockSigner
+	clock    *fakeClock
+	service  Service
+}
+
+func init() { RegisterTestSuite(&ServiceTest{}) }
+
+func (t *ServiceTest) SetUp(i *TestInfo) {
+	var err error
+
+	t.httpConn = mock_http.NewMockConn(i.MockController, "httpConn")
+	t.signer = mock_auth.NewMockSigner(i.MockController, "signer")
+	t.clock = &fakeClock{now: time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)}
+
+	t.service, err = openService(
+		&url.URL{Scheme: "https", Host: "s3.example.com"},
+		PathStyle,
+		t.httpConn,
+		t.signer,
+		t.clock)
+
+	AssertEq(nil, err)
+}
+
+// Expect a call to the signer, returning the request it is passed.
+func (t *ServiceTest) captureRequest() **http.Request {
+	httpReq := new(*http.Request)
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			*httpReq = r
+			return errors.New("")
+		}))
+
+	return httpReq
+}
+
+// Set up the signer and conn to return the supplied response.
+func (t *ServiceTest) respondWith(resp *http.Response) {
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *ServiceTest) InvalidBucketNames() {
+	var err error
+
+	err = t.service.CreateBucket("ab", nil)
+	ExpectThat(err, ErrorThat(HasSubstr("between 3 and 255")))
+
+	err = t.service.DeleteBucket("taco/burrito")
+	ExpectThat(err, ErrorThat(HasSubstr("Invalid bucket name")))
+
+	_, err = t.service.GetBucketLocation("taco burrito")
+	ExpectThat(err, ErrorThat(HasSubstr("Invalid bucket name")))
+}
+
+func (t *ServiceTest) CreateBucketCallsSigner() {
+	httpReq := t.captureRequest()
+
+	// Call
+	opts := &CreateBucketOptions{
+		LocationConstraint: "eu-west-1",
+		Acl:                AclPublicRead,
+	}
+
+	t.service.CreateBucket("some-bucket", opts)
+
+	AssertNe(nil, *httpReq)
+	ExpectEq("PUT", (*httpReq).Verb)
+	ExpectEq("/some-bucket", (*httpReq).Path)
+	ExpectEq("", (*httpReq).Bucket)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", (*httpReq).Headers["Date"])
+	ExpectEq("public-read", (*httpReq).Headers["x-amz-acl"])
+
+	ExpectEq(
+		`<CreateBucketConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">`+
+			"<LocationConstraint>eu-west-1</LocationConstraint>"+
+			"</CreateBucketConfiguration>",
+		string((*httpReq).Body))
+}
+
+func (t *ServiceTest) CreateBucketWithoutLocation() {
+	httpReq := t.captureRequest()
+
+	// Call
+	t.service.CreateBucket("some-bucket", nil)
+
+	AssertNe(nil, *httpReq)
+	ExpectEq(0, len((*httpReq).Body))
+	_, ok := (*httpReq).Headers["x-amz-acl"]
+	ExpectFalse(ok)
+}
+
+func (t *ServiceTest) CreateBucketSignerReturnsError() {
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(errors.New("taco")))
+
+	// Call
+	err := t.service.CreateBucket("some-bucket", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *ServiceTest) CreateBucketConnReturnsError() {
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	err := t.service.CreateBucket("some-bucket", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("SendRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *ServiceTest) CreateBucketServerReturnsError() {
+	t.respondWith(&http.Response{
+		StatusCode: 409,
+		Body: []byte(
+			"<Error><Code>BucketAlreadyExists</Code>" +
+				"<Message>The requested bucket name is not available.</Message></Error>"),
+	})
+
+	// Call
+	err := t.service.CreateBucket("some-bucket", nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("409")))
+	ExpectThat(err, ErrorThat(HasSubstr("BucketAlreadyExists")))
+}
+
+func (t *ServiceTest) CreateBucketSucceeds() {
+	t.respondWith(&http.Response{StatusCode: 200})
+
+	// Call
+	err := t.service.CreateBucket("some-bucket", nil)
+
+	ExpectEq(nil, err)
+}
+
+func (t *ServiceTest) VirtualHostedStyle() {
+	var err error
+	t.service, err = openService(
+		&url.URL{Scheme: "https", Host: "s3.example.com", Path: "/prefix"},
+		VirtualHostedStyle,
+		t.httpConn,
+		t.signer,
+		t.clock)
+
+	AssertEq(nil, err)
+
+	// DNS-compatible name
+	httpReq := t.captureRequest()
+	t.service.DeleteBucket("some-bucket")
+
+	AssertNe(nil, *httpReq)
+	ExpectEq("/prefix/", (*httpReq).Path)
+	ExpectEq("some-bucket", (*httpReq).Bucket)
+
+	// Other name
+	httpReq = t.captureRequest()
+	t.service.DeleteBucket("some.bucket")
+
+	AssertNe(nil, *httpReq)
+	ExpectEq("/prefix/some.bucket", (*httpReq).Path)
+	ExpectEq("", (*httpReq).Bucket)
+}
+
+func (t *ServiceTest) DeleteBucketCallsSigner() {
+	httpReq := t.captureRequest()
+
+	// Call
+	t.service.DeleteBucket("some-bucket")
+
+	AssertNe(nil, *httpReq)
+	ExpectEq("DELETE", (*httpReq).Verb)
+	ExpectEq("/some-bucket", (*httpReq).Path)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", (*httpReq).Headers["Date"])
+}
+
+func (t *ServiceTest) DeleteBucketServerReturnsError() {
+	t.respondWith(&http.Response{
+		StatusCode: 409,
+		Body:       []byte("<Error><Code>BucketNotEmpty</Code></Error>"),
+	})
+
+	// Call
+	err := t.service.DeleteBucket("some-bucket")
+
+	ExpectThat(err, ErrorThat(HasSubstr("BucketNotEmpty")))
+}
+
+func (t *ServiceTest) DeleteBucketSucceeds() {
+	t.respondWith(&http.Response{StatusCode: 204})
+
+	// Call
+	err := t.service.DeleteBucket("some-bucket")
+
+	ExpectEq(nil, err)
+}
+
+func (t *ServiceTest) ListBucketsCallsSigner() {
+	httpReq := t.captureRequest()
+
+	// Call
+	t.service.ListBuckets()
+
+	AssertNe(nil, *httpReq)
+	ExpectEq("GET", (*httpReq).Verb)
+	ExpectEq("/", (*httpReq).Path)
+	ExpectEq("", (*httpReq).Bucket)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", (*httpReq).Headers["Date"])
+}
+
+func (t *ServiceTest) ListBucketsReturnsJunk() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body:       []byte("<Taco/>"),
+	})
+
+	// Call
+	_, err := t.service.ListBuckets()
+
+	ExpectThat(err, ErrorThat(HasSubstr("Invalid data")))
+	ExpectThat(err, ErrorThat(HasSubstr("Taco")))
+}
+
+func (t *ServiceTest) ListBucketsReturnsInvalidDate() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(
+			"<ListAllMyBucketsResult><Buckets><Bucket>" +
+				"<Name>taco</Name><CreationDate>burrito</CreationDate>" +
+				"</Bucket></Buckets></ListAllMyBucketsResult>"),
+	})
+
+	// Call
+	_, err := t.service.ListBuckets()
+
+	ExpectThat(err, ErrorThat(HasSubstr("CreationDate")))
+	ExpectThat(err, ErrorThat(HasSubstr("burrito")))
+}
+
+func (t *ServiceTest) ListBucketsSucceeds() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<ListAllMyBucketsResult xmlns="http://s3.amazonaws.com/doc/2006-03-01">
+				<Owner>
+					<ID>bcaf1ffd86f461ca5fb16fd081034f</ID>
+					<DisplayName>webfile</DisplayName>
+				</Owner>
+				<Buckets>
+					<Bucket>
+						<Name>burrito</Name>
+						<CreationDate>2006-02-03T16:45:09.000Z</CreationDate>
+					</Bucket>
+					<Bucket>
+						<Name>taco</Name>
+						<CreationDate>2006-02-03T16:41:58.000Z</CreationDate>
+					</Bucket>
+				</Buckets>
+			</ListAllMyBucketsResult>`),
+	})
+
+	// Call
+	buckets, err := t.service.ListBuckets()
+	AssertEq(nil, err)
+
+	AssertEq(2, len(buckets))
+	ExpectEq("burrito", buckets[0].Name)
+	ExpectTrue(buckets[0].CreationDate.Equal(time.Date(2006, 2, 3, 16, 45, 9, 0, time.UTC)))
+	ExpectEq("taco", buckets[1].Name)
+	ExpectTrue(buckets[1].CreationDate.Equal(time.Date(2006, 2, 3, 16, 41, 58, 0, time.UTC)))
+}
+
+func (t *ServiceTest) GetBucketLocationCallsSigner() {
+	httpReq := t.captureRequest()
+
+	// Call
+	t.service.GetBucketLocation("some-bucket")
+
+	AssertNe(nil, *httpReq)
+	ExpectEq("GET", (*httpReq).Verb)
+	ExpectEq("/some-bucket", (*httpReq).Path)
+	ExpectThat((*httpReq).Parameters, DeepEquals(map[string]string{"location": ""}))
+}
+
+func (t *ServiceTest) GetBucketLocationSucceeds() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(
+			`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">` +
+				"eu-west-1</LocationConstraint>"),
+	})
+
+	// Call
+	location, err := t.service.GetBucketLocation("some-bucket")
+
+	AssertEq(nil, err)
+	ExpectEq("eu-west-1", location)
+}
+
+func (t *ServiceTest) GetBucketLocationInUsEast1() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body:       []byte(`<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/"/>`),
+	})
+
+	// Call
+	location, err := t.service.GetBucketLocation("some-bucket")
+
+	AssertEq(nil, err)
+	ExpectEq("", location)
+}
+
+func (t *ServiceTest) WithContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	var httpReq *http.Request
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) (*http.Response, error) {
+			httpReq = r
+			return nil, errors.New("")
+		}))
+
+	// Call
+	t.service.WithContext(ctx).ListBuckets()
+
+	AssertNe(nil, httpReq)
+	ExpectTrue(httpReq.Context == ctx)
+}
+
+func (t *ServiceTest) LaterContextWins() {
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	var httpReq *http.Request
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) (*http.Response, error) {
+			httpReq = r
+			return nil, errors.New("")
+		}))
+
+	// Call
+	t.service.WithContext(ctx1).WithContext(ctx2).ListBuckets()
+
+	AssertNe(nil, httpReq)
+	ExpectTrue(httpReq.Context == ctx2)
+}
@@ -0,0 +1,179 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"fmt"
+	"github.com/jacobsa/aws/s3/http"
+	"strconv"
+	"strings"
+	sys_time "time"
+)
+
+// ObjectInfo contains metadata about an object, as returned by
+// Bucket.StatObject.
+type ObjectInfo struct {
+	// The object's key.
+	Key string
+
+	// The size of the object's contents, in bytes, or -1 if the server didn't
+	// say.
+	Size int64
+
+	// The object's entity tag, exactly as returned by the server (including
+	// quotes).
+	ETag string
+
+	// The ID of this version of the object, or empty if the bucket has never
+	// had versioning enabled.
+	VersionId string
+
+	// The time at which the object was last modified, or the zero time if the
+	// server didn't say.
+	LastModified sys_time.Time
+
+	// The object's MIME type, if any.
+	ContentType string
+
+	// The kind of server-side encryption with which S3 stored the object, if
+	// any. Empty for objects encrypted with customer-provided keys.
+	ServerSideEncryption ServerSideEncryption
+
+	// The Content-Encoding and Cache-Control with which the object was stored,
+	// if any.
+	ContentEncoding string
+	CacheControl    string
+
+	// User-defined metadata stored with the object, from x-amz-meta-* headers.
+	// Names are lower case, with the x-amz-meta- prefix removed.
+	Metadata map[string]string
+}
+
+const amzMetaPrefix = "X-Amz-Meta-"
+
+// Parse object metadata out of the headers of a response to a GET or HEAD
+// request. Size is -1 if there is no Content-Length header.
+func parseObjectInfo(
+	key string,
+	headers map[string]string) (info *ObjectInfo, err error) {
+	info = &ObjectInfo{
+		Key:                  key,
+		ETag:                 headers["Etag"],
+		VersionId:            headers["X-Amz-Version-Id"],
+		ContentType:          headers["Content-Type"],
+		ServerSideEncryption: ServerSideEncryption(headers["X-Amz-Server-Side-Encryption"]),
+		ContentEncoding:      headers["Content-Encoding"],
+		CacheControl:         headers["Cache-Control"],
+		Metadata:             map[string]string{},
+	}
+
+	// Size
+	info.Size = -1
+	if sizeStr, ok := headers["Content-Length"]; ok {
+		if info.Size, err = strconv.ParseInt(sizeStr, 10, 64); err != nil || info.Size < 0 {
+			return nil, fmt.Errorf("Invalid Content-Length from server: %s", sizeStr)
+		}
+	}
+
+	// Last-Modified
+	if s, ok := headers["Last-Modified"]; ok {
+		if info.LastModified, err = sys_time.Parse(sys_time.RFC1123, s); err != nil {
+			return nil, fmt.Errorf("Invalid Last-Modified from server: %s", s)
+		}
+	}
+
+	// User-defined metadata
+	for name, val := range headers {
+		if strings.HasPrefix(name, amzMetaPrefix) {
+			info.Metadata[strings.ToLower(name[len(amzMetaPrefix):])] = val
+		}
+	}
+
+	return info, nil
+}
+
+// Options for Bucket.StatObjectWithOptions. The zero value is equivalent to
+// calling StatObject.
+type StatOptions struct {
+	// The key with which the object was encrypted, if it was stored with
+	// StoreOptions.CustomerKey. S3 refuses to describe such objects without it.
+	CustomerKey []byte
+}
+
+func (b *bucket) StatObject(key string) (info *ObjectInfo, err error) {
+	return b.StatObjectWithOptions(key, nil)
+}
+
+func (b *bucket) StatObjectWithOptions(
+	key string,
+	opts *StatOptions) (info *ObjectInfo, err error) {
+	if opts == nil {
+		opts = &StatOptions{}
+	}
+
+	// Validate the key.
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.amazonwebservices.com/AmazonS3/latest/API/RESTObjectHEAD.html
+	httpReq := &http.Request{
+		Verb:   "HEAD",
+		Path:   b.objectPath(key),
+		Bucket: b.hostBucket(),
+		Headers: map[string]string{
+			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+	}
+
+	if err := addCustomerKeyHeaders(httpReq.Headers, opts.CustomerKey); err != nil {
+		return nil, err
+	}
+
+	// Sign the request.
+	if err := b.signer.Sign(httpReq); err != nil {
+		return nil, fmt.Errorf("Sign: %v", err)
+	}
+
+	// Send the request.
+	httpResp, err := b.httpConn.SendRequest(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("SendRequest: %v", err)
+	}
+
+	// Check the response. A HEAD response has no body, so there is no further
+	// detail to be had about errors.
+	switch httpResp.StatusCode {
+	case 200:
+	case 404:
+		return nil, &Error{StatusCode: 404, Message: "Not Found", Key: key}
+	default:
+		return nil, newError(httpResp.StatusCode, httpResp.Body)
+	}
+
+	if info, err = parseObjectInfo(key, httpResp.Headers); err != nil {
+		return nil, err
+	}
+
+	if info.Size < 0 {
+		return nil, fmt.Errorf("No Content-Length in response from server.")
+	}
+
+	return info, nil
+}
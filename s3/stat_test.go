@@ -0,0 +1,235 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"testing"
+	"time"
+)
+
+func TestStat(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type StatObjectTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&StatObjectTest{}) }
+
+// Set up the signer and conn to return the supplied response.
+func (t *StatObjectTest) respondWith(resp *http.Response) {
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *StatObjectTest) KeyIsEmpty() {
+	// Call
+	_, err := t.bucket.StatObject("")
+
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *StatObjectTest) CallsSigner() {
+	key := "foo/bar/baz"
+
+	// Clock
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+
+	// Signer
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	// Call
+	t.bucket.StatObject(key)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("HEAD", httpReq.Verb)
+	ExpectEq("/some.bucket/foo/bar/baz", httpReq.Path)
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", httpReq.Headers["Date"])
+}
+
+func (t *StatObjectTest) CustomerKey() {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	// Signer
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	// Call
+	t.bucket.StatObjectWithOptions("a", &StatOptions{CustomerKey: key})
+
+	AssertNe(nil, httpReq)
+	ExpectEq("AES256", httpReq.Headers["x-amz-server-side-encryption-customer-algorithm"])
+	ExpectEq(
+		"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+		httpReq.Headers["x-amz-server-side-encryption-customer-key"])
+	ExpectEq(
+		computeBase64Md5(key),
+		httpReq.Headers["x-amz-server-side-encryption-customer-key-MD5"])
+}
+
+func (t *StatObjectTest) CustomerKeyHasWrongLength() {
+	// Call
+	_, err := t.bucket.StatObjectWithOptions("a", &StatOptions{CustomerKey: []byte("taco")})
+
+	ExpectThat(err, ErrorThat(HasSubstr("32 bytes long; got 4")))
+}
+
+func (t *StatObjectTest) SignerReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(errors.New("taco")))
+
+	// Call
+	_, err := t.bucket.StatObject("a")
+
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *StatObjectTest) ConnReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	_, err := t.bucket.StatObject("a")
+
+	ExpectThat(err, ErrorThat(HasSubstr("SendRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *StatObjectTest) ServerSaysNotFound() {
+	t.respondWith(&http.Response{StatusCode: 404})
+
+	// Call
+	_, err := t.bucket.StatObject("a")
+
+	AssertNe(nil, err)
+	ExpectTrue(IsNotFound(err), "%v", err)
+
+	s3Err, ok := err.(*Error)
+	AssertTrue(ok, "%v", err)
+	ExpectEq(404, s3Err.StatusCode)
+	ExpectEq("a", s3Err.Key)
+}
+
+func (t *StatObjectTest) ServerReturnsError() {
+	t.respondWith(&http.Response{StatusCode: 403})
+
+	// Call
+	_, err := t.bucket.StatObject("a")
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("403")))
+	ExpectTrue(IsAccessDenied(err))
+}
+
+func (t *StatObjectTest) MissingContentLength() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Headers:    map[string]string{},
+	})
+
+	// Call
+	_, err := t.bucket.StatObject("a")
+
+	ExpectThat(err, ErrorThat(HasSubstr("Content-Length")))
+}
+
+func (t *StatObjectTest) InvalidLastModified() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Content-Length": "17",
+			"Last-Modified":  "taco",
+		},
+	})
+
+	// Call
+	_, err := t.bucket.StatObject("a")
+
+	ExpectThat(err, ErrorThat(HasSubstr("Last-Modified")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *StatObjectTest) ReturnsInfo() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Cache-Control":       "max-age=60",
+			"Content-Encoding":    "gzip",
+			"Content-Length":      "17",
+			"Content-Type":        "text/plain",
+			"Etag":                `"deadbeef"`,
+			"Last-Modified":       "Mon, 18 Mar 1985 15:33:17 GMT",
+			"X-Amz-Meta-Flavor":   "taco",
+			"X-Amz-Meta-Spicy":    "true",
+			"X-Amz-Request-Id":    "blah",
+			"X-Amz-Storage-Class": "STANDARD",
+			"X-Amz-Version-Id":    "3HL4kqtJlcpXroDTDmjVBH40Nrjfkd",
+
+			"X-Amz-Server-Side-Encryption": "aws:kms",
+		},
+	})
+
+	// Call
+	info, err := t.bucket.StatObject("a")
+	AssertEq(nil, err)
+
+	ExpectEq("a", info.Key)
+	ExpectEq(17, info.Size)
+	ExpectEq("text/plain", info.ContentType)
+	ExpectEq("gzip", info.ContentEncoding)
+	ExpectEq("max-age=60", info.CacheControl)
+	ExpectEq(`"deadbeef"`, info.ETag)
+	ExpectEq("3HL4kqtJlcpXroDTDmjVBH40Nrjfkd", info.VersionId)
+	ExpectEq(SseKms, info.ServerSideEncryption)
+	ExpectTrue(
+		info.LastModified.Equal(time.Date(1985, time.March, 18, 15, 33, 17, 0, time.UTC)),
+		"%v",
+		info.LastModified)
+
+	ExpectEq(2, len(info.Metadata))
+	ExpectEq("taco", info.Metadata["flavor"])
+	ExpectEq("true", info.Metadata["spicy"])
+}
@@ -0,0 +1,232 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"fmt"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/aws/s3/internal/validate"
+	"io"
+	sys_time "time"
+)
+
+// A canned access control list that may be applied to an object when it is
+// stored.
+//
+// Reference:
+//     http://docs.amazonwebservices.com/AmazonS3/latest/dev/ACLOverview.html#CannedACL
+type CannedAcl string
+
+const (
+	AclPrivate                CannedAcl = "private"
+	AclPublicRead             CannedAcl = "public-read"
+	AclPublicReadWrite        CannedAcl = "public-read-write"
+	AclAuthenticatedRead      CannedAcl = "authenticated-read"
+	AclBucketOwnerRead        CannedAcl = "bucket-owner-read"
+	AclBucketOwnerFullControl CannedAcl = "bucket-owner-full-control"
+)
+
+// The storage class with which an object is stored.
+type StorageClass string
+
+const (
+	StorageClassStandard          StorageClass = "STANDARD"
+	StorageClassReducedRedundancy StorageClass = "REDUCED_REDUNDANCY"
+	StorageClassStandardIa        StorageClass = "STANDARD_IA"
+)
+
+// Options controlling the way in which an object is stored. The zero value
+// stores an object in the same way as StoreObject.
+type StoreOptions struct {
+	// The MIME type of the object, returned as Content-Type when it is
+	// retrieved. If empty, S3 uses binary/octet-stream.
+	ContentType string
+
+	// Returned as Content-Encoding when the object is retrieved, e.g. "gzip".
+	ContentEncoding string
+
+	// Returned as Cache-Control when the object is retrieved.
+	CacheControl string
+
+	// The canned ACL to apply to the object. If empty, it is private.
+	Acl CannedAcl
+
+	// The storage class of the object. If empty, it is STANDARD.
+	StorageClass StorageClass
+
+	// If non-empty, the object is stored only if an object with this entity
+	// tag currently exists under the key, allowing for optimistic concurrency
+	// control. Otherwise the error is of type *PreconditionFailedError.
+	IfMatch string
+
+	// If non-empty, the object is stored only if no object with a matching
+	// entity tag exists under the key. In particular "*" means that the object
+	// is stored only if the key doesn't yet exist. Otherwise the error is of
+	// type *PreconditionFailedError.
+	IfNoneMatch string
+
+	// User-defined metadata to store with the object, sent as x-amz-meta-*
+	// headers. Names are case-insensitive (S3 lower-cases them) and must
+	// consist of letters, digits, hyphens, and underscores. Values must be
+	// printable ASCII.
+	Metadata map[string]string
+
+	// If non-empty, S3 encrypts the object at rest in this manner.
+	ServerSideEncryption ServerSideEncryption
+
+	// The ID or ARN of the KMS key with which to encrypt the object. May be set
+	// only with SseKms; if empty, the account's default key is used.
+	KmsKeyId string
+
+	// If non-nil, S3 encrypts the object with this key of CustomerKeyLength
+	// bytes (SSE-C), which it doesn't store. The same key must then be supplied
+	// with GetOptions, StatOptions, or RangeOptions to read or describe the
+	// object. May not be combined with ServerSideEncryption. S3 accepts
+	// customer keys only over HTTPS.
+	CustomerKey []byte
+}
+
+// The result of successfully storing an object.
+type StoreResult struct {
+	// The entity tag of the new object, as returned by the server (including
+	// quotes).
+	ETag string
+
+	// The ID of the version created, or empty if the bucket has never had
+	// versioning enabled.
+	VersionId string
+}
+
+// Add the headers called for by the supplied options to the request.
+func addStoreOptionHeaders(r *http.Request, opts *StoreOptions) error {
+	if err := validate.Metadata(opts.Metadata); err != nil {
+		return err
+	}
+
+	if opts.ContentType != "" {
+		r.Headers["Content-Type"] = opts.ContentType
+	}
+
+	if opts.ContentEncoding != "" {
+		r.Headers["Content-Encoding"] = opts.ContentEncoding
+	}
+
+	if opts.CacheControl != "" {
+		r.Headers["Cache-Control"] = opts.CacheControl
+	}
+
+	if opts.Acl != "" {
+		r.Headers["x-amz-acl"] = string(opts.Acl)
+	}
+
+	if opts.StorageClass != "" {
+		r.Headers["x-amz-storage-class"] = string(opts.StorageClass)
+	}
+
+	if opts.IfMatch != "" {
+		r.Headers["If-Match"] = opts.IfMatch
+	}
+
+	if opts.IfNoneMatch != "" {
+		r.Headers["If-None-Match"] = opts.IfNoneMatch
+	}
+
+	for name, val := range opts.Metadata {
+		r.Headers["x-amz-meta-"+name] = val
+	}
+
+	return addEncryptionHeaders(
+		r,
+		opts.ServerSideEncryption,
+		opts.KmsKeyId,
+		opts.CustomerKey)
+}
+
+func (b *bucket) StoreObjectWithOptions(
+	key string,
+	r io.Reader,
+	size int64,
+	opts *StoreOptions) (result *StoreResult, err error) {
+	// Validate the key.
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &StoreOptions{}
+	}
+
+	// Figure out the length and MD5 sum of the data, and obtain a stream that
+	// will yield the data again.
+	body, n, sum, cleanup, err := prepareBody(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	defer cleanup()
+
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.amazonwebservices.com/AmazonS3/latest/API/RESTObjectPUT.html
+	httpReq := &http.Request{
+		Verb:          "PUT",
+		Path:          b.objectPath(key),
+		Bucket:        b.hostBucket(),
+		BodyReader:    body,
+		ContentLength: n,
+		Headers: map[string]string{
+			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+	}
+
+	if err := addStoreOptionHeaders(httpReq, opts); err != nil {
+		return nil, err
+	}
+
+	// Add a Content-MD5 header, so that S3 can detect data corrupted in transit.
+	if err := addMd5HeaderForSum(httpReq, sum); err != nil {
+		return nil, err
+	}
+
+	// Sign the request.
+	if err := b.signer.Sign(httpReq); err != nil {
+		return nil, fmt.Errorf("Sign: %v", err)
+	}
+
+	// Send the request.
+	httpResp, err := b.httpConn.SendRequest(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("SendRequest: %v", err)
+	}
+
+	// Check the response.
+	switch httpResp.StatusCode {
+	case 200:
+	case 412:
+		return nil, &PreconditionFailedError{Key: key}
+
+	default:
+		return nil, newError(httpResp.StatusCode, httpResp.Body)
+	}
+
+	result = &StoreResult{
+		ETag:      httpResp.Headers["Etag"],
+		VersionId: httpResp.Headers["X-Amz-Version-Id"],
+	}
+
+	return result, nil
+}
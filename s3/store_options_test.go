@@ -0,0 +1,271 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"strings"
+	"testing"
+)
+
+func TestStoreOptions(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type StoreObjectWithOptionsTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&StoreObjectWithOptionsTest{}) }
+
+// Call StoreObjectWithOptions with the supplied options, returning the request
+// passed to the signer.
+func (t *StoreObjectWithOptionsTest) captureRequest(
+	opts *StoreOptions) *http.Request {
+	var httpReq *http.Request
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			httpReq = r
+			return errors.New("")
+		}))
+
+	t.bucket.StoreObjectWithOptions("a", strings.NewReader("taco"), 4, opts)
+	return httpReq
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *StoreObjectWithOptionsTest) KeyIsEmpty() {
+	// Call
+	_, err := t.bucket.StoreObjectWithOptions("", strings.NewReader(""), 0, nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+}
+
+func (t *StoreObjectWithOptionsTest) EmptyMetadataName() {
+	opts := &StoreOptions{
+		Metadata: map[string]string{"": "taco"},
+	}
+
+	// Call
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader(""), 0, opts)
+
+	ExpectThat(err, ErrorThat(HasSubstr("non-empty")))
+}
+
+func (t *StoreObjectWithOptionsTest) InvalidMetadataName() {
+	opts := &StoreOptions{
+		Metadata: map[string]string{"taco: burrito": "enchilada"},
+	}
+
+	// Call
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader(""), 0, opts)
+
+	ExpectThat(err, ErrorThat(HasSubstr("metadata name")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco: burrito")))
+}
+
+func (t *StoreObjectWithOptionsTest) InvalidMetadataValue() {
+	opts := &StoreOptions{
+		Metadata: map[string]string{"taco": "burrito\nenchilada"},
+	}
+
+	// Call
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader(""), 0, opts)
+
+	ExpectThat(err, ErrorThat(HasSubstr("Invalid value")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *StoreObjectWithOptionsTest) NilOptions() {
+	httpReq := t.captureRequest(nil)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("PUT", httpReq.Verb)
+	ExpectEq("/some.bucket/a", httpReq.Path)
+	ExpectEq(4, httpReq.ContentLength)
+	ExpectEq(computeBase64Md5([]byte("taco")), httpReq.Headers["Content-MD5"])
+	ExpectEq(2, len(httpReq.Headers), "%v", httpReq.Headers)
+}
+
+func (t *StoreObjectWithOptionsTest) AllOptions() {
+	opts := &StoreOptions{
+		ContentType:     "text/plain",
+		ContentEncoding: "gzip",
+		CacheControl:    "max-age=60",
+		Acl:             AclPublicRead,
+		StorageClass:    StorageClassReducedRedundancy,
+		IfMatch:         `"taco"`,
+		IfNoneMatch:     "*",
+		Metadata: map[string]string{
+			"flavor":      "taco",
+			"Spice_Level": "7",
+		},
+	}
+
+	httpReq := t.captureRequest(opts)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("text/plain", httpReq.Headers["Content-Type"])
+	ExpectEq("gzip", httpReq.Headers["Content-Encoding"])
+	ExpectEq("max-age=60", httpReq.Headers["Cache-Control"])
+	ExpectEq("public-read", httpReq.Headers["x-amz-acl"])
+	ExpectEq("REDUCED_REDUNDANCY", httpReq.Headers["x-amz-storage-class"])
+	ExpectEq(`"taco"`, httpReq.Headers["If-Match"])
+	ExpectEq("*", httpReq.Headers["If-None-Match"])
+	ExpectEq("taco", httpReq.Headers["x-amz-meta-flavor"])
+	ExpectEq("7", httpReq.Headers["x-amz-meta-Spice_Level"])
+}
+
+func (t *StoreObjectWithOptionsTest) InvalidEncryptionOptions() {
+	testCases := []struct {
+		opts     StoreOptions
+		expected string
+	}{
+		{StoreOptions{ServerSideEncryption: "taco"}, "Invalid server-side encryption"},
+		{StoreOptions{KmsKeyId: "taco"}, "only with SseKms"},
+		{StoreOptions{ServerSideEncryption: SseS3, KmsKeyId: "taco"}, "only with SseKms"},
+		{StoreOptions{CustomerKey: make([]byte, 16)}, "32 bytes long; got 16"},
+		{
+			StoreOptions{ServerSideEncryption: SseS3, CustomerKey: make([]byte, 32)},
+			"may not be combined with AES256",
+		},
+	}
+
+	for i, tc := range testCases {
+		_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader(""), 0, &tc.opts)
+		ExpectThat(err, ErrorThat(HasSubstr(tc.expected)), "Test case %d", i)
+	}
+}
+
+func (t *StoreObjectWithOptionsTest) SseS3() {
+	httpReq := t.captureRequest(&StoreOptions{ServerSideEncryption: SseS3})
+
+	AssertNe(nil, httpReq)
+	ExpectEq("AES256", httpReq.Headers["x-amz-server-side-encryption"])
+	_, ok := httpReq.Headers["x-amz-server-side-encryption-aws-kms-key-id"]
+	ExpectFalse(ok)
+}
+
+func (t *StoreObjectWithOptionsTest) SseKms() {
+	opts := &StoreOptions{
+		ServerSideEncryption: SseKms,
+		KmsKeyId:             "arn:aws:kms:us-east-1:123456789012:key/taco",
+	}
+
+	httpReq := t.captureRequest(opts)
+
+	AssertNe(nil, httpReq)
+	ExpectEq("aws:kms", httpReq.Headers["x-amz-server-side-encryption"])
+	ExpectEq(
+		"arn:aws:kms:us-east-1:123456789012:key/taco",
+		httpReq.Headers["x-amz-server-side-encryption-aws-kms-key-id"])
+}
+
+func (t *StoreObjectWithOptionsTest) CustomerKey() {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	httpReq := t.captureRequest(&StoreOptions{CustomerKey: key})
+
+	AssertNe(nil, httpReq)
+	_, ok := httpReq.Headers["x-amz-server-side-encryption"]
+	ExpectFalse(ok)
+
+	ExpectEq("AES256", httpReq.Headers["x-amz-server-side-encryption-customer-algorithm"])
+	ExpectEq(
+		"MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+		httpReq.Headers["x-amz-server-side-encryption-customer-key"])
+	ExpectEq(
+		computeBase64Md5(key),
+		httpReq.Headers["x-amz-server-side-encryption-customer-key-MD5"])
+}
+
+func (t *StoreObjectWithOptionsTest) ServerReturnsError() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 500,
+		Body:       []byte("taco"),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader(""), 0, nil)
+
+	ExpectThat(err, ErrorThat(HasSubstr("server")))
+	ExpectThat(err, ErrorThat(HasSubstr("500")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *StoreObjectWithOptionsTest) ServerSaysPreconditionFailed() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 412,
+		Body:       []byte("<Error>...</Error>"),
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	opts := &StoreOptions{IfMatch: `"taco"`}
+	_, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader(""), 0, opts)
+
+	AssertNe(nil, err)
+	failed, ok := err.(*PreconditionFailedError)
+	AssertTrue(ok, "%v", err)
+	ExpectEq("a", failed.Key)
+}
+
+func (t *StoreObjectWithOptionsTest) ReturnsETag() {
+	// Signer
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// Conn
+	resp := &http.Response{
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Etag":             `"deadbeef"`,
+			"X-Amz-Version-Id": "taco",
+		},
+	}
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+
+	// Call
+	result, err := t.bucket.StoreObjectWithOptions("a", strings.NewReader(""), 0, nil)
+	AssertEq(nil, err)
+
+	ExpectEq(`"deadbeef"`, result.ETag)
+	ExpectEq("taco", result.VersionId)
+}
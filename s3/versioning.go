@@ -0,0 +1,360 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/jacobsa/aws/s3/http"
+	"strconv"
+	sys_time "time"
+)
+
+// The versioning state of a bucket.
+type VersioningStatus string
+
+const (
+	// New objects are given unique version IDs, and overwriting or deleting an
+	// object preserves its previous versions.
+	VersioningEnabled VersioningStatus = "Enabled"
+
+	// New objects are given the version ID "null", replacing any existing
+	// version with that ID. Existing versions are preserved.
+	VersioningSuspended VersioningStatus = "Suspended"
+)
+
+// A request to list the versions of objects in a bucket. See
+// Bucket.ListObjectVersions.
+type ListVersionsRequest struct {
+	// If non-empty, only versions of keys beginning with this prefix are
+	// returned.
+	Prefix string
+
+	// Rolls keys up into common prefixes, as with ListRequest.Delimiter.
+	Delimiter string
+
+	// If non-empty, only versions of keys strictly greater than this are
+	// returned, unless VersionIdMarker is also set. It must be a valid key.
+	KeyMarker string
+
+	// If non-empty, only versions of KeyMarker older than the one with this ID
+	// are returned, along with versions of greater keys. KeyMarker must be set.
+	VersionIdMarker string
+
+	// The maximum number of versions, delete markers, and common prefixes to
+	// return. If zero, the server's default (1000 for S3) is used.
+	MaxKeys int
+}
+
+// A version of an object, or a delete marker, as returned by
+// Bucket.ListObjectVersions.
+type ObjectVersion struct {
+	// The object's key.
+	Key string
+
+	// The ID of this version. Versions created while versioning was not
+	// enabled have the ID "null".
+	VersionId string
+
+	// Whether this is the latest version of the object.
+	IsLatest bool
+
+	// Whether this version is a delete marker, which has no contents and hides
+	// older versions from GetObject and friends.
+	IsDeleteMarker bool
+
+	// The time at which the version was created.
+	LastModified sys_time.Time
+
+	// The entity tag and size of the version's contents. Empty for delete
+	// markers.
+	ETag string
+	Size int64
+}
+
+// The result of a request to list the versions of objects in a bucket. See
+// Bucket.ListObjectVersions.
+type ListVersionsResult struct {
+	// The versions and delete markers matching the request, ordered by key and
+	// then from newest to oldest.
+	Versions []ObjectVersion
+
+	// The common prefixes that matching keys were rolled up into, in order.
+	// Empty unless a delimiter was specified.
+	CommonPrefixes []string
+
+	// Whether there are further versions or common prefixes matching the
+	// request.
+	IsTruncated bool
+
+	// If IsTruncated is true, the markers to use to obtain the next batch of
+	// results.
+	NextKeyMarker       string
+	NextVersionIdMarker string
+}
+
+type versioningConfiguration struct {
+	XMLName xml.Name `xml:"VersioningConfiguration"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	Status  VersioningStatus
+}
+
+// A Version or DeleteMarker element. S3 interleaves the two in the order in
+// which they're to be returned, so they're unmarshalled into a single slice.
+type listVersionsEntry struct {
+	XMLName      xml.Name
+	Key          string
+	VersionId    string
+	IsLatest     bool
+	LastModified string
+	ETag         string
+	Size         int64
+}
+
+type listVersionsResult struct {
+	XMLName             xml.Name
+	IsTruncated         bool
+	NextKeyMarker       string
+	NextVersionIdMarker string
+	CommonPrefixes      []bucketCommonPrefixes
+	Entries             []listVersionsEntry `xml:",any"`
+}
+
+func (b *bucket) SetVersioning(status VersioningStatus) error {
+	switch status {
+	case VersioningEnabled, VersioningSuspended:
+	default:
+		return fmt.Errorf("Invalid versioning status: %q", status)
+	}
+
+	body, err := xml.Marshal(versioningConfiguration{
+		Xmlns:  "http://s3.amazonaws.com/doc/2006-03-01/",
+		Status: status,
+	})
+
+	if err != nil {
+		return fmt.Errorf("xml.Marshal: %v", err)
+	}
+
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketPUTVersioningStatus.html
+	httpReq := &http.Request{
+		Verb:   "PUT",
+		Path:   b.bucketPath(),
+		Bucket: b.hostBucket(),
+		Body:   body,
+		Headers: map[string]string{
+			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+		Parameters: map[string]string{
+			"versioning": "",
+		},
+	}
+
+	if err := addMd5Header(httpReq, httpReq.Body); err != nil {
+		return err
+	}
+
+	_, err = b.sendRequest(httpReq, 200)
+	return err
+}
+
+func (b *bucket) GetVersioning() (status VersioningStatus, err error) {
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketGETversioningStatus.html
+	httpReq := &http.Request{
+		Verb:   "GET",
+		Path:   b.bucketPath(),
+		Bucket: b.hostBucket(),
+		Headers: map[string]string{
+			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+		Parameters: map[string]string{
+			"versioning": "",
+		},
+	}
+
+	httpResp, err := b.sendRequest(httpReq, 200)
+	if err != nil {
+		return "", err
+	}
+
+	// Parse the body. The Status element is missing if versioning has never
+	// been enabled.
+	parsed := versioningConfiguration{}
+	if err := xml.Unmarshal(httpResp.Body, &parsed); err != nil {
+		return "", fmt.Errorf(
+			"Invalid data from server (%s): %s",
+			err.Error(),
+			httpResp.Body)
+	}
+
+	return parsed.Status, nil
+}
+
+func (b *bucket) DeleteObjectVersion(key string, versionId string) error {
+	// Validate the arguments.
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	if versionId == "" {
+		return fmt.Errorf("A version ID is required.")
+	}
+
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectDELETE.html
+	httpReq := &http.Request{
+		Verb:   "DELETE",
+		Path:   b.objectPath(key),
+		Bucket: b.hostBucket(),
+		Headers: map[string]string{
+			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+		Parameters: map[string]string{
+			"versionId": versionId,
+		},
+	}
+
+	_, err := b.sendRequest(httpReq, 204)
+	return err
+}
+
+func (b *bucket) ListObjectVersions(
+	req ListVersionsRequest) (result *ListVersionsResult, err error) {
+	// Check the request in the same way as ListObjects.
+	if err := ValidateKey(req.KeyMarker); err != nil && req.KeyMarker != "" {
+		return nil, err
+	}
+
+	if req.VersionIdMarker != "" && req.KeyMarker == "" {
+		return nil, fmt.Errorf("A version ID marker requires a key marker.")
+	}
+
+	if err := ValidateKey(req.Prefix); err != nil && req.Prefix != "" {
+		return nil, fmt.Errorf("Invalid prefix: %v", err)
+	}
+
+	if err := ValidateKey(req.Delimiter); err != nil && req.Delimiter != "" {
+		return nil, fmt.Errorf("Invalid delimiter: %v", err)
+	}
+
+	if req.MaxKeys < 0 {
+		return nil, fmt.Errorf("Invalid max keys: %d", req.MaxKeys)
+	}
+
+	// Build an appropriate HTTP request.
+	//
+	// Reference:
+	//     http://docs.aws.amazon.com/AmazonS3/latest/API/RESTBucketGETVersion.html
+	httpReq := &http.Request{
+		Verb:   "GET",
+		Path:   b.bucketPath(),
+		Bucket: b.hostBucket(),
+		Headers: map[string]string{
+			"Date": b.clock.Now().UTC().Format(sys_time.RFC1123),
+		},
+		Parameters: map[string]string{
+			"versions": "",
+		},
+	}
+
+	if req.Prefix != "" {
+		httpReq.Parameters["prefix"] = req.Prefix
+	}
+
+	if req.Delimiter != "" {
+		httpReq.Parameters["delimiter"] = req.Delimiter
+	}
+
+	if req.KeyMarker != "" {
+		httpReq.Parameters["key-marker"] = req.KeyMarker
+	}
+
+	if req.VersionIdMarker != "" {
+		httpReq.Parameters["version-id-marker"] = req.VersionIdMarker
+	}
+
+	if req.MaxKeys != 0 {
+		httpReq.Parameters["max-keys"] = strconv.Itoa(req.MaxKeys)
+	}
+
+	httpResp, err := b.sendRequest(httpReq, 200)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attempt to parse the body.
+	parsed := listVersionsResult{}
+	if err := xml.Unmarshal(httpResp.Body, &parsed); err != nil {
+		return nil, fmt.Errorf(
+			"Invalid data from server (%s): %s",
+			err.Error(),
+			httpResp.Body)
+	}
+
+	if parsed.XMLName.Local != "ListVersionsResult" {
+		return nil, fmt.Errorf("Invalid data from server: %s", httpResp.Body)
+	}
+
+	result = &ListVersionsResult{
+		Versions:       []ObjectVersion{},
+		CommonPrefixes: make([]string, len(parsed.CommonPrefixes)),
+		IsTruncated:    parsed.IsTruncated,
+	}
+
+	for _, entry := range parsed.Entries {
+		v := ObjectVersion{
+			Key:       entry.Key,
+			VersionId: entry.VersionId,
+			IsLatest:  entry.IsLatest,
+			ETag:      entry.ETag,
+			Size:      entry.Size,
+		}
+
+		switch entry.XMLName.Local {
+		case "Version":
+		case "DeleteMarker":
+			v.IsDeleteMarker = true
+		default:
+			// Some other element that we don't care about.
+			continue
+		}
+
+		if v.LastModified, err = sys_time.Parse(sys_time.RFC3339, entry.LastModified); err != nil {
+			return nil, fmt.Errorf("Invalid LastModified from server: %s", entry.LastModified)
+		}
+
+		result.Versions = append(result.Versions, v)
+	}
+
+	for i, elem := range parsed.CommonPrefixes {
+		result.CommonPrefixes[i] = elem.Prefix
+	}
+
+	if result.IsTruncated {
+		result.NextKeyMarker = parsed.NextKeyMarker
+		result.NextVersionIdMarker = parsed.NextVersionIdMarker
+	}
+
+	return result, nil
+}
@@ -0,0 +1,402 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"errors"
+	"github.com/jacobsa/aws/s3/http"
+	"github.com/jacobsa/oglemock"
+	. "github.com/jacobsa/ogletest"
+	"testing"
+	"time"
+)
+
+func TestVersioning(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type VersioningTest struct {
+	bucketTest
+}
+
+func init() { RegisterTestSuite(&VersioningTest{}) }
+
+func (t *VersioningTest) SetUp(i *TestInfo) {
+	t.bucketTest.SetUp(i)
+	t.clock.now = time.Date(1985, time.March, 18, 15, 33, 17, 123, time.UTC)
+}
+
+// Expect a call to the signer, returning the request it is passed.
+func (t *VersioningTest) captureRequest() **http.Request {
+	httpReq := new(*http.Request)
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Invoke(func(r *http.Request) error {
+			*httpReq = r
+			return errors.New("")
+		}))
+
+	return httpReq
+}
+
+// Set up the signer and conn to return the supplied response.
+func (t *VersioningTest) respondWith(resp *http.Response) {
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(resp, nil))
+}
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *VersioningTest) SetVersioningInvalidStatus() {
+	// Call
+	err := t.bucket.SetVersioning("Taco")
+
+	ExpectThat(err, ErrorThat(HasSubstr("Invalid versioning status")))
+	ExpectThat(err, ErrorThat(HasSubstr("Taco")))
+}
+
+func (t *VersioningTest) SetVersioningCallsSigner() {
+	httpReq := t.captureRequest()
+
+	// Call
+	t.bucket.SetVersioning(VersioningSuspended)
+
+	AssertNe(nil, *httpReq)
+	ExpectEq("PUT", (*httpReq).Verb)
+	ExpectEq("/some.bucket", (*httpReq).Path)
+	ExpectThat((*httpReq).Parameters, DeepEquals(map[string]string{"versioning": ""}))
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", (*httpReq).Headers["Date"])
+	ExpectNe("", (*httpReq).Headers["Content-MD5"])
+
+	ExpectEq(
+		`<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">`+
+			"<Status>Suspended</Status>"+
+			"</VersioningConfiguration>",
+		string((*httpReq).Body))
+}
+
+func (t *VersioningTest) SetVersioningServerReturnsError() {
+	t.respondWith(&http.Response{
+		StatusCode: 403,
+		Body:       []byte("<Error><Code>AccessDenied</Code></Error>"),
+	})
+
+	// Call
+	err := t.bucket.SetVersioning(VersioningEnabled)
+
+	ExpectThat(err, ErrorThat(HasSubstr("403")))
+	ExpectThat(err, ErrorThat(HasSubstr("AccessDenied")))
+}
+
+func (t *VersioningTest) SetVersioningSucceeds() {
+	t.respondWith(&http.Response{StatusCode: 200})
+
+	// Call
+	err := t.bucket.SetVersioning(VersioningEnabled)
+
+	ExpectEq(nil, err)
+}
+
+func (t *VersioningTest) GetVersioningCallsSigner() {
+	httpReq := t.captureRequest()
+
+	// Call
+	t.bucket.GetVersioning()
+
+	AssertNe(nil, *httpReq)
+	ExpectEq("GET", (*httpReq).Verb)
+	ExpectEq("/some.bucket", (*httpReq).Path)
+	ExpectThat((*httpReq).Parameters, DeepEquals(map[string]string{"versioning": ""}))
+}
+
+func (t *VersioningTest) GetVersioningNeverEnabled() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body:       []byte(`<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"/>`),
+	})
+
+	// Call
+	status, err := t.bucket.GetVersioning()
+
+	AssertEq(nil, err)
+	ExpectEq("", status)
+}
+
+func (t *VersioningTest) GetVersioningEnabled() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(
+			`<VersioningConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">` +
+				"<Status>Enabled</Status></VersioningConfiguration>"),
+	})
+
+	// Call
+	status, err := t.bucket.GetVersioning()
+
+	AssertEq(nil, err)
+	ExpectEq(VersioningEnabled, status)
+}
+
+func (t *VersioningTest) DeleteObjectVersionInvalidArguments() {
+	var err error
+
+	err = t.bucket.DeleteObjectVersion("", "taco")
+	ExpectThat(err, ErrorThat(HasSubstr("empty")))
+
+	err = t.bucket.DeleteObjectVersion("a", "")
+	ExpectThat(err, ErrorThat(HasSubstr("version ID is required")))
+}
+
+func (t *VersioningTest) DeleteObjectVersionCallsSigner() {
+	httpReq := t.captureRequest()
+
+	// Call
+	t.bucket.DeleteObjectVersion("a", "taco")
+
+	AssertNe(nil, *httpReq)
+	ExpectEq("DELETE", (*httpReq).Verb)
+	ExpectEq("/some.bucket/a", (*httpReq).Path)
+	ExpectThat((*httpReq).Parameters, DeepEquals(map[string]string{"versionId": "taco"}))
+	ExpectEq("Mon, 18 Mar 1985 15:33:17 UTC", (*httpReq).Headers["Date"])
+}
+
+func (t *VersioningTest) DeleteObjectVersionSignerReturnsError() {
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(errors.New("taco")))
+
+	// Call
+	err := t.bucket.DeleteObjectVersion("a", "burrito")
+
+	ExpectThat(err, ErrorThat(HasSubstr("Sign")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *VersioningTest) DeleteObjectVersionConnReturnsError() {
+	ExpectCall(t.signer, "Sign")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	ExpectCall(t.httpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	err := t.bucket.DeleteObjectVersion("a", "burrito")
+
+	ExpectThat(err, ErrorThat(HasSubstr("SendRequest")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *VersioningTest) DeleteObjectVersionSucceeds() {
+	t.respondWith(&http.Response{StatusCode: 204})
+
+	// Call
+	err := t.bucket.DeleteObjectVersion("a", "burrito")
+
+	ExpectEq(nil, err)
+}
+
+func (t *VersioningTest) ListObjectVersionsInvalidRequests() {
+	var err error
+
+	_, err = t.bucket.ListObjectVersions(ListVersionsRequest{KeyMarker: "taco\x00"})
+	ExpectThat(err, ErrorThat(HasSubstr("U+0000")))
+
+	_, err = t.bucket.ListObjectVersions(ListVersionsRequest{VersionIdMarker: "taco"})
+	ExpectThat(err, ErrorThat(HasSubstr("requires a key marker")))
+
+	_, err = t.bucket.ListObjectVersions(ListVersionsRequest{Prefix: "\x80"})
+	ExpectThat(err, ErrorThat(HasSubstr("prefix")))
+
+	_, err = t.bucket.ListObjectVersions(ListVersionsRequest{Delimiter: "￾"})
+	ExpectThat(err, ErrorThat(HasSubstr("delimiter")))
+
+	_, err = t.bucket.ListObjectVersions(ListVersionsRequest{MaxKeys: -1})
+	ExpectThat(err, ErrorThat(HasSubstr("max keys")))
+}
+
+func (t *VersioningTest) ListObjectVersionsEmptyRequest() {
+	httpReq := t.captureRequest()
+
+	// Call
+	t.bucket.ListObjectVersions(ListVersionsRequest{})
+
+	AssertNe(nil, *httpReq)
+	ExpectEq("GET", (*httpReq).Verb)
+	ExpectEq("/some.bucket", (*httpReq).Path)
+	ExpectThat((*httpReq).Parameters, DeepEquals(map[string]string{"versions": ""}))
+}
+
+func (t *VersioningTest) ListObjectVersionsFullRequest() {
+	httpReq := t.captureRequest()
+
+	// Call
+	t.bucket.ListObjectVersions(ListVersionsRequest{
+		Prefix:          "foo/",
+		Delimiter:       "/",
+		KeyMarker:       "foo/bar",
+		VersionIdMarker: "taco",
+		MaxKeys:         17,
+	})
+
+	AssertNe(nil, *httpReq)
+	ExpectThat(
+		(*httpReq).Parameters,
+		DeepEquals(map[string]string{
+			"versions":          "",
+			"prefix":            "foo/",
+			"delimiter":         "/",
+			"key-marker":        "foo/bar",
+			"version-id-marker": "taco",
+			"max-keys":          "17",
+		}))
+}
+
+func (t *VersioningTest) ListObjectVersionsReturnsJunk() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body:       []byte("<ListBucketResult/>"),
+	})
+
+	// Call
+	_, err := t.bucket.ListObjectVersions(ListVersionsRequest{})
+
+	ExpectThat(err, ErrorThat(HasSubstr("Invalid data")))
+	ExpectThat(err, ErrorThat(HasSubstr("ListBucketResult")))
+}
+
+func (t *VersioningTest) ListObjectVersionsReturnsInvalidDate() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(
+			"<ListVersionsResult><Version><Key>a</Key>" +
+				"<LastModified>taco</LastModified></Version></ListVersionsResult>"),
+	})
+
+	// Call
+	_, err := t.bucket.ListObjectVersions(ListVersionsRequest{})
+
+	ExpectThat(err, ErrorThat(HasSubstr("LastModified")))
+	ExpectThat(err, ErrorThat(HasSubstr("taco")))
+}
+
+func (t *VersioningTest) ListObjectVersionsSucceeds() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<ListVersionsResult xmlns="http://s3.amazonaws.com/doc/2006-03-01">
+				<Name>some.bucket</Name>
+				<Prefix></Prefix>
+				<KeyMarker></KeyMarker>
+				<VersionIdMarker></VersionIdMarker>
+				<MaxKeys>3</MaxKeys>
+				<IsTruncated>true</IsTruncated>
+				<NextKeyMarker>b</NextKeyMarker>
+				<NextVersionIdMarker>null</NextVersionIdMarker>
+				<DeleteMarker>
+					<Key>a</Key>
+					<VersionId>taco</VersionId>
+					<IsLatest>true</IsLatest>
+					<LastModified>2009-10-15T17:50:30.000Z</LastModified>
+				</DeleteMarker>
+				<Version>
+					<Key>a</Key>
+					<VersionId>burrito</VersionId>
+					<IsLatest>false</IsLatest>
+					<LastModified>2009-10-12T17:50:30.000Z</LastModified>
+					<ETag>"deadbeef"</ETag>
+					<Size>17</Size>
+					<StorageClass>STANDARD</StorageClass>
+				</Version>
+				<Version>
+					<Key>b</Key>
+					<VersionId>null</VersionId>
+					<IsLatest>true</IsLatest>
+					<LastModified>2009-10-10T17:50:30.000Z</LastModified>
+					<ETag>"feedface"</ETag>
+					<Size>19</Size>
+				</Version>
+			</ListVersionsResult>`),
+	})
+
+	// Call
+	result, err := t.bucket.ListObjectVersions(ListVersionsRequest{MaxKeys: 3})
+	AssertEq(nil, err)
+
+	ExpectTrue(result.IsTruncated)
+	ExpectEq("b", result.NextKeyMarker)
+	ExpectEq("null", result.NextVersionIdMarker)
+	ExpectThat(result.CommonPrefixes, ElementsAre())
+
+	AssertEq(3, len(result.Versions))
+
+	ExpectThat(
+		result.Versions[0],
+		DeepEquals(ObjectVersion{
+			Key:            "a",
+			VersionId:      "taco",
+			IsLatest:       true,
+			IsDeleteMarker: true,
+			LastModified:   time.Date(2009, 10, 15, 17, 50, 30, 0, time.UTC),
+		}))
+
+	ExpectThat(
+		result.Versions[1],
+		DeepEquals(ObjectVersion{
+			Key:          "a",
+			VersionId:    "burrito",
+			LastModified: time.Date(2009, 10, 12, 17, 50, 30, 0, time.UTC),
+			ETag:         `"deadbeef"`,
+			Size:         17,
+		}))
+
+	ExpectThat(
+		result.Versions[2],
+		DeepEquals(ObjectVersion{
+			Key:          "b",
+			VersionId:    "null",
+			IsLatest:     true,
+			LastModified: time.Date(2009, 10, 10, 17, 50, 30, 0, time.UTC),
+			ETag:         `"feedface"`,
+			Size:         19,
+		}))
+}
+
+func (t *VersioningTest) ListObjectVersionsWithCommonPrefixes() {
+	t.respondWith(&http.Response{
+		StatusCode: 200,
+		Body: []byte(`
+			<ListVersionsResult>
+				<IsTruncated>false</IsTruncated>
+				<NextKeyMarker>ignored</NextKeyMarker>
+				<CommonPrefixes><Prefix>foo/</Prefix></CommonPrefixes>
+				<CommonPrefixes><Prefix>qux/</Prefix></CommonPrefixes>
+			</ListVersionsResult>`),
+	})
+
+	// Call
+	result, err := t.bucket.ListObjectVersions(ListVersionsRequest{Delimiter: "/"})
+	AssertEq(nil, err)
+
+	ExpectFalse(result.IsTruncated)
+	ExpectEq("", result.NextKeyMarker)
+	ExpectThat(result.Versions, ElementsAre())
+	ExpectThat(result.CommonPrefixes, ElementsAre("foo/", "qux/"))
+}
@@ -16,6 +16,7 @@
 package conn
 
 import (
+	"context"
 	"fmt"
 	"github.com/jacobsa/aws"
 	"github.com/jacobsa/aws/time"
@@ -30,6 +31,11 @@ type Conn interface {
 	// Send the supplied request to the service, taking care of adding
 	// appropriate authentication info.
 	SendRequest(req Request) (resp []byte, err error)
+
+	// Return a connection that sends requests in the same manner as the
+	// receiver, but aborts them if the supplied context is cancelled or its
+	// deadline passes. The receiver is unaffected.
+	WithContext(ctx context.Context) Conn
 }
 
 // Create a connection using the supplied dependencies.
@@ -48,6 +54,10 @@ type conn struct {
 	clock    time.Clock
 }
 
+func (c *conn) WithContext(ctx context.Context) Conn {
+	return &conn{c.key, c.httpConn.WithContext(ctx), c.signer, c.clock}
+}
+
 func (c *conn) SendRequest(req Request) (resp []byte, err error) {
 	// Make a copy of the request that we can modify below.
 	originalReq := req
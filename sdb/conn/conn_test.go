@@ -16,6 +16,7 @@
 package conn_test
 
 import (
+	"context"
 	"errors"
 	"github.com/jacobsa/aws"
 	"github.com/jacobsa/aws/sdb/conn"
@@ -42,6 +43,8 @@ func (c *fakeClock) Now() time.Time {
 }
 
 type ConnTest struct {
+	controller oglemock.Controller
+
 	key      aws.AccessKey
 	httpConn mock_conn.MockHttpConn
 	signer   mock_conn.MockSigner
@@ -55,6 +58,7 @@ func init() { RegisterTestSuite(&ConnTest{}) }
 func (t *ConnTest) SetUp(i *TestInfo) {
 	var err error
 
+	t.controller = i.MockController
 	t.key = aws.AccessKey{Id: "some_id", Secret: "some_secret"}
 	t.httpConn = mock_conn.NewMockHttpConn(i.MockController, "httpConn")
 	t.signer = mock_conn.NewMockSigner(i.MockController, "signer")
@@ -152,6 +156,27 @@ func (t *ConnTest) CallsHttpConn() {
 	ExpectEq(t.key.Id, sendArg["AWSAccessKeyId"])
 }
 
+func (t *ConnTest) WithContext() {
+	ctx := context.WithValue(context.Background(), "taco", "burrito")
+
+	// Signer
+	ExpectCall(t.signer, "SignRequest")(Any()).
+		WillOnce(oglemock.Return(nil))
+
+	// HTTP conn
+	boundHttpConn := mock_conn.NewMockHttpConn(t.controller, "boundHttpConn")
+	ExpectCall(t.httpConn, "WithContext")(ctx).
+		WillOnce(oglemock.Return(boundHttpConn))
+
+	ExpectCall(boundHttpConn, "SendRequest")(Any()).
+		WillOnce(oglemock.Return(nil, errors.New("taco")))
+
+	// Call
+	_, err := t.c.WithContext(ctx).SendRequest(conn.Request{})
+
+	ExpectThat(err, Error(HasSubstr("taco")))
+}
+
 func (t *ConnTest) HttpConnReturnsError() {
 	req := conn.Request{}
 
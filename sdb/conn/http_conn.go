@@ -17,6 +17,7 @@ package conn
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -35,22 +36,43 @@ type HttpResponse struct {
 type HttpConn interface {
 	// Send the supplied request to the service.
 	SendRequest(req Request) (resp *HttpResponse, err error)
+
+	// Return a connection that sends requests in the same manner as the
+	// receiver, but aborts them if the supplied context is cancelled or its
+	// deadline passes. The receiver is unaffected.
+	WithContext(ctx context.Context) HttpConn
 }
 
 // Return a connection to the supplied endpoint, based on its scheme and host
 // fields.
 func NewHttpConn(endpoint *url.URL) (HttpConn, error) {
+	return NewHttpConnWithClient(endpoint, http.DefaultClient)
+}
+
+// Like NewHttpConn, but send requests using the supplied HTTP client rather
+// than http.DefaultClient.
+func NewHttpConnWithClient(
+	endpoint *url.URL,
+	client *http.Client) (HttpConn, error) {
 	switch endpoint.Scheme {
 	case "http", "https":
 	default:
 		return nil, fmt.Errorf("Unsupported scheme: %s", endpoint.Scheme)
 	}
 
-	return &httpConn{endpoint}, nil
+	return &httpConn{endpoint, client, nil}, nil
 }
 
 type httpConn struct {
 	endpoint *url.URL
+	client   *http.Client
+
+	// The context with which requests are sent, or nil if none.
+	ctx context.Context
+}
+
+func (c *httpConn) WithContext(ctx context.Context) HttpConn {
+	return &httpConn{c.endpoint, c.client, ctx}
 }
 
 func (c *httpConn) SendRequest(req Request) (resp *HttpResponse, err error) {
@@ -72,6 +94,10 @@ func (c *httpConn) SendRequest(req Request) (resp *HttpResponse, err error) {
 		return nil, fmt.Errorf("http.NewRequest: %v", err)
 	}
 
+	if c.ctx != nil {
+		sysReq = sysReq.WithContext(c.ctx)
+	}
+
 	// Set required headers.
 	//
 	// More info:
@@ -85,9 +111,9 @@ func (c *httpConn) SendRequest(req Request) (resp *HttpResponse, err error) {
 		u.Host)
 
 	// Call the system HTTP library.
-	sysResp, err := http.DefaultClient.Do(sysReq)
+	sysResp, err := c.client.Do(sysReq)
 	if err != nil {
-		return nil, fmt.Errorf("http.DefaultClient.Do: %v", err)
+		return nil, fmt.Errorf("http.Client.Do: %v", err)
 	}
 
 	// Convert the response.
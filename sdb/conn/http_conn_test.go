@@ -16,6 +16,7 @@
 package conn_test
 
 import (
+	"context"
 	"github.com/jacobsa/aws/sdb/conn"
 	. "github.com/jacobsa/oglematchers"
 	. "github.com/jacobsa/ogletest"
@@ -65,6 +66,18 @@ func (h *localHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// A transport that records the request it is asked to send and returns a
+// canned response.
+type fakeTransport struct {
+	req  *http.Request
+	resp *http.Response
+}
+
+func (t *fakeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.req = r
+	return t.resp, nil
+}
+
 type HttpConnTest struct {
 	handler  localHandler
 	server   *httptest.Server
@@ -97,6 +110,67 @@ func (t *HttpConnTest) InvalidScheme() {
 	ExpectThat(err, Error(HasSubstr("taco")))
 }
 
+func (t *HttpConnTest) ContextCancelled() {
+	// Connection
+	c, err := conn.NewHttpConn(t.endpoint)
+	AssertEq(nil, err)
+
+	// Context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Call
+	_, err = c.WithContext(ctx).SendRequest(conn.Request{})
+
+	ExpectThat(err, Error(HasSubstr("canceled")))
+	ExpectEq(nil, t.handler.req)
+}
+
+func (t *HttpConnTest) LaterContextWins() {
+	t.handler.statusCode = 200
+
+	// Connection
+	c, err := conn.NewHttpConn(t.endpoint)
+	AssertEq(nil, err)
+
+	// Context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Call
+	_, err = c.WithContext(ctx).WithContext(context.Background()).SendRequest(conn.Request{})
+	AssertEq(nil, err)
+
+	ExpectNe(nil, t.handler.req)
+}
+
+func (t *HttpConnTest) UsesSuppliedClient() {
+	// Client
+	transport := &fakeTransport{
+		resp: &http.Response{
+			StatusCode: 203,
+			Body:       ioutil.NopCloser(strings.NewReader("taco")),
+		},
+	}
+
+	client := &http.Client{Transport: transport}
+
+	// Connection
+	c, err := conn.NewHttpConnWithClient(t.endpoint, client)
+	AssertEq(nil, err)
+
+	// Call
+	resp, err := c.SendRequest(conn.Request{"foo": "bar"})
+	AssertEq(nil, err)
+
+	AssertNe(nil, transport.req)
+	ExpectEq("POST", transport.req.Method)
+	ExpectEq(nil, t.handler.req)
+
+	ExpectEq(203, resp.StatusCode)
+	ExpectEq("taco", string(resp.Body))
+}
+
 func (t *HttpConnTest) UnknownHost() {
 	// Connection
 	c, err := conn.NewHttpConn(&url.URL{Scheme: "http", Host: "foo.sidofhdksjhf"})
@@ -7,6 +7,7 @@
 package mock_conn
 
 import (
+	context "context"
 	fmt "fmt"
 	conn "github.com/jacobsa/aws/sdb/conn"
 	oglemock "github.com/jacobsa/oglemock"
@@ -69,3 +70,27 @@ func (m *mockConn) SendRequest(p0 conn.Request) (o0 []uint8, o1 error) {
 
 	return
 }
+
+func (m *mockConn) WithContext(p0 context.Context) (o0 conn.Conn) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"WithContext",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockConn.WithContext: invalid return values: %v", retVals))
+	}
+
+	// o0 conn.Conn
+	if retVals[0] != nil {
+		o0 = retVals[0].(conn.Conn)
+	}
+
+	return
+}
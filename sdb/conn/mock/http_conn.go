@@ -7,6 +7,7 @@
 package mock_conn
 
 import (
+	context "context"
 	fmt "fmt"
 	conn "github.com/jacobsa/aws/sdb/conn"
 	oglemock "github.com/jacobsa/oglemock"
@@ -69,3 +70,27 @@ func (m *mockHttpConn) SendRequest(p0 conn.Request) (o0 *conn.HttpResponse, o1 e
 
 	return
 }
+
+func (m *mockHttpConn) WithContext(p0 context.Context) (o0 conn.HttpConn) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"WithContext",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockHttpConn.WithContext: invalid return values: %v", retVals))
+	}
+
+	// o0 conn.HttpConn
+	if retVals[0] != nil {
+		o0 = retVals[0].(conn.HttpConn)
+	}
+
+	return
+}
@@ -16,6 +16,7 @@
 package sdb
 
 import (
+	"context"
 	"github.com/jacobsa/aws/sdb/conn"
 )
 
@@ -116,6 +117,11 @@ type Domain interface {
 	// If no updates are supplied for a particular item, delete all of its
 	// attributes.
 	BatchDeleteAttributes(deleteMap BatchDeleteMap) error
+
+	// Return a view of the domain whose operations are bound to the supplied
+	// context: cancelling the context or passing its deadline aborts any request
+	// in progress. The receiver is unaffected.
+	WithContext(ctx context.Context) Domain
 }
 
 func newDomain(name string, c conn.Conn, db SimpleDB) (Domain, error) {
@@ -135,3 +141,7 @@ func (d *domain) Name() string {
 func (d *domain) Db() SimpleDB {
 	return d.db
 }
+
+func (d *domain) WithContext(ctx context.Context) Domain {
+	return &domain{d.name, d.c.WithContext(ctx), d.db}
+}
@@ -16,6 +16,7 @@
 package sdb
 
 import (
+	"context"
 	"github.com/jacobsa/aws/sdb/conn"
 	. "github.com/jacobsa/ogletest"
 )
@@ -25,7 +26,9 @@ import (
 ////////////////////////////////////////////////////////////////////////
 
 type fakeConn struct {
-	// Argument received
+	// Arguments received, including the context to which the connection was
+	// bound (nil if none).
+	ctx context.Context
 	req conn.Request
 
 	// Response to return
@@ -34,14 +37,37 @@ type fakeConn struct {
 }
 
 func (c *fakeConn) SendRequest(r conn.Request) ([]byte, error) {
+	return c.send(nil, r)
+}
+
+func (c *fakeConn) WithContext(ctx context.Context) conn.Conn {
+	return &contextFakeConn{c, ctx}
+}
+
+func (c *fakeConn) send(ctx context.Context, r conn.Request) ([]byte, error) {
 	if c.req != nil {
 		panic("Already called!")
 	}
 
+	c.ctx = ctx
 	c.req = r
 	return c.resp, c.err
 }
 
+// A view of a fakeConn bound to a context.
+type contextFakeConn struct {
+	wrapped *fakeConn
+	ctx     context.Context
+}
+
+func (c *contextFakeConn) SendRequest(r conn.Request) ([]byte, error) {
+	return c.wrapped.send(c.ctx, r)
+}
+
+func (c *contextFakeConn) WithContext(ctx context.Context) conn.Conn {
+	return &contextFakeConn{c.wrapped, ctx}
+}
+
 ////////////////////////////////////////////////////////////////////////
 // Common test class
 ////////////////////////////////////////////////////////////////////////
@@ -62,3 +88,41 @@ func (t *domainTest) SetUp(i *TestInfo) {
 	t.domain, err = newDomain(t.name, t.c, nil)
 	AssertEq(nil, err)
 }
+
+////////////////////////////////////////////////////////////////////////
+// WithContext
+////////////////////////////////////////////////////////////////////////
+
+type DomainWithContextTest struct {
+	domainTest
+}
+
+func init() { RegisterTestSuite(&DomainWithContextTest{}) }
+
+func (t *DomainWithContextTest) DefaultContext() {
+	// Call
+	t.domain.GetAttributes("foo", false, nil)
+
+	ExpectEq(nil, t.c.ctx)
+}
+
+func (t *DomainWithContextTest) PassesContextToConn() {
+	ctx := context.WithValue(context.Background(), "taco", "burrito")
+
+	// Call
+	d := t.domain.WithContext(ctx)
+	d.GetAttributes("foo", false, nil)
+
+	ExpectEq(ctx, t.c.ctx)
+	ExpectEq(t.name, d.Name())
+}
+
+func (t *DomainWithContextTest) ReceiverIsUnaffected() {
+	ctx := context.WithValue(context.Background(), "taco", "burrito")
+	t.domain.WithContext(ctx)
+
+	// Call
+	t.domain.GetAttributes("foo", false, nil)
+
+	ExpectEq(nil, t.c.ctx)
+}
@@ -7,6 +7,7 @@
 package mock_sdb
 
 import (
+	context "context"
 	fmt "fmt"
 	sdb "github.com/jacobsa/aws/sdb"
 	oglemock "github.com/jacobsa/oglemock"
@@ -213,3 +214,27 @@ func (m *mockDomain) PutAttributes(p0 sdb.ItemName, p1 []sdb.PutUpdate, p2 *sdb.
 
 	return
 }
+
+func (m *mockDomain) WithContext(p0 context.Context) (o0 sdb.Domain) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"WithContext",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockDomain.WithContext: invalid return values: %v", retVals))
+	}
+
+	// o0 sdb.Domain
+	if retVals[0] != nil {
+		o0 = retVals[0].(sdb.Domain)
+	}
+
+	return
+}
@@ -7,6 +7,7 @@
 package mock_sdb
 
 import (
+	context "context"
 	fmt "fmt"
 	sdb "github.com/jacobsa/aws/sdb"
 	oglemock "github.com/jacobsa/oglemock"
@@ -127,3 +128,27 @@ func (m *mockSimpleDB) Select(p0 string, p1 bool, p2 []uint8) (o0 []sdb.Selected
 
 	return
 }
+
+func (m *mockSimpleDB) WithContext(p0 context.Context) (o0 sdb.SimpleDB) {
+	// Get a file name and line number for the caller.
+	_, file, line, _ := runtime.Caller(1)
+
+	// Hand the call off to the controller, which does most of the work.
+	retVals := m.controller.HandleMethodCall(
+		m,
+		"WithContext",
+		file,
+		line,
+		[]interface{}{p0})
+
+	if len(retVals) != 1 {
+		panic(fmt.Sprintf("mockSimpleDB.WithContext: invalid return values: %v", retVals))
+	}
+
+	// o0 sdb.SimpleDB
+	if retVals[0] != nil {
+		o0 = retVals[0].(sdb.SimpleDB)
+	}
+
+	return
+}
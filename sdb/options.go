@@ -0,0 +1,51 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdb
+
+import (
+	"net/http"
+)
+
+// An Option customizes a connection created with NewSimpleDB.
+type Option func(*dbOptions)
+
+type dbOptions struct {
+	httpClient *http.Client
+}
+
+func makeDbOptions(opts []Option) *dbOptions {
+	o := &dbOptions{httpClient: http.DefaultClient}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithHttpClient causes requests to be sent using the supplied client rather
+// than http.DefaultClient, allowing control over connection pooling, proxies,
+// timeouts, TLS settings, and so on.
+func WithHttpClient(client *http.Client) Option {
+	return func(o *dbOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithHttpTransport is like WithHttpClient, for a client that uses the
+// supplied transport and is otherwise the default.
+func WithHttpTransport(transport http.RoundTripper) Option {
+	return WithHttpClient(&http.Client{Transport: transport})
+}
@@ -0,0 +1,69 @@
+// Copyright 2012 Aaron Jacobs. All Rights Reserved.
+// Author: aaronjjacobs@gmail.com (Aaron Jacobs)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdb
+
+import (
+	"github.com/jacobsa/aws"
+	. "github.com/jacobsa/ogletest"
+	"net/http"
+	"testing"
+)
+
+func TestOptions(t *testing.T) { RunTests(t) }
+
+////////////////////////////////////////////////////////////////////////
+// Helpers
+////////////////////////////////////////////////////////////////////////
+
+type OptionsTest struct {
+}
+
+func init() { RegisterTestSuite(&OptionsTest{}) }
+
+////////////////////////////////////////////////////////////////////////
+// Tests
+////////////////////////////////////////////////////////////////////////
+
+func (t *OptionsTest) Defaults() {
+	o := makeDbOptions(nil)
+
+	ExpectEq(http.DefaultClient, o.httpClient)
+}
+
+func (t *OptionsTest) HttpClient() {
+	client := &http.Client{}
+	o := makeDbOptions([]Option{WithHttpClient(client)})
+
+	ExpectEq(client, o.httpClient)
+}
+
+func (t *OptionsTest) HttpTransport() {
+	transport := &http.Transport{}
+	o := makeDbOptions([]Option{WithHttpTransport(transport)})
+
+	AssertNe(nil, o.httpClient)
+	ExpectEq(transport, o.httpClient.Transport)
+}
+
+func (t *OptionsTest) NewSimpleDBWithOptions() {
+	db, err := NewSimpleDB(
+		RegionUsEastNorthernVirginia,
+		aws.AccessKey{},
+		WithHttpClient(&http.Client{}))
+
+	AssertEq(nil, err)
+	ExpectNe(nil, db)
+}
@@ -16,6 +16,7 @@
 package sdb
 
 import (
+	"context"
 	"fmt"
 	"github.com/jacobsa/aws"
 	"github.com/jacobsa/aws/sdb/conn"
@@ -90,18 +91,30 @@ type SimpleDB interface {
 		query string,
 		constistentRead bool,
 		nextToken []byte) (results []SelectedItem, tok []byte, err error)
+
+	// Return a view of the database whose operations are bound to the supplied
+	// context: cancelling the context or passing its deadline aborts any request
+	// in progress. Domains opened using the view are bound to the context too.
+	// The receiver is unaffected.
+	WithContext(ctx context.Context) SimpleDB
 }
 
 // Return a SimpleDB connection tied to the given region, using the sipplied
-// access key to authenticate requests.
-func NewSimpleDB(region Region, key aws.AccessKey) (db SimpleDB, err error) {
+// access key to authenticate requests. Options may be supplied to customize
+// the connection's behavior.
+func NewSimpleDB(
+	region Region,
+	key aws.AccessKey,
+	opts ...Option) (db SimpleDB, err error) {
+	o := makeDbOptions(opts)
+
 	// Open an appropriate HTTP connection.
 	endpoint := &url.URL{
 		Scheme: "https",
 		Host:   string(region),
 	}
 
-	httpConn, err := conn.NewHttpConn(endpoint)
+	httpConn, err := conn.NewHttpConnWithClient(endpoint, o.httpClient)
 	if err != nil {
 		err = fmt.Errorf("Opening HTTP connection: %v", err)
 		return
@@ -133,6 +146,10 @@ type simpleDB struct {
 	c conn.Conn
 }
 
+func (db *simpleDB) WithContext(ctx context.Context) SimpleDB {
+	return &simpleDB{db.c.WithContext(ctx)}
+}
+
 func (db *simpleDB) OpenDomain(name string) (d Domain, err error) {
 	// Make sure the requested name is legal.
 	if !domainNameRe.MatchString(name) {
@@ -153,7 +170,7 @@ func (db *simpleDB) OpenDomain(name string) (d Domain, err error) {
 		return
 	}
 
-	// Create the object.
+	// Create the object. It shares our connection, and therefore our context.
 	return newDomain(name, db.c, db)
 }
 
@@ -16,6 +16,7 @@
 package sdb
 
 import (
+	"context"
 	"errors"
 	. "github.com/jacobsa/oglematchers"
 	. "github.com/jacobsa/ogletest"
@@ -82,6 +83,10 @@ func (d *fakeDomain) GetAttributes(
 	panic("Unsupported")
 }
 
+func (d *fakeDomain) WithContext(ctx context.Context) Domain {
+	panic("Unsupported")
+}
+
 ////////////////////////////////////////////////////////////////////////
 // OpenDomain
 ////////////////////////////////////////////////////////////////////////
@@ -254,3 +259,48 @@ func (t *DeleteDomainTest) ConnSaysOkay() {
 
 	ExpectEq(nil, t.err)
 }
+
+////////////////////////////////////////////////////////////////////////
+// WithContext
+////////////////////////////////////////////////////////////////////////
+
+type SimpleDBWithContextTest struct {
+	simpleDBTest
+	ctx context.Context
+}
+
+func init() { RegisterTestSuite(&SimpleDBWithContextTest{}) }
+
+func (t *SimpleDBWithContextTest) SetUp(i *TestInfo) {
+	t.simpleDBTest.SetUp(i)
+	t.ctx = context.WithValue(context.Background(), "taco", "burrito")
+}
+
+func (t *SimpleDBWithContextTest) DefaultContext() {
+	// Call
+	t.db.DeleteDomain(&fakeDomain{"foo"})
+
+	ExpectEq(nil, t.c.ctx)
+}
+
+func (t *SimpleDBWithContextTest) PassesContextToConn() {
+	// Call
+	t.db.WithContext(t.ctx).DeleteDomain(&fakeDomain{"foo"})
+
+	ExpectEq(t.ctx, t.c.ctx)
+}
+
+func (t *SimpleDBWithContextTest) OpenedDomainsInheritContext() {
+	t.c.resp = []byte{}
+
+	// Call
+	d, err := t.db.WithContext(t.ctx).OpenDomain("foo")
+	AssertEq(nil, err)
+
+	castedDomain, ok := d.(*domain)
+	AssertTrue(ok)
+
+	boundConn, ok := castedDomain.c.(*contextFakeConn)
+	AssertTrue(ok)
+	ExpectEq(t.ctx, boundConn.ctx)
+}